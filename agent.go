@@ -1,10 +1,16 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_agent.go . Agent
 
 type Agent interface {
+	Self() (map[string]map[string]interface{}, error)
+	Members(wan bool) ([]*api.AgentMember, error)
 	Checks() (map[string]*api.AgentCheck, error)
 	Services() (map[string]*api.AgentService, error)
 	ServiceRegister(service *api.AgentServiceRegistration) error
@@ -12,18 +18,40 @@ type Agent interface {
 	PassTTL(checkID, note string) error
 	WarnTTL(checkID, note string) error
 	FailTTL(checkID, note string) error
+	UpdateTTL(checkID, output, status string) error
 	NodeName() (string, error)
+	CheckRegister(check *api.AgentCheckRegistration) error
 	CheckDeregister(checkID string) error
+	Join(addr string, wan bool) error
+	ForceLeave(node string) error
+
+	// Maintenance mode registers a critical check on the service or the node,
+	// taking it out of healthy service lookups until disabled.
+	EnableServiceMaintenance(serviceID, reason string) error
+	DisableServiceMaintenance(serviceID string) error
+	EnableNodeMaintenance(reason string) error
+	DisableNodeMaintenance() error
+
+	WithContext(ctx context.Context) Agent
 }
 
 type agent struct {
-	agent *api.Agent
+	agent  *api.Agent
+	client *client
 }
 
 func NewConsulAgent(a *api.Agent) Agent {
 	return &agent{agent: a}
 }
 
+func (a *agent) Self() (map[string]map[string]interface{}, error) {
+	return a.agent.Self()
+}
+
+func (a *agent) Members(wan bool) ([]*api.AgentMember, error) {
+	return a.agent.Members(wan)
+}
+
 func (a *agent) Checks() (map[string]*api.AgentCheck, error) {
 	return a.agent.Checks()
 }
@@ -40,6 +68,10 @@ func (a *agent) ServiceDeregister(serviceID string) error {
 	return a.agent.ServiceDeregister(serviceID)
 }
 
+func (a *agent) CheckRegister(check *api.AgentCheckRegistration) error {
+	return a.agent.CheckRegister(check)
+}
+
 func (a *agent) CheckDeregister(checkID string) error {
 	return a.agent.CheckDeregister(checkID)
 }
@@ -56,6 +88,41 @@ func (a *agent) FailTTL(checkID, note string) error {
 	return a.agent.FailTTL(checkID, note)
 }
 
+func (a *agent) UpdateTTL(checkID, output, status string) error {
+	return a.agent.UpdateTTL(checkID, output, status)
+}
+
 func (a *agent) NodeName() (string, error) {
 	return a.agent.NodeName()
 }
+
+func (a *agent) Join(addr string, wan bool) error {
+	return a.agent.Join(addr, wan)
+}
+
+func (a *agent) ForceLeave(node string) error {
+	return a.agent.ForceLeave(node)
+}
+
+func (a *agent) EnableServiceMaintenance(serviceID, reason string) error {
+	return a.agent.EnableServiceMaintenance(serviceID, reason)
+}
+
+func (a *agent) DisableServiceMaintenance(serviceID string) error {
+	return a.agent.DisableServiceMaintenance(serviceID)
+}
+
+func (a *agent) EnableNodeMaintenance(reason string) error {
+	return a.agent.EnableNodeMaintenance(reason)
+}
+
+func (a *agent) DisableNodeMaintenance() error {
+	return a.agent.DisableNodeMaintenance()
+}
+
+func (a *agent) WithContext(ctx context.Context) Agent {
+	if a.client == nil {
+		return &contextAgent{agent: a, ctx: ctx}
+	}
+	return a.client.WithContext(ctx).Agent()
+}
@@ -0,0 +1,101 @@
+package consuladapter_test
+
+import (
+	"net"
+	"strconv"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Agent", func() {
+	var agent consuladapter.Agent
+
+	BeforeEach(func() {
+		Expect(consulRunner.Reset()).To(Succeed())
+		consulClient = consulRunner.NewClient()
+		agent = consulClient.Agent()
+	})
+
+	checks := func() map[string]*api.AgentCheck {
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		return checks
+	}
+
+	It("registers standalone TTL checks and updates them", func() {
+		err := agent.CheckRegister(&api.AgentCheckRegistration{
+			ID:                "disk",
+			Name:              "Disk",
+			AgentServiceCheck: api.AgentServiceCheck{TTL: "1m"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer agent.CheckDeregister("disk")
+
+		Expect(agent.UpdateTTL("disk", "90% full", api.HealthWarning)).To(Succeed())
+		Expect(checks()["disk"].Status).To(Equal(api.HealthWarning))
+		Expect(checks()["disk"].Output).To(Equal("90% full"))
+
+		Expect(agent.UpdateTTL("disk", "", api.HealthPassing)).To(Succeed())
+		Expect(checks()["disk"].Status).To(Equal(api.HealthPassing))
+	})
+
+	It("describes itself and its members", func() {
+		nodeName, err := agent.NodeName()
+		Expect(err).NotTo(HaveOccurred())
+
+		self, err := agent.Self()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(self["Config"]["NodeName"]).To(Equal(nodeName))
+
+		members, err := agent.Members(false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(clusterSize))
+		Expect(members[0].Name).To(Equal(nodeName))
+
+		Expect(agent.ForceLeave("no-such-node")).To(Succeed())
+	})
+
+	It("joins members by their serf address", func() {
+		nodeName, err := agent.NodeName()
+		Expect(err).NotTo(HaveOccurred())
+
+		members, err := agent.Members(false)
+		Expect(err).NotTo(HaveOccurred())
+		addr := net.JoinHostPort(members[0].Addr, strconv.Itoa(int(members[0].Port)))
+
+		Expect(agent.Join(addr, false)).To(Succeed())
+		Expect(agent.Join("127.0.0.1:1", false)).NotTo(Succeed())
+
+		// Force-leave only affects failed members, so the agent stays alive.
+		Expect(agent.ForceLeave(nodeName)).To(Succeed())
+		members, err = agent.Members(false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(clusterSize))
+		Expect(members[0].Name).To(Equal(nodeName))
+		Expect(members[0].Status).To(Equal(1))
+	})
+
+	It("puts services and the node into maintenance", func() {
+		Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "web"})).To(Succeed())
+		defer agent.ServiceDeregister("web")
+
+		Expect(agent.EnableServiceMaintenance("web", "draining")).To(Succeed())
+		Expect(checks()).To(HaveKey("_service_maintenance:web"))
+		Expect(checks()["_service_maintenance:web"].Status).To(Equal(api.HealthCritical))
+		Expect(checks()["_service_maintenance:web"].Notes).To(Equal("draining"))
+
+		Expect(agent.DisableServiceMaintenance("web")).To(Succeed())
+		Expect(checks()).NotTo(HaveKey("_service_maintenance:web"))
+
+		Expect(agent.EnableNodeMaintenance("evacuating")).To(Succeed())
+		Expect(checks()).To(HaveKey("_node_maintenance"))
+		Expect(checks()["_node_maintenance"].Notes).To(Equal("evacuating"))
+
+		Expect(agent.DisableNodeMaintenance()).To(Succeed())
+		Expect(checks()).NotTo(HaveKey("_node_maintenance"))
+	})
+})
@@ -0,0 +1,408 @@
+package consuladapter
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultCacheMaxStaleness = 30 * time.Second
+
+type CachedKVOptions struct {
+	// Prefixes are mirrored in memory. Reads under any of them are served
+	// from the mirror.
+	Prefixes []string
+
+	// MaxStaleness bounds how long reads are served from a mirror whose
+	// watch is failing before they fall back to the live KV.
+	MaxStaleness time.Duration
+
+	Watch WatchOptions
+}
+
+// CachedKV serves Get, List and Keys under its prefixes from memory once Run
+// has synced them, and everything else from the wrapped KV. Cached reads
+// report the mirror's index in QueryMeta.LastIndex, and in LastContact how
+// long ago the mirror last synced if its watch is failing. Blocking and
+// consistent queries, and queries for another datacenter or token than the
+// watch's, always go to the wrapped KV. Writes through CachedKV re-read the
+// mirrors they touch, so they are visible to the reads that follow.
+type CachedKV struct {
+	KV
+	cache *kvCache
+}
+
+type kvCache struct {
+	opts    CachedKVOptions
+	mirrors []*mirror
+	mutex   sync.RWMutex
+}
+
+type mirror struct {
+	prefix   string
+	pairs    map[string]*api.KVPair
+	index    uint64
+	synced   bool
+	lastSync time.Time
+	failing  bool
+}
+
+func NewCachedKV(kv KV, opts CachedKVOptions) *CachedKV {
+	if opts.MaxStaleness <= 0 {
+		opts.MaxStaleness = DefaultCacheMaxStaleness
+	}
+
+	cache := &kvCache{opts: opts}
+	for _, prefix := range opts.Prefixes {
+		cache.mirrors = append(cache.mirrors, &mirror{prefix: prefix})
+	}
+	return &CachedKV{KV: kv, cache: cache}
+}
+
+// Run keeps the mirrors current until signalled. It is ready once every
+// prefix has been queried once, whether or not that succeeded.
+func (c *CachedKV) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	o := c.cache.opts.Watch.withDefaults()
+	kv := c.KV.WithContext(ctx)
+
+	var first, done sync.WaitGroup
+	for _, m := range c.cache.mirrors {
+		first.Add(1)
+		done.Add(1)
+		go func(m *mirror) {
+			defer done.Done()
+			c.cache.sync(ctx, kv, m, o, first.Done)
+		}(m)
+	}
+
+	synced := make(chan struct{})
+	go func() {
+		first.Wait()
+		close(synced)
+	}()
+
+	select {
+	case <-synced:
+		close(ready)
+	case <-signals:
+		cancel()
+		done.Wait()
+		return nil
+	}
+
+	<-signals
+	cancel()
+	done.Wait()
+	return nil
+}
+
+// Synced reports whether every prefix is mirrored and fresh.
+func (c *CachedKV) Synced() bool {
+	c.cache.mutex.RLock()
+	defer c.cache.mutex.RUnlock()
+
+	for _, m := range c.cache.mirrors {
+		if !c.cache.fresh(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CachedKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if c.cache.cacheable(q) {
+		c.cache.mutex.RLock()
+		m := c.cache.covering(key)
+		if m != nil {
+			pair := copyKVPair(m.pairs[key])
+			meta := c.cache.meta(m)
+			c.cache.mutex.RUnlock()
+			return pair, meta, nil
+		}
+		c.cache.mutex.RUnlock()
+	}
+	return c.KV.Get(key, q)
+}
+
+func (c *CachedKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	if c.cache.cacheable(q) {
+		c.cache.mutex.RLock()
+		m := c.cache.covering(prefix)
+		if m != nil {
+			var pairs api.KVPairs
+			for _, key := range m.keys(prefix) {
+				pairs = append(pairs, copyKVPair(m.pairs[key]))
+			}
+			meta := c.cache.meta(m)
+			c.cache.mutex.RUnlock()
+			return pairs, meta, nil
+		}
+		c.cache.mutex.RUnlock()
+	}
+	return c.KV.List(prefix, q)
+}
+
+func (c *CachedKV) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	if c.cache.cacheable(q) {
+		c.cache.mutex.RLock()
+		m := c.cache.covering(prefix)
+		if m != nil {
+			keys := splitKeys(m.keys(prefix), prefix, separator)
+			meta := c.cache.meta(m)
+			c.cache.mutex.RUnlock()
+			return keys, meta, nil
+		}
+		c.cache.mutex.RUnlock()
+	}
+	return c.KV.Keys(prefix, separator, q)
+}
+
+func (c *CachedKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := c.KV.Put(p, q)
+	if err == nil {
+		c.refresh(p.Key, false)
+	}
+	return meta, err
+}
+
+func (c *CachedKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := c.KV.CAS(p, q)
+	if ok {
+		c.refresh(p.Key, false)
+	}
+	return ok, meta, err
+}
+
+func (c *CachedKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := c.KV.Acquire(p, q)
+	if ok {
+		c.refresh(p.Key, false)
+	}
+	return ok, meta, err
+}
+
+func (c *CachedKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := c.KV.Release(p, q)
+	if ok {
+		c.refresh(p.Key, false)
+	}
+	return ok, meta, err
+}
+
+func (c *CachedKV) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := c.KV.Delete(key, w)
+	if err == nil {
+		c.refresh(key, false)
+	}
+	return meta, err
+}
+
+func (c *CachedKV) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := c.KV.DeleteCAS(p, q)
+	if ok {
+		c.refresh(p.Key, false)
+	}
+	return ok, meta, err
+}
+
+func (c *CachedKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := c.KV.DeleteTree(prefix, w)
+	if err == nil {
+		c.refresh(prefix, true)
+	}
+	return meta, err
+}
+
+func (c *CachedKV) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	ok, resp, meta, err := c.KV.Txn(txn, q)
+	if ok {
+		for _, op := range txn {
+			switch op.Verb {
+			case api.KVGet, api.KVGetTree, api.KVCheckIndex, api.KVCheckSession:
+			default:
+				c.refresh(op.Key, op.Verb == api.KVDeleteTree)
+			}
+		}
+	}
+	return ok, resp, meta, err
+}
+
+// WithContext binds reads that miss the cache, and writes, to ctx. The cache
+// is shared.
+func (c *CachedKV) WithContext(ctx context.Context) KV {
+	return &CachedKV{KV: c.KV.WithContext(ctx), cache: c.cache}
+}
+
+// refresh re-reads the synced mirrors covering key, or every key under it if
+// tree is set. A mirror that cannot be re-read is marked unsynced until its
+// watch catches up.
+func (c *CachedKV) refresh(key string, tree bool) {
+	for _, m := range c.cache.mirrors {
+		c.cache.mutex.RLock()
+		synced := m.synced
+		c.cache.mutex.RUnlock()
+
+		covered := strings.HasPrefix(key, m.prefix) || (tree && strings.HasPrefix(m.prefix, key))
+		if !synced || !covered {
+			continue
+		}
+
+		q := c.cache.opts.Watch.QueryOptions
+		q.WaitIndex = 0
+		pairs, meta, err := c.KV.List(m.prefix, &q)
+
+		c.cache.mutex.Lock()
+		if err != nil {
+			m.synced = false
+		} else {
+			c.cache.apply(m, pairs, meta, 0)
+		}
+		c.cache.mutex.Unlock()
+	}
+}
+
+// cacheable reports whether q may be answered by the mirrors, which hold what
+// the watch's datacenter and token can see.
+func (c *kvCache) cacheable(q *api.QueryOptions) bool {
+	if q == nil {
+		q = &api.QueryOptions{}
+	}
+	watch := c.opts.Watch.QueryOptions
+	return q.WaitIndex == 0 && !q.RequireConsistent && q.Datacenter == watch.Datacenter && q.Token == watch.Token
+}
+
+// sync mirrors m.prefix with blocking queries until ctx is done, calling
+// first once the first query has completed.
+func (c *kvCache) sync(ctx context.Context, kv KV, m *mirror, o WatchOptions, first func()) {
+	signalFirst := func() {
+		if first != nil {
+			first()
+			first = nil
+		}
+	}
+	defer signalFirst()
+
+	var failures uint
+	for ctx.Err() == nil {
+		q := o.QueryOptions
+		c.mutex.RLock()
+		q.WaitIndex = m.index
+		c.mutex.RUnlock()
+
+		pairs, meta, err := kv.List(m.prefix, &q)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.mutex.Lock()
+			m.failing = true
+			c.mutex.Unlock()
+			signalFirst()
+
+			if o.OnError != nil {
+				o.OnError(err)
+			}
+			if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(o.MinBackoff, o.MaxBackoff, failures))) {
+				return
+			}
+			failures++
+			continue
+		}
+		failures = 0
+
+		c.mutex.Lock()
+		reset := c.apply(m, pairs, meta, q.WaitIndex)
+		c.mutex.Unlock()
+
+		signalFirst()
+		if reset && !retry.Sleep(ctx, retry.Jitter(o.MinBackoff)) {
+			return
+		}
+	}
+}
+
+// apply replaces the contents of m with the result of a query sent with
+// waitIndex, unless a refresh has already applied a newer one. It reports
+// whether the index went backwards or is missing, in which case the watch
+// starts over with a non-blocking query. It must be called with the mutex
+// held.
+func (c *kvCache) apply(m *mirror, pairs api.KVPairs, meta *api.QueryMeta, waitIndex uint64) bool {
+	var index uint64
+	if meta != nil {
+		index = meta.LastIndex
+	}
+	reset := index == 0 || index < waitIndex
+	if !reset && index < m.index {
+		return false
+	}
+
+	m.pairs = make(map[string]*api.KVPair, len(pairs))
+	for _, pair := range pairs {
+		m.pairs[pair.Key] = pair
+	}
+	m.synced = true
+	m.failing = false
+	m.lastSync = time.Now()
+	if reset {
+		m.index = 0
+	} else {
+		m.index = index
+	}
+	return reset
+}
+
+// covering returns the fresh mirror whose prefix covers key, preferring the
+// longest. It must be called with the mutex held.
+func (c *kvCache) covering(key string) *mirror {
+	var found *mirror
+	for _, m := range c.mirrors {
+		if strings.HasPrefix(key, m.prefix) && c.fresh(m) && (found == nil || len(m.prefix) > len(found.prefix)) {
+			found = m
+		}
+	}
+	return found
+}
+
+func (c *kvCache) fresh(m *mirror) bool {
+	return m.synced && (!m.failing || time.Since(m.lastSync) <= c.opts.MaxStaleness)
+}
+
+func (c *kvCache) meta(m *mirror) *api.QueryMeta {
+	meta := &api.QueryMeta{LastIndex: m.index, KnownLeader: !m.failing}
+	if m.failing {
+		meta.LastContact = time.Since(m.lastSync)
+	}
+	return meta
+}
+
+func (m *mirror) keys(prefix string) []string {
+	var keys []string
+	for key := range m.pairs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyKVPair(p *api.KVPair) *api.KVPair {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+	if p.Value != nil {
+		c.Value = append([]byte{}, p.Value...)
+	}
+	return &c
+}
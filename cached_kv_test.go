@@ -0,0 +1,253 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CachedKV", func() {
+	var (
+		live    consuladapter.KV
+		fakeKV  *fakes.FakeKV
+		failing atomic.Bool
+		blocked atomic.Bool
+		opts    consuladapter.CachedKVOptions
+		cached  *consuladapter.CachedKV
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		live = memconsul.NewClient().KV()
+		failing.Store(false)
+		blocked.Store(false)
+
+		fakeKV = &fakes.FakeKV{}
+		fakeKV.WithContextReturns(fakeKV)
+		fakeKV.GetStub = live.Get
+		fakeKV.KeysStub = live.Keys
+		fakeKV.ListStub = func(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+			if failing.Load() || (blocked.Load() && q.WaitIndex != 0) {
+				return nil, nil, errors.New("Unexpected response code: 500 (no leader)")
+			}
+			return live.List(prefix, q)
+		}
+		fakeKV.PutStub = live.Put
+		fakeKV.DeleteTreeStub = live.DeleteTree
+		fakeKV.TxnStub = live.Txn
+
+		for _, key := range []string{"cells/a", "cells/b", "other"} {
+			_, err := live.Put(&api.KVPair{Key: key, Value: []byte(key)}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		opts = consuladapter.CachedKVOptions{
+			Prefixes:     []string{"cells/"},
+			MaxStaleness: time.Minute,
+			Watch: consuladapter.WatchOptions{
+				QueryOptions: api.QueryOptions{WaitTime: 20 * time.Millisecond},
+				MinBackoff:   10 * time.Millisecond,
+				MaxBackoff:   20 * time.Millisecond,
+			},
+		}
+		process = nil
+	})
+
+	JustBeforeEach(func() {
+		cached = consuladapter.NewCachedKV(fakeKV, opts)
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ginkgomon.Interrupt(process)
+		}
+	})
+
+	It("reads through to the live KV while cold", func() {
+		pair, _, err := cached.Get("cells/a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("cells/a")))
+		Expect(fakeKV.GetCallCount()).To(Equal(1))
+		Expect(cached.Synced()).To(BeFalse())
+	})
+
+	Context("once synced", func() {
+		JustBeforeEach(func() {
+			process = ginkgomon.Invoke(cached)
+			Expect(cached.Synced()).To(BeTrue())
+		})
+
+		It("serves reads under its prefixes from memory", func() {
+			pair, meta, err := cached.Get("cells/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("cells/a")))
+			Expect(meta.LastIndex).NotTo(BeZero())
+			Expect(meta.KnownLeader).To(BeTrue())
+
+			pair, _, err = cached.Get("cells/missing", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+
+			pairs, _, err := cached.List("cells/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(HaveLen(2))
+
+			keys, _, err := cached.Keys("cells/", "/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{"cells/a", "cells/b"}))
+
+			Expect(fakeKV.GetCallCount()).To(BeZero())
+			Expect(fakeKV.KeysCallCount()).To(BeZero())
+		})
+
+		It("does not share values with callers", func() {
+			pair, _, err := cached.Get("cells/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			pair.Value[0] = 'X'
+
+			pair, _, err = cached.Get("cells/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("cells/a")))
+		})
+
+		It("picks up changes", func() {
+			_, err := live.Put(&api.KVPair{Key: "cells/c", Value: []byte("new")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = live.Delete("cells/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() []string {
+				keys, _, _ := cached.Keys("cells/", "", nil)
+				return keys
+			}).Should(Equal([]string{"cells/b", "cells/c"}))
+		})
+
+		It("reads other keys, blocking and consistent queries from the live KV", func() {
+			_, _, err := cached.Get("other", nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = cached.Get("cells/a", &api.QueryOptions{RequireConsistent: true})
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = cached.Keys("", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeKV.GetCallCount()).To(Equal(2))
+			Expect(fakeKV.KeysCallCount()).To(Equal(1))
+		})
+
+		It("reads queries for another datacenter or token from the live KV", func() {
+			_, _, err := cached.Get("cells/a", &api.QueryOptions{Datacenter: "dc2"})
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = cached.List("cells/", &api.QueryOptions{Token: "other"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeKV.GetCallCount()).To(Equal(1))
+			Expect(fakeKV.ListCallCount()).To(BeNumerically(">", 1))
+		})
+
+		Context("when writing through it", func() {
+			BeforeEach(func() {
+				blocked.Store(true)
+			})
+
+			It("reflects the writes in the reads that follow", func() {
+				_, err := cached.Put(&api.KVPair{Key: "cells/a", Value: []byte("changed")}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				pair, _, err := cached.Get("cells/a", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pair.Value).To(Equal([]byte("changed")))
+
+				_, _, err = consuladapter.NewTxn().Delete("cells/b").Commit(cached, nil)
+				Expect(err).NotTo(HaveOccurred())
+				keys, _, err := cached.Keys("cells/", "", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(keys).To(Equal([]string{"cells/a"}))
+
+				_, err = cached.DeleteTree("cells/", nil)
+				Expect(err).NotTo(HaveOccurred())
+				keys, _, err = cached.Keys("cells/", "", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(keys).To(BeEmpty())
+
+				Expect(fakeKV.GetCallCount()).To(BeZero())
+				Expect(fakeKV.KeysCallCount()).To(BeZero())
+			})
+		})
+
+		It("reports staleness while its watch fails", func() {
+			failing.Store(true)
+
+			Eventually(func() time.Duration {
+				_, meta, _ := cached.Get("cells/a", nil)
+				return meta.LastContact
+			}).Should(BeNumerically(">", 0))
+
+			_, meta, err := cached.Get("cells/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(meta.KnownLeader).To(BeFalse())
+			Expect(fakeKV.GetCallCount()).To(BeZero())
+
+			failing.Store(false)
+			Eventually(func() bool {
+				_, meta, _ := cached.Get("cells/a", nil)
+				return meta.KnownLeader
+			}).Should(BeTrue())
+		})
+
+		Context("when the watch has failed for longer than MaxStaleness", func() {
+			BeforeEach(func() {
+				opts.MaxStaleness = 50 * time.Millisecond
+			})
+
+			It("falls back to the live KV", func() {
+				failing.Store(true)
+				Eventually(cached.Synced).Should(BeFalse())
+
+				pair, _, err := cached.Get("cells/a", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pair.Value).To(Equal([]byte("cells/a")))
+				Expect(fakeKV.GetCallCount()).To(Equal(1))
+
+				failing.Store(false)
+				Eventually(cached.Synced).Should(BeTrue())
+			})
+		})
+	})
+
+	It("starts over when a query returns no meta", func() {
+		var calls atomic.Int32
+		fakeKV.ListStub = func(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+			pairs, meta, err := live.List(prefix, q)
+			if calls.Add(1) == 1 {
+				meta = nil
+			}
+			return pairs, meta, err
+		}
+
+		process = ginkgomon.Invoke(cached)
+		Eventually(calls.Load).Should(BeNumerically(">", 2))
+		_, meta, err := cached.Get("cells/a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.LastIndex).NotTo(BeZero())
+	})
+
+	It("is ready even if the first sync fails", func() {
+		failing.Store(true)
+		errs := make(chan error, 100)
+		opts.Watch.OnError = func(err error) { errs <- err }
+		cached = consuladapter.NewCachedKV(fakeKV, opts)
+
+		process = ginkgomon.Invoke(cached)
+		Expect(cached.Synced()).To(BeFalse())
+		Eventually(errs).Should(Receive(MatchError(ContainSubstring("no leader"))))
+	})
+})
@@ -1,15 +1,28 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_catalog.go . Catalog
 
 type Catalog interface {
 	Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error)
+	Node(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error)
+	Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error)
+	Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error)
+	Datacenters() ([]string, error)
+	Register(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error)
+	Deregister(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error)
+
+	WithContext(ctx context.Context) Catalog
 }
 
 type catalog struct {
 	catalog *api.Catalog
+	client  *client
 }
 
 func NewConsulCatalog(c *api.Catalog) Catalog {
@@ -19,3 +32,34 @@ func NewConsulCatalog(c *api.Catalog) Catalog {
 func (c *catalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
 	return c.catalog.Nodes(q)
 }
+
+func (c *catalog) Node(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error) {
+	return c.catalog.Node(node, q)
+}
+
+func (c *catalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	return c.catalog.Services(q)
+}
+
+func (c *catalog) Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	return c.catalog.Service(service, tag, q)
+}
+
+func (c *catalog) Datacenters() ([]string, error) {
+	return c.catalog.Datacenters()
+}
+
+func (c *catalog) Register(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return c.catalog.Register(reg, q)
+}
+
+func (c *catalog) Deregister(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return c.catalog.Deregister(dereg, q)
+}
+
+func (c *catalog) WithContext(ctx context.Context) Catalog {
+	if c.client == nil {
+		return &contextCatalog{catalog: c, ctx: ctx}
+	}
+	return c.client.WithContext(ctx).Catalog()
+}
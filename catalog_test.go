@@ -0,0 +1,62 @@
+package consuladapter_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog", func() {
+	var catalog consuladapter.Catalog
+
+	BeforeEach(func() {
+		Expect(consulRunner.Reset()).To(Succeed())
+		consulClient = consulRunner.NewClient()
+		catalog = consulClient.Catalog()
+	})
+
+	It("registers and deregisters external services", func() {
+		_, meta, err := catalog.Service("db", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		result := make(chan []*api.CatalogService)
+		go func() {
+			defer GinkgoRecover()
+			services, _, err := catalog.Service("db", "", &api.QueryOptions{WaitIndex: meta.LastIndex})
+			Expect(err).NotTo(HaveOccurred())
+			result <- services
+		}()
+
+		_, err = catalog.Register(&api.CatalogRegistration{
+			Node:    "external",
+			Address: "10.0.0.1",
+			Service: &api.AgentService{ID: "db-1", Service: "db", Port: 5432},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var services []*api.CatalogService
+		Eventually(result).Should(Receive(&services))
+		Expect(services).To(HaveLen(1))
+		Expect(services[0].Node).To(Equal("external"))
+		Expect(services[0].ServicePort).To(Equal(5432))
+
+		node, _, err := catalog.Node("external", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Services).To(HaveKey("db-1"))
+
+		_, err = catalog.Deregister(&api.CatalogDeregistration{Node: "external"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		node, _, err = catalog.Node("external", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node).To(BeNil())
+	})
+
+	It("lists the datacenters", func() {
+		datacenters, err := catalog.Datacenters()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(datacenters).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,387 @@
+package consuladapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const chunkDir = "/_chunks/"
+
+// chunkReadAttempts bounds how often Get re-reads a chunked value that was
+// rewritten between reading its manifest and its chunks.
+const chunkReadAttempts = 3
+
+// manifestMagic starts the value stored under a chunked key. Inline values
+// that start with it, or with verbatimMagic, are stored behind verbatimMagic.
+var (
+	manifestMagic = []byte("\x00ck")
+	verbatimMagic = []byte("\x00cv")
+)
+
+type chunkManifest struct {
+	Chunks int    `json:"chunks"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ChunkedKV stores values too large for a single key across key/_chunks/N
+// entries, written in one transaction together with a manifest under the key
+// itself. Reads reassemble and verify them, so callers see whole values.
+// Smaller values are stored as is.
+type ChunkedKV struct {
+	KV
+	chunkSize int
+}
+
+// NewChunkedKV wraps kv so that values over chunkSize bytes are chunked. A
+// chunkSize of 0 uses MaxValueSize.
+func NewChunkedKV(kv KV, chunkSize int) *ChunkedKV {
+	if chunkSize <= 0 {
+		chunkSize = MaxValueSize
+	}
+	return &ChunkedKV{KV: kv, chunkSize: chunkSize}
+}
+
+func (c *ChunkedKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	for attempt := 1; ; attempt++ {
+		pair, meta, err := c.KV.Get(key, q)
+		if err != nil || pair == nil {
+			return pair, meta, err
+		}
+
+		manifest, ok := parseManifest(pair.Value)
+		if !ok {
+			pair.Value = unescapeInline(pair.Value)
+			return pair, meta, nil
+		}
+
+		// Read the chunks in a transaction that fails if the manifest moved.
+		// The manifest is read again without blocking if it did.
+		var txnQ *api.QueryOptions
+		if q != nil {
+			copied := *q
+			copied.WaitIndex, copied.WaitTime = 0, 0
+			txnQ, q = &copied, &copied
+		}
+		results, _, err := NewTxn().
+			CheckIndex(key, pair.ModifyIndex).
+			Op(&api.KVTxnOp{Verb: api.KVGetTree, Key: key + chunkDir}).
+			Commit(c.KV, txnQ)
+		if _, stale := err.(TxnFailedError); stale && attempt < chunkReadAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pair.Value, err = manifest.assemble(key, results)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pair, meta, nil
+	}
+}
+
+func (c *ChunkedKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	pairs, meta, err := c.KV.List(prefix, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunks := map[string][]*api.KVPair{}
+	var values api.KVPairs
+	for _, pair := range pairs {
+		if base, _, ok := parseChunkKey(pair.Key); ok {
+			chunks[base] = append(chunks[base], pair)
+			continue
+		}
+		values = append(values, pair)
+	}
+
+	for _, pair := range values {
+		manifest, ok := parseManifest(pair.Value)
+		if !ok {
+			pair.Value = unescapeInline(pair.Value)
+			continue
+		}
+		pair.Value, err = manifest.assemble(pair.Key, chunks[pair.Key])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return values, meta, nil
+}
+
+// Keys hides chunk entries. It applies the separator itself, so that a key
+// whose only children are chunks does not show up as a directory.
+func (c *ChunkedKV) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	all, meta, err := c.KV.Keys(prefix, "", q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	for _, key := range all {
+		if _, _, ok := parseChunkKey(key); !ok {
+			keys = append(keys, key)
+		}
+	}
+	return splitKeys(keys, prefix, separator), meta, nil
+}
+
+// splitKeys truncates sorted keys under prefix after the first separator
+// following the prefix and drops the duplicates, as Consul does for Keys.
+func splitKeys(keys []string, prefix, separator string) []string {
+	if separator == "" {
+		return keys
+	}
+
+	var split []string
+	for _, key := range keys {
+		if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+			key = key[:len(prefix)+i+len(separator)]
+		}
+		if len(split) == 0 || split[len(split)-1] != key {
+			split = append(split, key)
+		}
+	}
+	return split
+}
+
+func (c *ChunkedKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	txn, err := c.write(p, &api.KVTxnOp{Verb: string(api.KVSet)})
+	if err != nil {
+		return nil, err
+	}
+	_, meta, err := txn.Commit(c.KV, txnOptions(q))
+	if err != nil {
+		return nil, err
+	}
+	return writeMeta(meta), nil
+}
+
+// CAS reports false when the key's ModifyIndex does not match p.ModifyIndex,
+// whether or not its value is chunked.
+func (c *ChunkedKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	txn, err := c.write(p, &api.KVTxnOp{Verb: api.KVCAS, Index: p.ModifyIndex})
+	if err != nil {
+		return false, nil, err
+	}
+	return commitCAS(txn, c.KV, q)
+}
+
+func (c *ChunkedKV) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	_, meta, err := NewTxn().Delete(key).DeleteTree(key+chunkDir).Commit(c.KV, txnOptions(w))
+	if err != nil {
+		return nil, err
+	}
+	return writeMeta(meta), nil
+}
+
+func (c *ChunkedKV) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	txn := NewTxn().
+		Op(&api.KVTxnOp{Verb: api.KVDeleteCAS, Key: p.Key, Index: p.ModifyIndex}).
+		DeleteTree(p.Key + chunkDir)
+	return commitCAS(txn, c.KV, q)
+}
+
+func (c *ChunkedKV) WithContext(ctx context.Context) KV {
+	return NewChunkedKV(c.KV.WithContext(ctx), c.chunkSize)
+}
+
+// CollectChunks deletes the chunks under prefix that no manifest refers to,
+// which are left behind when a chunked key is overwritten or deleted through
+// a plain KV. Chunks rewritten while it runs are kept. It returns the number
+// of chunks deleted.
+func (c *ChunkedKV) CollectChunks(prefix string) (int, error) {
+	pairs, _, err := c.KV.List(prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	values := map[string]*api.KVPair{}
+	for _, pair := range pairs {
+		values[pair.Key] = pair
+	}
+
+	txn := NewTxn()
+	manifests := map[string]*chunkManifest{}
+	for _, pair := range pairs {
+		base, n, ok := parseChunkKey(pair.Key)
+		if !ok {
+			continue
+		}
+
+		manifest, seen := manifests[base]
+		if !seen {
+			value, listed := values[base]
+			if !listed && !strings.HasPrefix(base, prefix) {
+				value, _, err = c.KV.Get(base, nil)
+				if err != nil {
+					return 0, err
+				}
+			}
+			if value != nil {
+				manifest, _ = parseManifest(value.Value)
+			}
+			manifests[base] = manifest
+		}
+
+		if manifest == nil || n >= manifest.Chunks {
+			txn.Op(&api.KVTxnOp{Verb: api.KVDeleteCAS, Key: pair.Key, Index: pair.ModifyIndex})
+		}
+	}
+
+	deleted := 0
+	for _, t := range txn.Split() {
+		_, _, err := t.Commit(c.KV, nil)
+		if _, raced := err.(TxnFailedError); raced {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted += t.Len()
+	}
+	return deleted, nil
+}
+
+// write builds a transaction that stores p with op, which carries the verb
+// and index, and replaces any chunks of a previous value.
+func (c *ChunkedKV) write(p *api.KVPair, op *api.KVTxnOp) (*Txn, error) {
+	op.Key = p.Key
+	op.Flags = p.Flags
+	op.Value = p.Value
+	if bytes.HasPrefix(p.Value, manifestMagic) || bytes.HasPrefix(p.Value, verbatimMagic) {
+		op.Value = append(append([]byte{}, verbatimMagic...), p.Value...)
+	}
+
+	txn := NewTxn()
+	if len(op.Value) <= c.chunkSize {
+		return txn.Op(op).DeleteTree(p.Key + chunkDir), nil
+	}
+
+	chunks := (len(p.Value) + c.chunkSize - 1) / c.chunkSize
+	if max := MaxTxnOps - 2; chunks > max {
+		return nil, ValueTooLargeError{Key: p.Key, Size: len(p.Value), Max: max * c.chunkSize}
+	}
+
+	sum := sha256.Sum256(p.Value)
+	manifest, err := json.Marshal(chunkManifest{
+		Chunks: chunks,
+		Size:   len(p.Value),
+		SHA256: hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Value = append(append([]byte{}, manifestMagic...), manifest...)
+
+	txn.Op(op).DeleteTree(p.Key + chunkDir)
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * c.chunkSize
+		if end > len(p.Value) {
+			end = len(p.Value)
+		}
+		txn.Set(p.Key+chunkDir+strconv.Itoa(i), p.Value[i*c.chunkSize:end])
+	}
+	return txn, nil
+}
+
+// commitCAS commits a transaction whose first operation is a check-and-set,
+// reporting false rather than an error when only that operation failed.
+func commitCAS(txn *Txn, kv KV, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	_, meta, err := txn.Commit(kv, txnOptions(w))
+	if failed, ok := err.(TxnFailedError); ok && len(failed.Failures) == 1 && failed.Failures[0].Index == 0 {
+		return false, writeMeta(meta), nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	return true, writeMeta(meta), nil
+}
+
+// txnOptions carries the datacenter and token of a write over to the
+// transaction that performs it.
+func txnOptions(w *api.WriteOptions) *api.QueryOptions {
+	if w == nil {
+		return nil
+	}
+	return &api.QueryOptions{Datacenter: w.Datacenter, Token: w.Token}
+}
+
+func writeMeta(meta *api.QueryMeta) *api.WriteMeta {
+	if meta == nil {
+		return &api.WriteMeta{}
+	}
+	return &api.WriteMeta{RequestTime: meta.RequestTime}
+}
+
+func unescapeInline(value []byte) []byte {
+	return bytes.TrimPrefix(value, verbatimMagic)
+}
+
+func parseManifest(value []byte) (*chunkManifest, bool) {
+	if !bytes.HasPrefix(value, manifestMagic) {
+		return nil, false
+	}
+
+	manifest := &chunkManifest{}
+	if err := json.Unmarshal(value[len(manifestMagic):], manifest); err != nil {
+		return nil, false
+	}
+	return manifest, true
+}
+
+func parseChunkKey(key string) (string, int, bool) {
+	i := strings.LastIndex(key, chunkDir)
+	if i < 0 {
+		return "", 0, false
+	}
+
+	n, err := strconv.Atoi(key[i+len(chunkDir):])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return key[:i], n, true
+}
+
+// assemble joins the chunks of key, ignoring any other pairs, and verifies
+// the result against the manifest.
+func (m *chunkManifest) assemble(key string, pairs []*api.KVPair) ([]byte, error) {
+	chunks := map[int][]byte{}
+	for _, pair := range pairs {
+		if pair == nil {
+			continue
+		}
+		if base, n, ok := parseChunkKey(pair.Key); ok && base == key {
+			chunks[n] = pair.Value
+		}
+	}
+
+	value := make([]byte, 0, m.Size)
+	for i := 0; i < m.Chunks; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return nil, NewCorruptValueError(key, fmt.Sprintf("chunk %d of %d is missing", i, m.Chunks))
+		}
+		value = append(value, chunk...)
+	}
+
+	if len(value) != m.Size {
+		return nil, NewCorruptValueError(key, fmt.Sprintf("size %d does not match %d", len(value), m.Size))
+	}
+	sum := sha256.Sum256(value)
+	if hex.EncodeToString(sum[:]) != m.SHA256 {
+		return nil, NewCorruptValueError(key, "checksum mismatch")
+	}
+	return value, nil
+}
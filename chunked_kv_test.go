@@ -0,0 +1,232 @@
+package consuladapter_test
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChunkedKV", func() {
+	var (
+		raw     consuladapter.KV
+		chunked *consuladapter.ChunkedKV
+		blob    []byte
+	)
+
+	BeforeEach(func() {
+		raw = memconsul.NewClient().KV()
+		chunked = consuladapter.NewChunkedKV(raw, 4)
+		blob = []byte("0123456789")
+	})
+
+	It("splits large values into chunks and reassembles them", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob, Flags: 9}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		keys, _, err := raw.Keys("routes", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"routes", "routes/_chunks/0", "routes/_chunks/1", "routes/_chunks/2"}))
+
+		pair, _, err := chunked.Get("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(blob))
+		Expect(pair.Flags).To(Equal(uint64(9)))
+	})
+
+	It("writes the manifest and chunks at a single index", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pairs, _, err := raw.List("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, pair := range pairs {
+			Expect(pair.ModifyIndex).To(Equal(pairs[0].ModifyIndex))
+		}
+	})
+
+	It("stores small values as is and drops chunks of a previous value", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = chunked.Put(&api.KVPair{Key: "routes", Value: []byte("abc")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := raw.Get("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("abc")))
+
+		keys, _, err := raw.Keys("routes", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"routes"}))
+	})
+
+	It("hides chunks from List and Keys", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "blobs/a", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = chunked.Put(&api.KVPair{Key: "blobs/b", Value: []byte("b")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pairs, _, err := chunked.List("blobs/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(2))
+		Expect(pairs[0].Value).To(Equal(blob))
+		Expect(pairs[1].Value).To(Equal([]byte("b")))
+
+		keys, _, err := chunked.Keys("blobs/", "/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"blobs/a", "blobs/b"}))
+	})
+
+	It("checks and sets chunked values", func() {
+		ok, _, err := chunked.CAS(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		pair, _, err := chunked.Get("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, _, err = chunked.CAS(&api.KVPair{Key: "routes", Value: []byte("x"), ModifyIndex: pair.ModifyIndex + 1}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		ok, _, err = chunked.CAS(&api.KVPair{Key: "routes", Value: []byte("x"), ModifyIndex: pair.ModifyIndex}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("deletes chunks along with the key", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = chunked.Delete("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		keys, _, err := raw.Keys("", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(BeEmpty())
+
+		_, err = chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		pair, _, err := chunked.Get("routes", nil)
+		Expect(err).NotTo(HaveOccurred())
+		ok, _, err := chunked.DeleteCAS(pair, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		keys, _, err = raw.Keys("", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(BeEmpty())
+	})
+
+	It("detects corrupt chunks", func() {
+		_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = raw.Put(&api.KVPair{Key: "routes/_chunks/1", Value: []byte("XXXX")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = chunked.Get("routes", nil)
+		Expect(err).To(Equal(consuladapter.NewCorruptValueError("routes", "checksum mismatch")))
+
+		_, err = raw.Delete("routes/_chunks/2", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = chunked.Get("routes", nil)
+		Expect(err).To(Equal(consuladapter.NewCorruptValueError("routes", "chunk 2 of 3 is missing")))
+	})
+
+	It("refuses values that do not fit in one transaction", func() {
+		big := bytes.Repeat([]byte("x"), 4*(consuladapter.MaxTxnOps-1))
+		_, err := chunked.Put(&api.KVPair{Key: "big", Value: big}, nil)
+		Expect(err).To(Equal(consuladapter.ValueTooLargeError{Key: "big", Size: len(big), Max: 4 * (consuladapter.MaxTxnOps - 2)}))
+	})
+
+	It("stores inline values that look like a manifest verbatim", func() {
+		chunked = consuladapter.NewChunkedKV(raw, 64)
+		lookalike := []byte("\x00ck{\"chunks\":1,\"size\":1,\"sha256\":\"\"}")
+		_, err := chunked.Put(&api.KVPair{Key: "plain", Value: lookalike}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := chunked.Get("plain", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(lookalike))
+
+		pairs, _, err := chunked.List("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs[0].Value).To(Equal(lookalike))
+	})
+
+	It("passes the options of the caller on to the transactions", func() {
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.GetReturns(&api.KVPair{Key: "routes", Value: []byte("\x00ck{\"chunks\":0,\"size\":0,\"sha256\":\"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\"}")}, &api.QueryMeta{}, nil)
+		fakeKV.TxnReturns(true, &api.KVTxnResponse{}, &api.QueryMeta{RequestTime: time.Second}, nil)
+		chunked = consuladapter.NewChunkedKV(fakeKV, 4)
+
+		meta, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, &api.WriteOptions{Datacenter: "dc2", Token: "secret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.RequestTime).To(Equal(time.Second))
+		_, q := fakeKV.TxnArgsForCall(0)
+		Expect(q.Datacenter).To(Equal("dc2"))
+		Expect(q.Token).To(Equal("secret"))
+
+		_, _, err = chunked.Get("routes", &api.QueryOptions{Datacenter: "dc2", WaitIndex: 7})
+		Expect(err).NotTo(HaveOccurred())
+		_, q = fakeKV.TxnArgsForCall(1)
+		Expect(q.Datacenter).To(Equal("dc2"))
+		Expect(q.WaitIndex).To(BeZero())
+	})
+
+	Describe("CollectChunks", func() {
+		It("deletes chunks without a manifest", func() {
+			for _, key := range []string{"blobs/kept", "blobs/orphaned", "blobs/overwritten"} {
+				_, err := chunked.Put(&api.KVPair{Key: key, Value: blob}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			_, err := raw.Delete("blobs/orphaned", nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = raw.Put(&api.KVPair{Key: "blobs/overwritten", Value: []byte("plain")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			deleted, err := chunked.CollectChunks("blobs/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal(6))
+
+			keys, _, err := raw.Keys("blobs/", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{
+				"blobs/kept",
+				"blobs/kept/_chunks/0",
+				"blobs/kept/_chunks/1",
+				"blobs/kept/_chunks/2",
+				"blobs/overwritten",
+			}))
+		})
+
+		It("looks up manifests outside the prefix", func() {
+			_, err := chunked.Put(&api.KVPair{Key: "routes", Value: blob}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			deleted, err := chunked.CollectChunks("routes/_chunks/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeZero())
+		})
+
+		It("collects more chunks than fit in one transaction", func() {
+			for i := 0; i < 30; i++ {
+				key := fmt.Sprintf("blobs/%d", i)
+				_, err := chunked.Put(&api.KVPair{Key: key, Value: blob}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = raw.Delete(key, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			deleted, err := chunked.CollectChunks("blobs/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal(90))
+		})
+	})
+})
@@ -1,7 +1,9 @@
 package consuladapter
 
 import (
-	cfhttp "code.cloudfoundry.org/cfhttp/v2"
+	"context"
+
+	"code.cloudfoundry.org/consuladapter/internal/acquire"
 	"github.com/hashicorp/consul/api"
 )
 
@@ -11,103 +13,235 @@ type Client interface {
 	Agent() Agent
 	Session() Session
 	Catalog() Catalog
+	Health() Health
 	KV() KV
 	Status() Status
 
 	LockOpts(opts *api.LockOptions) (Lock, error)
+	SemaphoreOpts(opts *api.SemaphoreOptions) (Semaphore, error)
+
+	// WithContext returns a Client whose requests are cancelled when ctx is
+	// done. Clients created with NewConsulClient return ctx.Err() once ctx is
+	// done but leave their requests to finish in the background.
+	WithContext(ctx context.Context) Client
 }
 
 //go:generate counterfeiter -o fakes/fake_lock.go . Lock
 
 type Lock interface {
 	Lock(stopCh <-chan struct{}) (lostLock <-chan struct{}, err error)
+	LockContext(ctx context.Context) (lostLock <-chan struct{}, err error)
+
+	// Unlock releases the lock, returning a LockNotHeldError if it is not
+	// held. Destroy deletes the lock key once no session holds it, returning a
+	// LockInUseError otherwise.
+	Unlock() error
+	Destroy() error
+}
+
+//go:generate counterfeiter -o fakes/fake_semaphore.go . Semaphore
+
+type Semaphore interface {
+	Acquire(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error)
+	AcquireContext(ctx context.Context) (lostSlot <-chan struct{}, err error)
+
+	// Release gives up the slot, returning a SemaphoreNotHeldError if it is
+	// not held. Destroy deletes the semaphore once it has no holders,
+	// returning a SemaphoreInUseError otherwise.
+	Release() error
+	Destroy() error
 }
 
 type client struct {
 	client *api.Client
+	config *api.Config
 }
 
 func NewConsulClient(c *api.Client) Client {
 	return &client{client: c}
 }
 
-func NewClientFromUrl(urlString string) (Client, error) {
+func New(urlString string, opts ...Option) (Client, error) {
 	scheme, address, err := Parse(urlString)
 	if err != nil {
 		return nil, err
 	}
 
-	config := &api.Config{
-		Address:    address,
-		Scheme:     scheme,
-		HttpClient: cfhttp.NewClient(cfhttp.WithStreamingDefaults()),
+	config, err := newClientConfig(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	c, err := api.NewClient(config)
+	httpClient, err := config.buildHTTPClient(address)
 	if err != nil {
 		return nil, err
 	}
 
-	return &client{client: c}, nil
+	return newClient(config.apiConfig(scheme, address, httpClient))
 }
 
-func NewTLSClientFromUrl(urlString, caCert, clientCert, clientKey string) (Client, error) {
-	scheme, address, err := Parse(urlString)
+func NewFromUrls(urls []string, opts ...Option) (Client, error) {
+	config, err := newClientConfig(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	tlsConfig := api.TLSConfig{
-		Address:  address,
-		CAFile:   caCert,
-		CertFile: clientCert,
-		KeyFile:  clientKey,
+	httpClient, err := config.buildHTTPClient("")
+	if err != nil {
+		return nil, err
 	}
 
-	tlsClientConfig, err := api.SetupTLSConfig(&tlsConfig)
+	transport, err := newFailoverTransport(httpClient.Transport, urls)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient := cfhttp.NewClient(
-		cfhttp.WithStreamingDefaults(),
-		cfhttp.WithTLSConfig(tlsClientConfig),
-	)
+	failoverClient := *httpClient
+	failoverClient.Transport = transport
 
-	config := &api.Config{
-		Address:    address,
-		Scheme:     scheme,
-		HttpClient: httpClient,
-	}
+	first := transport.endpoints[0]
+	return newClient(config.apiConfig(first.scheme, first.address, &failoverClient))
+}
 
+func newClient(config *api.Config) (*client, error) {
 	c, err := api.NewClient(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &client{client: c}, nil
+	return &client{client: c, config: config}, nil
+}
+
+func NewClientFromUrl(urlString string) (Client, error) {
+	return New(urlString)
+}
+
+func NewTLSClientFromUrl(urlString, caCert, clientCert, clientKey string) (Client, error) {
+	return New(urlString, WithTLSFiles(caCert, clientCert, clientKey))
+}
+
+func NewClientFromUrls(urls []string) (Client, error) {
+	return NewFromUrls(urls)
+}
+
+func NewTLSClientFromUrls(urls []string, caCert, clientCert, clientKey string) (Client, error) {
+	return NewFromUrls(urls, WithTLSFiles(caCert, clientCert, clientKey))
 }
 
 func (c *client) Agent() Agent {
-	return NewConsulAgent(c.client.Agent())
+	return &agent{agent: c.client.Agent(), client: c}
 }
 
 func (c *client) KV() KV {
-	return NewConsulKV(c.client.KV())
+	return &keyValue{keyValue: c.client.KV(), client: c}
 }
 
 func (c *client) Catalog() Catalog {
-	return NewConsulCatalog(c.client.Catalog())
+	return &catalog{catalog: c.client.Catalog(), client: c}
+}
+
+func (c *client) Health() Health {
+	return &health{health: c.client.Health(), client: c}
 }
 
 func (c *client) Session() Session {
-	return NewConsulSession(c.client.Session())
+	return &session{session: c.client.Session(), client: c}
 }
 
 func (c *client) LockOpts(opts *api.LockOptions) (Lock, error) {
-	return c.client.LockOpts(opts)
+	l, err := c.client.LockOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lock{lock: l, key: opts.Key}, nil
+}
+
+func (c *client) SemaphoreOpts(opts *api.SemaphoreOptions) (Semaphore, error) {
+	s, err := c.client.SemaphoreOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &semaphore{semaphore: s, prefix: opts.Prefix}, nil
 }
 
 func (c *client) Status() Status {
-	return NewConsulStatus(c.client.Status())
+	return &status{status: c.client.Status(), client: c}
+}
+
+func (c *client) WithContext(ctx context.Context) Client {
+	if c.config == nil {
+		return &contextClient{client: c, ctx: ctx}
+	}
+
+	bound, err := newClient(withContext(c.config, ctx))
+	if err != nil {
+		return &contextClient{client: c, ctx: ctx}
+	}
+
+	bound.config = c.config
+	return bound
+}
+
+type lock struct {
+	lock *api.Lock
+	key  string
+}
+
+func (l *lock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	return l.lock.Lock(stopCh)
+}
+
+// LockContext is Lock with stopCh closed when ctx is done, in which case it
+// returns ctx.Err().
+func (l *lock) LockContext(ctx context.Context) (<-chan struct{}, error) {
+	return acquire.UntilDone(ctx, l.lock.Lock)
+}
+
+func (l *lock) Unlock() error {
+	err := l.lock.Unlock()
+	if err == api.ErrLockNotHeld {
+		return NewLockNotHeldError(l.key)
+	}
+	return err
+}
+
+func (l *lock) Destroy() error {
+	err := l.lock.Destroy()
+	if err == api.ErrLockHeld || err == api.ErrLockInUse {
+		return NewLockInUseError(l.key)
+	}
+	return err
+}
+
+type semaphore struct {
+	semaphore *api.Semaphore
+	prefix    string
+}
+
+func (s *semaphore) Acquire(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	return s.semaphore.Acquire(stopCh)
+}
+
+// AcquireContext is Acquire with stopCh closed when ctx is done, in which case
+// it returns ctx.Err().
+func (s *semaphore) AcquireContext(ctx context.Context) (<-chan struct{}, error) {
+	return acquire.UntilDone(ctx, s.semaphore.Acquire)
+}
+
+func (s *semaphore) Release() error {
+	err := s.semaphore.Release()
+	if err == api.ErrSemaphoreNotHeld {
+		return NewSemaphoreNotHeldError(s.prefix)
+	}
+	return err
+}
+
+func (s *semaphore) Destroy() error {
+	err := s.semaphore.Destroy()
+	if err == api.ErrSemaphoreHeld || err == api.ErrSemaphoreInUse {
+		return NewSemaphoreInUseError(s.prefix)
+	}
+	return err
 }
@@ -39,6 +39,20 @@ var _ = Describe("ConsulAdapter Client", func() {
 			})
 		})
 
+		Context("with a HTTPS client for multiple urls", func() {
+			It("is able to query consul when an endpoint is unreachable", func() {
+				consulClient, err = consuladapter.NewTLSClientFromUrls(
+					[]string{"https://127.0.0.1:1", consulRunner.URL()},
+					consulCACert,
+					consulClientCert,
+					consulCLientKey,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = consulClient.Status().Leader()
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
 		Context("with a HTTP client", func() {
 			It("is not able to query consul on the HTTPS endpoint", func() {
 				consulClient, err = consuladapter.NewClientFromUrl("http://" + consulRunner.Address())
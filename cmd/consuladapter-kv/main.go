@@ -0,0 +1,207 @@
+// Command consuladapter-kv exports the keys under a Consul KV prefix to a
+// JSON or YAML file and imports them back.
+//
+//	consuladapter-kv export -prefix cells/ -file cells.json
+//	consuladapter-kv import -file cells.json -prefix restored/ -dry-run
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+// newKV is replaced in tests.
+var newKV = func(addr string, opts ...consuladapter.Option) (consuladapter.KV, error) {
+	client, err := consuladapter.New(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return client.KV(), nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(os.Args[2:], os.Stdout, os.Stderr)
+	case "import":
+		err = imp(os.Args[2:], os.Stdin, os.Stdout)
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "consuladapter-kv: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: consuladapter-kv export|import [flags]")
+	os.Exit(2)
+}
+
+type connection struct {
+	addr       string
+	token      string
+	caCert     string
+	clientCert string
+	clientKey  string
+}
+
+func connectionFlags(flags *flag.FlagSet) *connection {
+	c := &connection{}
+	flags.StringVar(&c.addr, "addr", "http://127.0.0.1:8500", "consul agent URL")
+	flags.StringVar(&c.token, "token", "", "ACL token")
+	flags.StringVar(&c.caCert, "ca-cert", "", "CA certificate file for https")
+	flags.StringVar(&c.clientCert, "client-cert", "", "client certificate file for https")
+	flags.StringVar(&c.clientKey, "client-key", "", "client key file for https")
+	return c
+}
+
+func (c *connection) kv() (consuladapter.KV, error) {
+	var opts []consuladapter.Option
+	if c.token != "" {
+		opts = append(opts, consuladapter.WithToken(c.token))
+	}
+	if c.caCert != "" || c.clientCert != "" || c.clientKey != "" {
+		opts = append(opts, consuladapter.WithTLSFiles(c.caCert, c.clientCert, c.clientKey))
+	}
+	return newKV(c.addr, opts...)
+}
+
+func export(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := connectionFlags(flags)
+	prefix := flags.String("prefix", "", "prefix to export")
+	file := flags.String("file", "", "file to write, .json or .yaml (default stdout as JSON)")
+	flags.Parse(args)
+
+	kv, err := conn.kv()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := consuladapter.ExportSnapshot(kv, *prefix)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		if err := consuladapter.WriteSnapshot(stdout, snapshot, format(*file)); err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		if err := consuladapter.WriteSnapshot(f, snapshot, format(*file)); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stderr, "exported %d keys\n", len(snapshot.Pairs))
+	return nil
+}
+
+func imp(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	conn := connectionFlags(flags)
+	file := flags.String("file", "", "file to read, .json or .yaml (default stdin as JSON)")
+	prefix := flags.String("prefix", "", "prefix to import under instead of the exported one")
+	overwrite := flags.Bool("overwrite", false, "update keys that exist with a different value")
+	dryRun := flags.Bool("dry-run", false, "print the changes and value differences without writing them")
+	flags.Parse(args)
+
+	r := stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	snapshot, err := consuladapter.ReadSnapshot(r, format(*file))
+	if err != nil {
+		return err
+	}
+
+	kv, err := conn.kv()
+	if err != nil {
+		return err
+	}
+
+	changes, err := consuladapter.ImportSnapshot(kv, snapshot, consuladapter.ImportOptions{
+		Prefix:    *prefix,
+		Overwrite: *overwrite,
+		DryRun:    *dryRun,
+	})
+	for _, change := range changes {
+		fmt.Fprintf(stdout, "%-9s %s\n", change.Action, change.Key)
+		if *dryRun {
+			printDiff(stdout, change)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.Action == consuladapter.ImportConflict {
+			return fmt.Errorf("some keys changed while importing")
+		}
+	}
+	return nil
+}
+
+// printDiff prints the value a change replaces and the value it writes.
+func printDiff(w io.Writer, change consuladapter.ImportChange) {
+	if change.Action == consuladapter.ImportUnchanged {
+		return
+	}
+	if change.Old != nil {
+		if bytes.Equal(change.Old.Value, change.New.Value) {
+			fmt.Fprintf(w, "  flags %d -> %d\n", change.Old.Flags, change.New.Flags)
+			return
+		}
+		printValue(w, "-", change.Old.Value)
+	}
+	printValue(w, "+", change.New.Value)
+}
+
+func printValue(w io.Writer, sign string, value []byte) {
+	if !utf8.Valid(value) {
+		fmt.Fprintf(w, "  %s <%d bytes>\n", sign, len(value))
+		return
+	}
+	for _, line := range strings.Split(string(value), "\n") {
+		fmt.Fprintf(w, "  %s %s\n", sign, line)
+	}
+}
+
+func format(file string) consuladapter.SnapshotFormat {
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		return consuladapter.SnapshotYAML
+	default:
+		return consuladapter.SnapshotJSON
+	}
+}
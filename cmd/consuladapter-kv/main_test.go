@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("consuladapter-kv", func() {
+	var (
+		kv     consuladapter.KV
+		realKV = newKV
+		dir    string
+		file   string
+	)
+
+	BeforeEach(func() {
+		kv = memconsul.NewClient().KV()
+		newKV = func(addr string, opts ...consuladapter.Option) (consuladapter.KV, error) {
+			return kv, nil
+		}
+
+		var err error
+		dir, err = os.MkdirTemp("", "consuladapter-kv")
+		Expect(err).NotTo(HaveOccurred())
+		file = filepath.Join(dir, "cells.yaml")
+
+		for key, value := range map[string]string{"cells/a": "one", "cells/b": "two", "other": "three"} {
+			_, err := kv.Put(&api.KVPair{Key: key, Value: []byte(value)}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	AfterEach(func() {
+		newKV = realKV
+		os.RemoveAll(dir)
+	})
+
+	get := func(key string) string {
+		pair, _, err := kv.Get(key, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if pair == nil {
+			return ""
+		}
+		return string(pair.Value)
+	}
+
+	It("exports a prefix and imports it back", func() {
+		var stderr bytes.Buffer
+		Expect(export([]string{"-prefix", "cells/", "-file", file}, &bytes.Buffer{}, &stderr)).To(Succeed())
+		Expect(stderr.String()).To(Equal("exported 2 keys\n"))
+
+		_, err := kv.DeleteTree("cells/", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stdout bytes.Buffer
+		Expect(imp([]string{"-file", file, "-prefix", "restored/"}, nil, &stdout)).To(Succeed())
+		Expect(stdout.String()).To(Equal("create    restored/a\ncreate    restored/b\n"))
+		Expect(get("restored/a")).To(Equal("one"))
+		Expect(get("restored/b")).To(Equal("two"))
+	})
+
+	It("prints the value differences on a dry run without writing", func() {
+		Expect(export([]string{"-prefix", "cells/", "-file", file}, &bytes.Buffer{}, &bytes.Buffer{})).To(Succeed())
+		_, err := kv.Put(&api.KVPair{Key: "cells/a", Value: []byte("changed")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = kv.Delete("cells/b", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stdout bytes.Buffer
+		Expect(imp([]string{"-file", file, "-overwrite", "-dry-run"}, nil, &stdout)).To(Succeed())
+		Expect(stdout.String()).To(Equal("update    cells/a\n  - changed\n  + one\ncreate    cells/b\n  + two\n"))
+		Expect(get("cells/a")).To(Equal("changed"))
+		Expect(get("cells/b")).To(BeEmpty())
+	})
+
+	Context("against an agent", func() {
+		var (
+			server *httptest.Server
+			tokens chan string
+		)
+
+		BeforeEach(func() {
+			newKV = realKV
+			tokens = make(chan string, 1)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokens <- r.Header.Get("X-Consul-Token")
+				w.Header().Set("X-Consul-Index", "1")
+				fmt.Fprint(w, `[]`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("sends the token", func() {
+			Expect(export([]string{"-addr", server.URL, "-token", "secret"}, &bytes.Buffer{}, &bytes.Buffer{})).To(Succeed())
+			Expect(tokens).To(Receive(Equal("secret")))
+		})
+
+		It("loads the TLS files", func() {
+			err := export([]string{"-addr", server.URL, "-ca-cert", filepath.Join(dir, "missing.crt")}, &bytes.Buffer{}, &bytes.Buffer{})
+			Expect(err).To(MatchError(ContainSubstring("missing.crt")))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package consuladapter
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/hashicorp/consul/api"
+)
+
+func withContext(config *api.Config, ctx context.Context) *api.Config {
+	httpClient := http.Client{}
+	if config.HttpClient != nil {
+		httpClient = *config.HttpClient
+	}
+
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient.Transport = &contextTransport{ctx: ctx, transport: transport}
+
+	c := *config
+	c.HttpClient = &httpClient
+	return &c
+}
+
+// contextTransport cancels every request when either its own context or ctx is
+// done. The request stays bound until its response body is closed.
+type contextTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := mergeContext(req.Context(), t.ctx)
+
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if t.ctx.Err() != nil {
+			return nil, t.ctx.Err()
+		}
+		return nil, err
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func mergeContext(parent, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-other.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
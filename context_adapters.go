@@ -0,0 +1,380 @@
+package consuladapter
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Adapters built from api types, rather than from a client config, cannot
+// cancel their requests. WithContext binds them to ctx by returning as soon as
+// ctx is done, with ctx.Err(), and leaving the request to finish in the
+// background.
+
+type contextClient struct {
+	client *client
+	ctx    context.Context
+}
+
+func (c *contextClient) Agent() Agent {
+	return &contextAgent{agent: c.client.Agent(), ctx: c.ctx}
+}
+
+func (c *contextClient) Session() Session {
+	return &contextSession{session: c.client.Session(), ctx: c.ctx}
+}
+
+func (c *contextClient) Catalog() Catalog {
+	return &contextCatalog{catalog: c.client.Catalog(), ctx: c.ctx}
+}
+
+func (c *contextClient) Health() Health {
+	return &contextHealth{health: c.client.Health(), ctx: c.ctx}
+}
+
+func (c *contextClient) KV() KV {
+	return &contextKV{kv: c.client.KV(), ctx: c.ctx}
+}
+
+func (c *contextClient) Status() Status {
+	return &contextStatus{status: c.client.Status(), ctx: c.ctx}
+}
+
+func (c *contextClient) LockOpts(opts *api.LockOptions) (Lock, error) {
+	return c.client.LockOpts(opts)
+}
+
+func (c *contextClient) SemaphoreOpts(opts *api.SemaphoreOptions) (Semaphore, error) {
+	return c.client.SemaphoreOpts(opts)
+}
+
+func (c *contextClient) WithContext(ctx context.Context) Client {
+	return &contextClient{client: c.client, ctx: ctx}
+}
+
+type contextAgent struct {
+	agent Agent
+	ctx   context.Context
+}
+
+func (a *contextAgent) Self() (map[string]map[string]interface{}, error) {
+	return callContext(a.ctx, a.agent.Self)
+}
+
+func (a *contextAgent) Members(wan bool) ([]*api.AgentMember, error) {
+	return callContext(a.ctx, func() ([]*api.AgentMember, error) { return a.agent.Members(wan) })
+}
+
+func (a *contextAgent) Checks() (map[string]*api.AgentCheck, error) {
+	return callContext(a.ctx, a.agent.Checks)
+}
+
+func (a *contextAgent) Services() (map[string]*api.AgentService, error) {
+	return callContext(a.ctx, a.agent.Services)
+}
+
+func (a *contextAgent) ServiceRegister(service *api.AgentServiceRegistration) error {
+	return callContext0(a.ctx, func() error { return a.agent.ServiceRegister(service) })
+}
+
+func (a *contextAgent) ServiceDeregister(serviceID string) error {
+	return callContext0(a.ctx, func() error { return a.agent.ServiceDeregister(serviceID) })
+}
+
+func (a *contextAgent) PassTTL(checkID, note string) error {
+	return callContext0(a.ctx, func() error { return a.agent.PassTTL(checkID, note) })
+}
+
+func (a *contextAgent) WarnTTL(checkID, note string) error {
+	return callContext0(a.ctx, func() error { return a.agent.WarnTTL(checkID, note) })
+}
+
+func (a *contextAgent) FailTTL(checkID, note string) error {
+	return callContext0(a.ctx, func() error { return a.agent.FailTTL(checkID, note) })
+}
+
+func (a *contextAgent) UpdateTTL(checkID, output, status string) error {
+	return callContext0(a.ctx, func() error { return a.agent.UpdateTTL(checkID, output, status) })
+}
+
+func (a *contextAgent) NodeName() (string, error) {
+	return callContext(a.ctx, a.agent.NodeName)
+}
+
+func (a *contextAgent) CheckRegister(check *api.AgentCheckRegistration) error {
+	return callContext0(a.ctx, func() error { return a.agent.CheckRegister(check) })
+}
+
+func (a *contextAgent) CheckDeregister(checkID string) error {
+	return callContext0(a.ctx, func() error { return a.agent.CheckDeregister(checkID) })
+}
+
+func (a *contextAgent) Join(addr string, wan bool) error {
+	return callContext0(a.ctx, func() error { return a.agent.Join(addr, wan) })
+}
+
+func (a *contextAgent) ForceLeave(node string) error {
+	return callContext0(a.ctx, func() error { return a.agent.ForceLeave(node) })
+}
+
+func (a *contextAgent) EnableServiceMaintenance(serviceID, reason string) error {
+	return callContext0(a.ctx, func() error { return a.agent.EnableServiceMaintenance(serviceID, reason) })
+}
+
+func (a *contextAgent) DisableServiceMaintenance(serviceID string) error {
+	return callContext0(a.ctx, func() error { return a.agent.DisableServiceMaintenance(serviceID) })
+}
+
+func (a *contextAgent) EnableNodeMaintenance(reason string) error {
+	return callContext0(a.ctx, func() error { return a.agent.EnableNodeMaintenance(reason) })
+}
+
+func (a *contextAgent) DisableNodeMaintenance() error {
+	return callContext0(a.ctx, a.agent.DisableNodeMaintenance)
+}
+
+func (a *contextAgent) WithContext(ctx context.Context) Agent {
+	return &contextAgent{agent: a.agent, ctx: ctx}
+}
+
+type contextCatalog struct {
+	catalog Catalog
+	ctx     context.Context
+}
+
+func (c *contextCatalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
+	return callContext2(c.ctx, func() ([]*api.Node, *api.QueryMeta, error) { return c.catalog.Nodes(q) })
+}
+
+func (c *contextCatalog) Node(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error) {
+	return callContext2(c.ctx, func() (*api.CatalogNode, *api.QueryMeta, error) { return c.catalog.Node(node, q) })
+}
+
+func (c *contextCatalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	return callContext2(c.ctx, func() (map[string][]string, *api.QueryMeta, error) { return c.catalog.Services(q) })
+}
+
+func (c *contextCatalog) Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	return callContext2(c.ctx, func() ([]*api.CatalogService, *api.QueryMeta, error) { return c.catalog.Service(service, tag, q) })
+}
+
+func (c *contextCatalog) Datacenters() ([]string, error) {
+	return callContext(c.ctx, c.catalog.Datacenters)
+}
+
+func (c *contextCatalog) Register(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(c.ctx, func() (*api.WriteMeta, error) { return c.catalog.Register(reg, q) })
+}
+
+func (c *contextCatalog) Deregister(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(c.ctx, func() (*api.WriteMeta, error) { return c.catalog.Deregister(dereg, q) })
+}
+
+func (c *contextCatalog) WithContext(ctx context.Context) Catalog {
+	return &contextCatalog{catalog: c.catalog, ctx: ctx}
+}
+
+type contextHealth struct {
+	health Health
+	ctx    context.Context
+}
+
+func (h *contextHealth) Node(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return callContext2(h.ctx, func() ([]*api.HealthCheck, *api.QueryMeta, error) { return h.health.Node(node, q) })
+}
+
+func (h *contextHealth) Checks(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return callContext2(h.ctx, func() ([]*api.HealthCheck, *api.QueryMeta, error) { return h.health.Checks(service, q) })
+}
+
+func (h *contextHealth) Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	return callContext2(h.ctx, func() ([]*api.ServiceEntry, *api.QueryMeta, error) {
+		return h.health.Service(service, tag, passingOnly, q)
+	})
+}
+
+func (h *contextHealth) State(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return callContext2(h.ctx, func() ([]*api.HealthCheck, *api.QueryMeta, error) { return h.health.State(state, q) })
+}
+
+func (h *contextHealth) WithContext(ctx context.Context) Health {
+	return &contextHealth{health: h.health, ctx: ctx}
+}
+
+type contextKV struct {
+	kv  KV
+	ctx context.Context
+}
+
+func (kv *contextKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	return callContext2(kv.ctx, func() (*api.KVPair, *api.QueryMeta, error) { return kv.kv.Get(key, q) })
+}
+
+func (kv *contextKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return callContext2(kv.ctx, func() (api.KVPairs, *api.QueryMeta, error) { return kv.kv.List(prefix, q) })
+}
+
+func (kv *contextKV) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	return callContext2(kv.ctx, func() ([]string, *api.QueryMeta, error) { return kv.kv.Keys(prefix, separator, q) })
+}
+
+func (kv *contextKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(kv.ctx, func() (*api.WriteMeta, error) { return kv.kv.Put(p, q) })
+}
+
+func (kv *contextKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return callContext2(kv.ctx, func() (bool, *api.WriteMeta, error) { return kv.kv.CAS(p, q) })
+}
+
+func (kv *contextKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return callContext2(kv.ctx, func() (bool, *api.WriteMeta, error) { return kv.kv.Acquire(p, q) })
+}
+
+func (kv *contextKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return callContext2(kv.ctx, func() (bool, *api.WriteMeta, error) { return kv.kv.Release(p, q) })
+}
+
+func (kv *contextKV) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(kv.ctx, func() (*api.WriteMeta, error) { return kv.kv.Delete(key, w) })
+}
+
+func (kv *contextKV) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return callContext2(kv.ctx, func() (bool, *api.WriteMeta, error) { return kv.kv.DeleteCAS(p, q) })
+}
+
+func (kv *contextKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(kv.ctx, func() (*api.WriteMeta, error) { return kv.kv.DeleteTree(prefix, w) })
+}
+
+func (kv *contextKV) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	type result struct {
+		ok   bool
+		resp *api.KVTxnResponse
+	}
+	r, meta, err := callContext2(kv.ctx, func() (result, *api.QueryMeta, error) {
+		ok, resp, meta, err := kv.kv.Txn(txn, q)
+		return result{ok: ok, resp: resp}, meta, err
+	})
+	return r.ok, r.resp, meta, err
+}
+
+func (kv *contextKV) WithContext(ctx context.Context) KV {
+	return &contextKV{kv: kv.kv, ctx: ctx}
+}
+
+type contextSession struct {
+	session Session
+	ctx     context.Context
+}
+
+func (s *contextSession) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	return callContext2(s.ctx, func() (string, *api.WriteMeta, error) { return s.session.Create(se, q) })
+}
+
+func (s *contextSession) CreateNoChecks(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	return callContext2(s.ctx, func() (string, *api.WriteMeta, error) { return s.session.CreateNoChecks(se, q) })
+}
+
+func (s *contextSession) Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return callContext(s.ctx, func() (*api.WriteMeta, error) { return s.session.Destroy(id, q) })
+}
+
+func (s *contextSession) Info(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
+	return callContext2(s.ctx, func() (*api.SessionEntry, *api.QueryMeta, error) { return s.session.Info(id, q) })
+}
+
+func (s *contextSession) List(q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return callContext2(s.ctx, func() ([]*api.SessionEntry, *api.QueryMeta, error) { return s.session.List(q) })
+}
+
+func (s *contextSession) Node(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return callContext2(s.ctx, func() ([]*api.SessionEntry, *api.QueryMeta, error) { return s.session.Node(node, q) })
+}
+
+func (s *contextSession) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+	return callContext2(s.ctx, func() (*api.SessionEntry, *api.WriteMeta, error) { return s.session.Renew(id, q) })
+}
+
+// RenewPeriodic also stops renewing when ctx is done.
+func (s *contextSession) RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
+	stopCh := make(chan struct{})
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	go func() {
+		select {
+		case <-doneCh:
+		case <-s.ctx.Done():
+		case <-stopped:
+			return
+		}
+		close(stopCh)
+	}()
+
+	err := s.session.RenewPeriodic(initialTTL, id, q, stopCh)
+	if err == nil && s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+	return err
+}
+
+func (s *contextSession) WithContext(ctx context.Context) Session {
+	return &contextSession{session: s.session, ctx: ctx}
+}
+
+type contextStatus struct {
+	status Status
+	ctx    context.Context
+}
+
+func (s *contextStatus) Leader() (string, error) {
+	return callContext(s.ctx, s.status.Leader)
+}
+
+func (s *contextStatus) Peers() ([]string, error) {
+	return callContext(s.ctx, s.status.Peers)
+}
+
+func (s *contextStatus) WithContext(ctx context.Context) Status {
+	return &contextStatus{status: s.status, ctx: ctx}
+}
+
+func callContext0(ctx context.Context, call func() error) error {
+	_, err := callContext(ctx, func() (struct{}, error) { return struct{}{}, call() })
+	return err
+}
+
+func callContext[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	v, _, err := callContext2(ctx, func() (T, struct{}, error) {
+		v, err := call()
+		return v, struct{}{}, err
+	})
+	return v, err
+}
+
+func callContext2[T, U any](ctx context.Context, call func() (T, U, error)) (T, U, error) {
+	type result struct {
+		t   T
+		u   U
+		err error
+	}
+
+	var zeroT T
+	var zeroU U
+	if err := ctx.Err(); err != nil {
+		return zeroT, zeroU, err
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		t, u, err := call()
+		done <- result{t: t, u: u, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.t, r.u, r.err
+	case <-ctx.Done():
+		return zeroT, zeroU, ctx.Err()
+	}
+}
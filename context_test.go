@@ -0,0 +1,133 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Context", func() {
+	var (
+		server *httptest.Server
+		client consuladapter.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("index") != "" {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+			}
+			w.Header().Set("X-Consul-Index", "1")
+			fmt.Fprint(w, `[]`)
+		}))
+
+		var err error
+		client, err = consuladapter.New(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	blockingList := func(kv consuladapter.KV) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, _, err := kv.List("prefix", &api.QueryOptions{WaitIndex: 1, WaitTime: time.Minute})
+			errs <- err
+		}()
+		return errs
+	}
+
+	It("cancels an in-flight blocking query when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := blockingList(client.WithContext(ctx).KV())
+
+		Consistently(errs, 100*time.Millisecond).ShouldNot(Receive())
+		cancel()
+
+		var err error
+		Eventually(errs).Should(Receive(&err))
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+
+	It("enforces the context deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		var err error
+		Eventually(blockingList(client.KV().WithContext(ctx))).Should(Receive(&err))
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("leaves the parent client unbound", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := client.WithContext(ctx).KV().List("prefix", nil)
+		Expect(err).To(HaveOccurred())
+
+		_, _, err = client.KV().List("prefix", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rebinds rather than nests contexts", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := client.WithContext(ctx).WithContext(context.Background()).KV().List("prefix", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("with adapters created from api types", func() {
+		var apiClient *api.Client
+
+		BeforeEach(func() {
+			var err error
+			apiClient, err = api.NewClient(&api.Config{Address: server.Listener.Addr().String()})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := blockingList(consuladapter.NewConsulKV(apiClient.KV()).WithContext(ctx))
+
+			Consistently(errs, 100*time.Millisecond).ShouldNot(Receive())
+			cancel()
+
+			var err error
+			Eventually(errs).Should(Receive(&err))
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+
+			// The abandoned request is still in flight.
+			server.CloseClientConnections()
+		})
+
+		It("binds clients and the adapters they create", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			client := consuladapter.NewConsulClient(apiClient)
+			_, err := client.WithContext(ctx).Status().Leader()
+			Expect(err).To(MatchError(context.Canceled))
+			_, _, err = client.Health().WithContext(ctx).Service("web", "", false, nil)
+			Expect(err).To(MatchError(context.Canceled))
+
+			_, _, err = client.WithContext(ctx).WithContext(context.Background()).KV().List("prefix", nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,212 @@
+package consuladapter
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultDrainPollInterval = time.Second
+
+type DrainStage string
+
+const (
+	// DrainMaintenance reports the node, or a service when ServiceID is set,
+	// entering maintenance mode.
+	DrainMaintenance DrainStage = "maintenance"
+	// DrainWaiting reports the start of the wait for in-flight work.
+	DrainWaiting DrainStage = "waiting"
+	// DrainReleased reports a key released from one of the node's sessions.
+	DrainReleased     DrainStage = "released"
+	DrainDeregistered DrainStage = "deregistered"
+	DrainComplete     DrainStage = "complete"
+	// DrainLeftMaintenance reports the node, or a service when ServiceID is
+	// set, leaving maintenance mode on undrain.
+	DrainLeftMaintenance DrainStage = "left-maintenance"
+)
+
+type DrainEvent struct {
+	Stage     DrainStage
+	ServiceID string
+	Key       string
+}
+
+type DrainOptions struct {
+	// ServiceIDs are put into maintenance. If empty, the whole node is.
+	ServiceIDs []string
+	Reason     string
+
+	// QuietPeriod is waited out after entering maintenance. Drained, if set,
+	// is then polled every PollInterval until it reports true.
+	QuietPeriod  time.Duration
+	Drained      func() (bool, error)
+	PollInterval time.Duration
+
+	// ReleasePrefixes are searched for keys held by the sessions of the
+	// agent's node, such as presence keys and locks, which are released.
+	// This frees keys left behind by owners that stopped without giving them
+	// up. Owners still running, such as a Presence, acquire their keys again,
+	// so they should be stopped before, for instance from Drained.
+	ReleasePrefixes []string
+
+	// Deregister deregisters ServiceIDs once drained. Undrain cannot bring
+	// deregistered services back.
+	Deregister bool
+
+	// OnProgress, if set, is called as the drain advances.
+	OnProgress func(DrainEvent)
+}
+
+// Drainer takes the local agent's node or some of its services out of
+// discovery through maintenance mode, waits for in-flight work to finish and
+// gives up the keys the node holds. Undrain reverses the maintenance mode.
+type Drainer struct {
+	client Client
+	opts   DrainOptions
+}
+
+func NewDrainer(client Client, opts DrainOptions) (*Drainer, error) {
+	if opts.QuietPeriod < 0 {
+		return nil, NewInvalidOptionError("QuietPeriod", "must not be negative")
+	}
+	if opts.Deregister && len(opts.ServiceIDs) == 0 {
+		return nil, NewInvalidOptionError("Deregister", "requires ServiceIDs")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultDrainPollInterval
+	}
+	return &Drainer{client: client, opts: opts}, nil
+}
+
+// Drain runs the drain to completion, stopping early with ctx's error if ctx
+// is done. Maintenance mode stays on in either case.
+func (d *Drainer) Drain(ctx context.Context) error {
+	client := d.client.WithContext(ctx)
+	agent := client.Agent()
+
+	if len(d.opts.ServiceIDs) == 0 {
+		if err := agent.EnableNodeMaintenance(d.opts.Reason); err != nil {
+			return err
+		}
+		d.report(DrainEvent{Stage: DrainMaintenance})
+	}
+	for _, id := range d.opts.ServiceIDs {
+		if err := agent.EnableServiceMaintenance(id, d.opts.Reason); err != nil {
+			return err
+		}
+		d.report(DrainEvent{Stage: DrainMaintenance, ServiceID: id})
+	}
+
+	d.report(DrainEvent{Stage: DrainWaiting})
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+
+	if err := d.release(client); err != nil {
+		return err
+	}
+
+	if d.opts.Deregister {
+		for _, id := range d.opts.ServiceIDs {
+			if err := agent.ServiceDeregister(id); err != nil {
+				return err
+			}
+			d.report(DrainEvent{Stage: DrainDeregistered, ServiceID: id})
+		}
+	}
+
+	d.report(DrainEvent{Stage: DrainComplete})
+	return nil
+}
+
+// Undrain takes the node or services out of maintenance mode. Released keys
+// are left for their owners to acquire again.
+func (d *Drainer) Undrain() error {
+	agent := d.client.Agent()
+
+	if len(d.opts.ServiceIDs) == 0 {
+		if err := agent.DisableNodeMaintenance(); err != nil {
+			return err
+		}
+		d.report(DrainEvent{Stage: DrainLeftMaintenance})
+		return nil
+	}
+
+	for _, id := range d.opts.ServiceIDs {
+		if err := agent.DisableServiceMaintenance(id); err != nil {
+			return err
+		}
+		d.report(DrainEvent{Stage: DrainLeftMaintenance, ServiceID: id})
+	}
+	return nil
+}
+
+func (d *Drainer) wait(ctx context.Context) error {
+	if d.opts.QuietPeriod > 0 && !retry.Sleep(ctx, d.opts.QuietPeriod) {
+		return ctx.Err()
+	}
+	if d.opts.Drained == nil {
+		return nil
+	}
+
+	for {
+		drained, err := d.opts.Drained()
+		if err != nil {
+			return err
+		}
+		if drained {
+			return nil
+		}
+		if !retry.Sleep(ctx, d.opts.PollInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *Drainer) release(client Client) error {
+	if len(d.opts.ReleasePrefixes) == 0 {
+		return nil
+	}
+
+	node, err := client.Agent().NodeName()
+	if err != nil {
+		return err
+	}
+	entries, _, err := client.Session().Node(node, nil)
+	if err != nil {
+		return err
+	}
+	sessions := map[string]bool{}
+	for _, entry := range entries {
+		sessions[entry.ID] = true
+	}
+
+	kv := client.KV()
+	for _, prefix := range d.opts.ReleasePrefixes {
+		pairs, _, err := kv.List(prefix, nil)
+		if err != nil {
+			return err
+		}
+		for _, pair := range pairs {
+			if !sessions[pair.Session] {
+				continue
+			}
+			released, _, err := kv.Release(&api.KVPair{Key: pair.Key, Flags: pair.Flags, Value: pair.Value, Session: pair.Session}, nil)
+			if err != nil {
+				return err
+			}
+			if released {
+				d.report(DrainEvent{Stage: DrainReleased, Key: pair.Key})
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Drainer) report(event DrainEvent) {
+	if d.opts.OnProgress != nil {
+		d.opts.OnProgress(event)
+	}
+}
@@ -0,0 +1,219 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drainer", func() {
+	var (
+		client *memconsul.Client
+		agent  consuladapter.Agent
+		opts   consuladapter.DrainOptions
+		drain  []consuladapter.DrainEvent
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		agent = client.Agent()
+		drain = nil
+
+		Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "rep"})).To(Succeed())
+		Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "garden"})).To(Succeed())
+
+		opts = consuladapter.DrainOptions{
+			Reason:       "evacuating",
+			PollInterval: 10 * time.Millisecond,
+			OnProgress:   func(event consuladapter.DrainEvent) { drain = append(drain, event) },
+		}
+	})
+
+	newDrainer := func() *consuladapter.Drainer {
+		drainer, err := consuladapter.NewDrainer(client, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return drainer
+	}
+
+	checks := func() map[string]*api.AgentCheck {
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		return checks
+	}
+
+	It("puts the node into maintenance and takes it out again", func() {
+		drainer := newDrainer()
+		Expect(drainer.Drain(context.Background())).To(Succeed())
+
+		Expect(checks()).To(HaveKey("_node_maintenance"))
+		Expect(checks()["_node_maintenance"].Notes).To(Equal("evacuating"))
+		Expect(drain).To(Equal([]consuladapter.DrainEvent{
+			{Stage: consuladapter.DrainMaintenance},
+			{Stage: consuladapter.DrainWaiting},
+			{Stage: consuladapter.DrainComplete},
+		}))
+
+		Expect(drainer.Undrain()).To(Succeed())
+		Expect(checks()).NotTo(HaveKey("_node_maintenance"))
+		Expect(drain[len(drain)-1]).To(Equal(consuladapter.DrainEvent{Stage: consuladapter.DrainLeftMaintenance}))
+	})
+
+	Context("with services", func() {
+		BeforeEach(func() {
+			opts.ServiceIDs = []string{"rep"}
+		})
+
+		It("puts only those services into maintenance", func() {
+			drainer := newDrainer()
+			Expect(drainer.Drain(context.Background())).To(Succeed())
+
+			Expect(checks()).To(HaveKey("_service_maintenance:rep"))
+			Expect(checks()).NotTo(HaveKey("_service_maintenance:garden"))
+			Expect(checks()).NotTo(HaveKey("_node_maintenance"))
+
+			Expect(drainer.Undrain()).To(Succeed())
+			Expect(checks()).NotTo(HaveKey("_service_maintenance:rep"))
+		})
+
+		It("deregisters them once drained if asked to", func() {
+			opts.Deregister = true
+			Expect(newDrainer().Drain(context.Background())).To(Succeed())
+
+			services, err := agent.Services()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services).NotTo(HaveKey("rep"))
+			Expect(services).To(HaveKey("garden"))
+			Expect(drain).To(ContainElement(consuladapter.DrainEvent{Stage: consuladapter.DrainDeregistered, ServiceID: "rep"}))
+		})
+	})
+
+	It("waits for the quiet period and then for the predicate", func() {
+		var polls atomic.Int32
+		opts.QuietPeriod = 50 * time.Millisecond
+		opts.Drained = func() (bool, error) {
+			return polls.Add(1) >= 3, nil
+		}
+
+		start := time.Now()
+		Expect(newDrainer().Drain(context.Background())).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		Expect(polls.Load()).To(BeEquivalentTo(3))
+	})
+
+	It("returns the error of the predicate", func() {
+		opts.Drained = func() (bool, error) { return false, errors.New("boom") }
+		Expect(newDrainer().Drain(context.Background())).To(MatchError("boom"))
+	})
+
+	It("stops waiting when the context is done", func() {
+		opts.Drained = func() (bool, error) { return false, nil }
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		Expect(newDrainer().Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+		Expect(drain).NotTo(ContainElement(consuladapter.DrainEvent{Stage: consuladapter.DrainComplete}))
+	})
+
+	It("releases the keys held by the node's sessions", func() {
+		local, _, err := client.Session().Create(&api.SessionEntry{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Catalog().Register(&api.CatalogRegistration{Node: "cell-2", Address: "10.0.0.2"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		remote, _, err := client.Session().CreateNoChecks(&api.SessionEntry{Node: "cell-2"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		kv := client.KV()
+		for key, session := range map[string]string{"cells/cell-1": local, "cells/cell-2": remote, "locks/auctioneer": local} {
+			acquired, _, err := kv.Acquire(&api.KVPair{Key: key, Value: []byte(key), Session: session}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		}
+
+		opts.ReleasePrefixes = []string{"cells/"}
+		Expect(newDrainer().Drain(context.Background())).To(Succeed())
+
+		present, err := consuladapter.ListPresent(kv, "")
+		Expect(err).NotTo(HaveOccurred())
+		var keys []string
+		for _, pair := range present {
+			keys = append(keys, pair.Key)
+		}
+		Expect(keys).To(ConsistOf("cells/cell-2", "locks/auctioneer"))
+
+		pair, _, err := kv.Get("cells/cell-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("cells/cell-1")))
+		Expect(drain).To(ContainElement(consuladapter.DrainEvent{Stage: consuladapter.DrainReleased, Key: "cells/cell-1"}))
+	})
+
+	Context("when a presence holds a key", func() {
+		var (
+			presence *consuladapter.Presence
+			process  ifrit.Process
+		)
+
+		BeforeEach(func() {
+			var err error
+			presence, err = consuladapter.NewPresence(client, consuladapter.PresenceOptions{
+				Key:        "cells/cell-1",
+				Value:      []byte("cell-1"),
+				MinBackoff: 10 * time.Millisecond,
+				MaxBackoff: 20 * time.Millisecond,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			process = ginkgomon.Invoke(presence)
+			Eventually(presence.Status).Should(Equal(consuladapter.PresencePresent))
+
+			opts.ReleasePrefixes = []string{"cells/"}
+		})
+
+		AfterEach(func() {
+			ginkgomon.Interrupt(process)
+		})
+
+		It("releases the key, which the running presence acquires again", func() {
+			Expect(newDrainer().Drain(context.Background())).To(Succeed())
+			Expect(drain).To(ContainElement(consuladapter.DrainEvent{Stage: consuladapter.DrainReleased, Key: "cells/cell-1"}))
+
+			Eventually(func() []string {
+				present, err := consuladapter.ListPresent(client.KV(), "cells/")
+				Expect(err).NotTo(HaveOccurred())
+				var keys []string
+				for _, pair := range present {
+					keys = append(keys, pair.Key)
+				}
+				return keys
+			}).Should(ConsistOf("cells/cell-1"))
+		})
+
+		It("has nothing to release once the presence is stopped while draining", func() {
+			opts.Drained = func() (bool, error) {
+				ginkgomon.Interrupt(process)
+				return true, nil
+			}
+			Expect(newDrainer().Drain(context.Background())).To(Succeed())
+			Expect(drain).NotTo(ContainElement(consuladapter.DrainEvent{Stage: consuladapter.DrainReleased, Key: "cells/cell-1"}))
+
+			pair, _, err := client.KV().Get("cells/cell-1", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+	})
+
+	It("requires services to deregister", func() {
+		_, err := consuladapter.NewDrainer(client, consuladapter.DrainOptions{Deregister: true})
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+	})
+})
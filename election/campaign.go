@@ -0,0 +1,222 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	DefaultSessionName = "consuladapter election"
+	DefaultSessionTTL  = "15s"
+	DefaultMinBackoff  = 1 * time.Second
+	DefaultMaxBackoff  = 30 * time.Second
+)
+
+var ErrNoLeader = errors.New("no leader")
+
+type Options struct {
+	// Key is the lock key contended for. Value is stored in it while this
+	// campaign holds it, typically the leader's address.
+	Key   string
+	Value []byte
+
+	// SessionName and SessionTTL configure the session that holds the lock.
+	// LockDelay is the session lock-delay; zero uses Consul's default of 15s.
+	SessionName string
+	SessionTTL  string
+	LockDelay   time.Duration
+
+	// MinBackoff and MaxBackoff bound the jittered exponential backoff used
+	// before campaigning again after a failure or a lost lock.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every failed attempt to campaign.
+	OnError func(err error)
+}
+
+// Campaign is an ifrit.Runner that contends for a lock until it is signalled.
+// While it holds the lock it is the leader; if it loses the lock it backs off
+// and campaigns again. On shutdown it releases the lock and destroys its
+// session.
+type Campaign struct {
+	client consuladapter.Client
+	opts   Options
+
+	isLeader bool
+	changes  chan bool
+	mutex    sync.Mutex
+}
+
+func NewCampaign(client consuladapter.Client, opts Options) (*Campaign, error) {
+	if opts.Key == "" {
+		return nil, errors.New("missing key")
+	}
+	if opts.SessionName == "" {
+		opts.SessionName = DefaultSessionName
+	}
+	if opts.SessionTTL == "" {
+		opts.SessionTTL = DefaultSessionTTL
+	} else if _, err := time.ParseDuration(opts.SessionTTL); err != nil {
+		return nil, fmt.Errorf("invalid SessionTTL: %v", err)
+	}
+	opts.MinBackoff, opts.MaxBackoff = retry.Bounds(opts.MinBackoff, opts.MaxBackoff, DefaultMinBackoff, DefaultMaxBackoff)
+
+	return &Campaign{
+		client:  client,
+		opts:    opts,
+		changes: make(chan bool, 2),
+	}, nil
+}
+
+func (c *Campaign) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	close(ready)
+
+	var failures uint
+	for {
+		lostLock, resign, err := c.campaign(ctx)
+		if err == nil {
+			c.setLeader(true)
+			select {
+			case <-lostLock:
+				resign(false)
+				c.setLeader(false)
+			case <-ctx.Done():
+				resign(true)
+				c.setLeader(false)
+				return nil
+			}
+			failures = 0
+		} else if ctx.Err() != nil {
+			return nil
+		} else if c.opts.OnError != nil {
+			c.opts.OnError(err)
+		}
+
+		if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(c.opts.MinBackoff, c.opts.MaxBackoff, failures))) {
+			return nil
+		}
+		failures++
+	}
+}
+
+// campaign blocks until the lock is acquired or ctx is done. resign destroys
+// the session, first releasing the key if release is set so that the next
+// leader is not held back by the lock-delay.
+func (c *Campaign) campaign(ctx context.Context) (<-chan struct{}, func(release bool), error) {
+	sessions := c.client.Session()
+	sessionID, _, err := sessions.Create(&api.SessionEntry{
+		Name:      c.opts.SessionName,
+		TTL:       c.opts.SessionTTL,
+		LockDelay: c.opts.LockDelay,
+		Behavior:  api.SessionBehaviorRelease,
+	}, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	renewDone := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		sessions.RenewPeriodic(c.opts.SessionTTL, sessionID, nil, renewDone)
+	}()
+
+	stopRenewing := func() {
+		close(renewDone)
+		<-renewed
+	}
+
+	lock, err := c.client.LockOpts(&api.LockOptions{
+		Key:     c.opts.Key,
+		Value:   c.opts.Value,
+		Session: sessionID,
+	})
+	if err != nil {
+		stopRenewing()
+		return nil, nil, err
+	}
+
+	lostLock, err := lock.LockContext(ctx)
+	if err != nil {
+		stopRenewing()
+		return nil, nil, err
+	}
+
+	resign := func(release bool) {
+		if release {
+			lock.Unlock()
+		}
+		stopRenewing()
+	}
+
+	return lostLock, resign, nil
+}
+
+func (c *Campaign) setLeader(leader bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.isLeader == leader {
+		return
+	}
+	c.isLeader = leader
+
+	// Transitions alternate, so two pending ones cancel out. Dropping them
+	// keeps the send from blocking without hiding a change from a receiver.
+	if len(c.changes) == cap(c.changes) {
+		for i := 0; i < cap(c.changes); i++ {
+			select {
+			case <-c.changes:
+			default:
+			}
+		}
+	}
+	c.changes <- leader
+}
+
+func (c *Campaign) IsLeader() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.isLeader
+}
+
+// Changes reports whether this campaign is the leader whenever that changes.
+// A receiver that falls behind may miss a lost-and-regained cycle, but the next
+// value it receives always differs from the last one it read.
+func (c *Campaign) Changes() <-chan bool {
+	return c.changes
+}
+
+// Leader returns the value published by the current leader, or ErrNoLeader if
+// the lock is not held.
+func (c *Campaign) Leader() ([]byte, error) {
+	pair, _, err := c.client.KV().Get(c.opts.Key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil || pair.Session == "" {
+		return nil, ErrNoLeader
+	}
+	return pair.Value, nil
+}
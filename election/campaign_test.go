@@ -0,0 +1,143 @@
+package election_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/election"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Campaign", func() {
+	var (
+		client *memconsul.Client
+		opts   election.Options
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		opts = election.Options{
+			Key:        "leader",
+			Value:      []byte("10.0.0.1:8080"),
+			SessionTTL: "10s",
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+		}
+	})
+
+	newCampaign := func(value string) *election.Campaign {
+		o := opts
+		o.Value = []byte(value)
+		campaign, err := election.NewCampaign(client, o)
+		Expect(err).NotTo(HaveOccurred())
+		return campaign
+	}
+
+	It("requires a key", func() {
+		_, err := election.NewCampaign(client, election.Options{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an invalid session TTL", func() {
+		opts.SessionTTL = "forever"
+		_, err := election.NewCampaign(client, opts)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("becomes the leader and publishes its value", func() {
+		campaign := newCampaign("first")
+		_, err := campaign.Leader()
+		Expect(err).To(Equal(election.ErrNoLeader))
+
+		process := ginkgomon.Invoke(campaign)
+		defer ginkgomon.Interrupt(process)
+
+		Eventually(campaign.Changes()).Should(Receive(BeTrue()))
+		Expect(campaign.IsLeader()).To(BeTrue())
+
+		value, err := campaign.Leader()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal([]byte("first")))
+	})
+
+	It("hands leadership over cleanly on shutdown", func() {
+		first := newCampaign("first")
+		firstProcess := ginkgomon.Invoke(first)
+		Eventually(first.IsLeader).Should(BeTrue())
+
+		second := newCampaign("second")
+		secondProcess := ginkgomon.Invoke(second)
+		defer ginkgomon.Interrupt(secondProcess)
+		Consistently(second.IsLeader).Should(BeFalse())
+
+		ginkgomon.Interrupt(firstProcess)
+		Expect(first.IsLeader()).To(BeFalse())
+
+		Eventually(second.IsLeader).Should(BeTrue())
+		Expect(second.Leader()).To(Equal([]byte("second")))
+
+		sessions, _, err := client.Session().List(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions).To(HaveLen(1))
+	})
+
+	It("campaigns again after losing the lock", func() {
+		campaign := newCampaign("first")
+		process := ginkgomon.Invoke(campaign)
+		defer ginkgomon.Interrupt(process)
+		Eventually(campaign.Changes()).Should(Receive(BeTrue()))
+
+		pair, _, err := client.KV().Get("leader", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Session().Destroy(pair.Session, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(campaign.Changes()).Should(Receive(BeFalse()))
+		Eventually(campaign.Changes()).Should(Receive(BeTrue()))
+
+		newPair, _, err := client.KV().Get("leader", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newPair.Session).NotTo(Equal(pair.Session))
+	})
+
+	It("reports failures and retries", func() {
+		fakeClient, components := fakes.NewFakeClient()
+		components.Session.CreateReturns("", nil, errors.New("boom"))
+
+		errs := make(chan error, 10)
+		opts.OnError = func(err error) { errs <- err }
+		campaign, err := election.NewCampaign(fakeClient, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		process := ifrit.Background(campaign)
+		defer ginkgomon.Interrupt(process)
+
+		Eventually(components.Session.CreateCallCount).Should(BeNumerically(">=", 2))
+		Expect(errs).To(Receive(MatchError(ContainSubstring("boom"))))
+		Expect(campaign.IsLeader()).To(BeFalse())
+	})
+
+	It("stops campaigning when signalled while waiting for the lock", func() {
+		holder := newCampaign("holder")
+		holderProcess := ginkgomon.Invoke(holder)
+		defer ginkgomon.Interrupt(holderProcess)
+		Eventually(holder.IsLeader).Should(BeTrue())
+
+		waiter := newCampaign("waiter")
+		waiterProcess := ginkgomon.Invoke(waiter)
+		Consistently(waiter.IsLeader).Should(BeFalse())
+
+		ginkgomon.Interrupt(waiterProcess)
+		Expect(holder.IsLeader()).To(BeTrue())
+
+		sessions, _, err := client.Session().List(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,335 @@
+package consuladapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+// Encoded values start with a header of encodedMagic, a version byte and a
+// byte of the encodings applied. Flags are left to the caller.
+const (
+	encodedMagic   = "\x00cenc"
+	encodedVersion = 1
+
+	encodingGzip      byte = 1
+	encodingZstd      byte = 2
+	encodingEncrypted byte = 4
+)
+
+var encodedHeaderLen = len(encodedMagic) + 2
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+type EncodingOptions struct {
+	// Compression is applied to values it makes smaller.
+	Compression Compression
+	// Keyring, if set, encrypts every value with AES-GCM, bound to its key.
+	Keyring *Keyring
+}
+
+// EncodedKV compresses and encrypts values on their way into a KV and
+// reverses it on the way out. Values without its header, such as those written
+// before it was introduced, are returned as stored.
+type EncodedKV struct {
+	KV
+	opts EncodingOptions
+}
+
+func NewEncodedKV(kv KV, opts EncodingOptions) *EncodedKV {
+	return &EncodedKV{KV: kv, opts: opts}
+}
+
+func (e *EncodedKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	pair, meta, err := e.KV.Get(key, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.decodePair(pair); err != nil {
+		return nil, nil, err
+	}
+	return pair, meta, nil
+}
+
+func (e *EncodedKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	pairs, meta, err := e.KV.List(prefix, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pair := range pairs {
+		if err := e.decodePair(pair); err != nil {
+			return nil, nil, err
+		}
+	}
+	return pairs, meta, nil
+}
+
+func (e *EncodedKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	encoded, err := e.encodePair(p)
+	if err != nil {
+		return nil, err
+	}
+	return e.KV.Put(encoded, q)
+}
+
+func (e *EncodedKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	encoded, err := e.encodePair(p)
+	if err != nil {
+		return false, nil, err
+	}
+	return e.KV.CAS(encoded, q)
+}
+
+func (e *EncodedKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	encoded, err := e.encodePair(p)
+	if err != nil {
+		return false, nil, err
+	}
+	return e.KV.Acquire(encoded, q)
+}
+
+func (e *EncodedKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	encoded, err := e.encodePair(p)
+	if err != nil {
+		return false, nil, err
+	}
+	return e.KV.Release(encoded, q)
+}
+
+// Txn encodes the values of set, cas, lock and unlock operations and decodes
+// the pairs in the response.
+func (e *EncodedKV) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	ops := make(api.KVTxnOps, len(txn))
+	for i, op := range txn {
+		ops[i] = op
+		switch op.Verb {
+		case string(api.KVSet), api.KVCAS, api.KVLock, api.KVUnlock:
+			value, err := e.encode(op.Key, op.Value)
+			if err != nil {
+				return false, nil, nil, err
+			}
+			encoded := *op
+			encoded.Value = value
+			ops[i] = &encoded
+		}
+	}
+
+	ok, resp, meta, err := e.KV.Txn(ops, q)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	if resp != nil {
+		for _, pair := range resp.Results {
+			if err := e.decodePair(pair); err != nil {
+				return false, nil, nil, err
+			}
+		}
+	}
+	return ok, resp, meta, nil
+}
+
+func (e *EncodedKV) WithContext(ctx context.Context) KV {
+	return NewEncodedKV(e.KV.WithContext(ctx), e.opts)
+}
+
+// Reseal rewrites the values under prefix that are not encoded with the
+// current options, such as values encrypted with a key other than the
+// primary one or written before encoding was enabled. Values changed
+// concurrently are left to their writer. It returns the number rewritten.
+func (e *EncodedKV) Reseal(prefix string) (int, error) {
+	pairs, _, err := e.KV.List(prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resealed := 0
+	for _, pair := range pairs {
+		if e.current(pair) {
+			continue
+		}
+		if err := e.decodePair(pair); err != nil {
+			return resealed, err
+		}
+
+		ok, _, err := e.CAS(pair, nil)
+		if err != nil {
+			return resealed, err
+		}
+		if ok {
+			resealed++
+		}
+	}
+	return resealed, nil
+}
+
+// current reports whether a stored pair is encrypted as the options require.
+// Compression is not checked, as it is only applied when it helps.
+func (e *EncodedKV) current(pair *api.KVPair) bool {
+	encodings, value, _ := parseEncoded(pair.Value)
+	encrypted := encodings&encodingEncrypted != 0
+	if e.opts.Keyring == nil {
+		return !encrypted
+	}
+	if !encrypted {
+		return false
+	}
+	id, ok := sealedKeyID(value)
+	return ok && id == e.opts.Keyring.Primary()
+}
+
+func (e *EncodedKV) encodePair(p *api.KVPair) (*api.KVPair, error) {
+	value, err := e.encode(p.Key, p.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := *p
+	encoded.Value = value
+	return &encoded, nil
+}
+
+func (e *EncodedKV) encode(key string, value []byte) ([]byte, error) {
+	var encodings byte
+	switch e.opts.Compression {
+	case CompressionGzip:
+		if compressed, err := gzipCompress(value); err != nil {
+			return nil, err
+		} else if len(compressed) < len(value) {
+			value, encodings = compressed, encodings|encodingGzip
+		}
+	case CompressionZstd:
+		encoder, _, err := zstdCodec()
+		if err != nil {
+			return nil, err
+		}
+		if compressed := encoder.EncodeAll(value, nil); len(compressed) < len(value) {
+			value, encodings = compressed, encodings|encodingZstd
+		}
+	}
+
+	if e.opts.Keyring != nil {
+		sealed, err := e.opts.Keyring.seal(value, []byte(key))
+		if err != nil {
+			return nil, err
+		}
+		value, encodings = sealed, encodings|encodingEncrypted
+	}
+
+	// Plain values that look encoded get an empty header so they read back
+	// as written.
+	if encodings == 0 && !bytes.HasPrefix(value, []byte(encodedMagic)) {
+		return value, nil
+	}
+
+	encoded := make([]byte, 0, encodedHeaderLen+len(value))
+	encoded = append(encoded, encodedMagic...)
+	encoded = append(encoded, encodedVersion, encodings)
+	return append(encoded, value...), nil
+}
+
+func (e *EncodedKV) decodePair(pair *api.KVPair) error {
+	if pair == nil {
+		return nil
+	}
+
+	encodings, value, ok := parseEncoded(pair.Value)
+	if !ok {
+		return nil
+	}
+	if version := pair.Value[len(encodedMagic)]; version != encodedVersion {
+		return NewDecodeError(pair.Key, fmt.Errorf("unknown encoding version %d", version))
+	}
+
+	if encodings&encodingEncrypted != 0 {
+		if e.opts.Keyring == nil {
+			return NewDecodeError(pair.Key, fmt.Errorf("value is encrypted and no keyring is configured"))
+		}
+		opened, err := e.opts.Keyring.open(value, []byte(pair.Key))
+		if err != nil {
+			return NewDecodeError(pair.Key, err)
+		}
+		value = opened
+	}
+
+	switch {
+	case encodings&encodingGzip != 0:
+		decompressed, err := gzipDecompress(value)
+		if err != nil {
+			return NewDecodeError(pair.Key, err)
+		}
+		value = decompressed
+	case encodings&encodingZstd != 0:
+		_, decoder, err := zstdCodec()
+		if err != nil {
+			return NewDecodeError(pair.Key, err)
+		}
+		decompressed, err := decoder.DecodeAll(value, nil)
+		if err != nil {
+			return NewDecodeError(pair.Key, err)
+		}
+		value = decompressed
+	}
+
+	pair.Value = value
+	return nil
+}
+
+// parseEncoded splits a stored value into its encodings and payload, and
+// reports whether it carries a header.
+func parseEncoded(stored []byte) (byte, []byte, bool) {
+	if len(stored) < encodedHeaderLen || !bytes.HasPrefix(stored, []byte(encodedMagic)) {
+		return 0, stored, false
+	}
+	return stored[encodedHeaderLen-1], stored[encodedHeaderLen:], true
+}
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+func gzipCompress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(value []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
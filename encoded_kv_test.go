@@ -0,0 +1,205 @@
+package consuladapter_test
+
+import (
+	"bytes"
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EncodedKV", func() {
+	var (
+		client  *memconsul.Client
+		raw     consuladapter.KV
+		keyring *consuladapter.Keyring
+		secret  []byte
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		raw = client.KV()
+
+		var err error
+		keyring, err = consuladapter.NewKeyring(1, map[uint32][]byte{1: bytes.Repeat([]byte("k"), 32)})
+		Expect(err).NotTo(HaveOccurred())
+
+		secret = bytes.Repeat([]byte("secret "), 100)
+	})
+
+	roundTrip := func(opts consuladapter.EncodingOptions) *api.KVPair {
+		encoded := consuladapter.NewEncodedKV(raw, opts)
+		_, err := encoded.Put(&api.KVPair{Key: "key", Value: secret, Flags: 5}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := encoded.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(secret))
+		Expect(pair.Flags).To(Equal(uint64(5)))
+
+		stored, _, err := raw.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		return stored
+	}
+
+	It("compresses values with gzip and zstd", func() {
+		for _, compression := range []consuladapter.Compression{consuladapter.CompressionGzip, consuladapter.CompressionZstd} {
+			stored := roundTrip(consuladapter.EncodingOptions{Compression: compression})
+			Expect(len(stored.Value)).To(BeNumerically("<", len(secret)))
+			Expect(stored.Flags).To(Equal(uint64(5)))
+		}
+	})
+
+	It("encrypts values", func() {
+		stored := roundTrip(consuladapter.EncodingOptions{Compression: consuladapter.CompressionZstd, Keyring: keyring})
+		Expect(bytes.Contains(stored.Value, []byte("secret"))).To(BeFalse())
+	})
+
+	It("stores values compression does not shrink as is", func() {
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Compression: consuladapter.CompressionGzip})
+		_, err := encoded.Put(&api.KVPair{Key: "key", Value: []byte("x"), Flags: 5}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stored, _, err := raw.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Value).To(Equal([]byte("x")))
+		Expect(stored.Flags).To(Equal(uint64(5)))
+	})
+
+	It("leaves legacy values readable", func() {
+		_, err := raw.Put(&api.KVPair{Key: "legacy", Value: []byte("plain"), Flags: 0xca << 56}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+		pairs, _, err := encoded.List("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(1))
+		Expect(pairs[0].Value).To(Equal([]byte("plain")))
+		Expect(pairs[0].Flags).To(Equal(uint64(0xca << 56)))
+	})
+
+	It("leaves flags to the caller, so sessions can hold encoded keys", func() {
+		session, _, err := client.Session().Create(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+		acquired, _, err := encoded.Acquire(&api.KVPair{Key: "lock", Value: secret, Flags: api.LockFlagValue, Session: session}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acquired).To(BeTrue())
+
+		pair, _, err := encoded.Get("lock", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(secret))
+		Expect(pair.Flags).To(BeEquivalentTo(api.LockFlagValue))
+	})
+
+	It("round-trips plain values that look encoded", func() {
+		lookalike := append([]byte("\x00cenc\x01\x04"), secret...)
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{})
+		_, err := encoded.Put(&api.KVPair{Key: "key", Value: lookalike}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := encoded.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(lookalike))
+	})
+
+	It("binds ciphertexts to their key", func() {
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+		_, err := encoded.Put(&api.KVPair{Key: "a", Value: secret}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		stored, _, err := raw.Get("a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		stored.Key = "b"
+		_, err = raw.Put(stored, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = encoded.Get("b", nil)
+		var decodeErr consuladapter.DecodeError
+		Expect(errors.As(err, &decodeErr)).To(BeTrue())
+		Expect(decodeErr.Key).To(Equal("b"))
+	})
+
+	It("encodes transaction operations and decodes their results", func() {
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+		results, _, err := consuladapter.NewTxn().Set("key", secret).Get("key").Commit(encoded, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0].Flags).To(BeZero())
+		Expect(results[1].Value).To(Equal(secret))
+
+		stored, _, err := raw.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Value).NotTo(Equal(secret))
+	})
+
+	It("composes with ChunkedKV", func() {
+		encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+		chunked := consuladapter.NewChunkedKV(encoded, 64)
+
+		_, err := chunked.Put(&api.KVPair{Key: "blob", Value: secret}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		pair, _, err := chunked.Get("blob", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal(secret))
+	})
+
+	Describe("key rotation", func() {
+		It("reads values sealed with older keys and reseals them with the primary", func() {
+			old := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+			_, err := old.Put(&api.KVPair{Key: "secrets/a", Value: secret}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = raw.Put(&api.KVPair{Key: "secrets/legacy", Value: []byte("plain")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			rotated, err := consuladapter.NewKeyring(2, map[uint32][]byte{
+				1: bytes.Repeat([]byte("k"), 32),
+				2: bytes.Repeat([]byte("n"), 32),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			encoded := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: rotated})
+
+			pair, _, err := encoded.Get("secrets/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal(secret))
+
+			resealed, err := encoded.Reseal("secrets/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resealed).To(Equal(2))
+
+			resealed, err = encoded.Reseal("secrets/")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resealed).To(BeZero())
+
+			newOnly, err := consuladapter.NewKeyring(2, map[uint32][]byte{2: bytes.Repeat([]byte("n"), 32)})
+			Expect(err).NotTo(HaveOccurred())
+			pairs, _, err := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: newOnly}).List("secrets/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs[0].Value).To(Equal(secret))
+			Expect(pairs[1].Value).To(Equal([]byte("plain")))
+		})
+
+		It("fails to read values sealed with an unknown key", func() {
+			old := consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: keyring})
+			_, err := old.Put(&api.KVPair{Key: "a", Value: secret}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			other, err := consuladapter.NewKeyring(2, map[uint32][]byte{2: bytes.Repeat([]byte("n"), 32)})
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = consuladapter.NewEncodedKV(raw, consuladapter.EncodingOptions{Keyring: other}).Get("a", nil)
+			Expect(err).To(MatchError(ContainSubstring("unknown key ID 1")))
+		})
+	})
+
+	It("validates keyrings", func() {
+		_, err := consuladapter.NewKeyring(1, map[uint32][]byte{2: bytes.Repeat([]byte("k"), 32)})
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+
+		_, err = consuladapter.NewKeyring(1, map[uint32][]byte{1: []byte("short")})
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+	})
+})
@@ -1,6 +1,16 @@
 package consuladapter
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// ErrNotFound matches every KeyNotFoundError and PrefixNotFoundError under
+// errors.Is.
+var ErrNotFound = errors.New("not found")
 
 func NewKeyNotFoundError(key string) error {
 	return KeyNotFoundError(key)
@@ -12,6 +22,10 @@ func (e KeyNotFoundError) Error() string {
 	return fmt.Sprintf("key not found: '%s'", string(e))
 }
 
+func (e KeyNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 func NewPrefixNotFoundError(prefix string) error {
 	return PrefixNotFoundError(prefix)
 }
@@ -21,3 +35,252 @@ type PrefixNotFoundError string
 func (e PrefixNotFoundError) Error() string {
 	return fmt.Sprintf("prefix not found: '%s'", string(e))
 }
+
+func (e PrefixNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func NewInvalidOptionError(option, reason string) error {
+	return InvalidOptionError{Option: option, Reason: reason}
+}
+
+type InvalidOptionError struct {
+	Option string
+	Reason string
+}
+
+func (e InvalidOptionError) Error() string {
+	return fmt.Sprintf("invalid option %s: %s", e.Option, e.Reason)
+}
+
+func NewLockNotHeldError(key string) error {
+	return LockNotHeldError(key)
+}
+
+type LockNotHeldError string
+
+func (e LockNotHeldError) Error() string {
+	return fmt.Sprintf("lock not held: '%s'", string(e))
+}
+
+func (e LockNotHeldError) Unwrap() error {
+	return api.ErrLockNotHeld
+}
+
+func NewLockInUseError(key string) error {
+	return LockInUseError(key)
+}
+
+type LockInUseError string
+
+func (e LockInUseError) Error() string {
+	return fmt.Sprintf("lock in use: '%s'", string(e))
+}
+
+func (e LockInUseError) Unwrap() error {
+	return api.ErrLockInUse
+}
+
+func NewSemaphoreNotHeldError(prefix string) error {
+	return SemaphoreNotHeldError(prefix)
+}
+
+type SemaphoreNotHeldError string
+
+func (e SemaphoreNotHeldError) Error() string {
+	return fmt.Sprintf("semaphore not held: '%s'", string(e))
+}
+
+func (e SemaphoreNotHeldError) Unwrap() error {
+	return api.ErrSemaphoreNotHeld
+}
+
+func NewSemaphoreInUseError(prefix string) error {
+	return SemaphoreInUseError(prefix)
+}
+
+type SemaphoreInUseError string
+
+func (e SemaphoreInUseError) Error() string {
+	return fmt.Sprintf("semaphore in use: '%s'", string(e))
+}
+
+func (e SemaphoreInUseError) Unwrap() error {
+	return api.ErrSemaphoreInUse
+}
+
+func NewConnectionError(err error) error {
+	return ConnectionError{Err: err}
+}
+
+type ConnectionError struct {
+	Err error
+}
+
+func (e ConnectionError) Error() string {
+	return fmt.Sprintf("consul connection failed: %s", e.Err)
+}
+
+func (e ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+func NewACLDeniedError(err error) error {
+	return ACLDeniedError{Err: err}
+}
+
+type ACLDeniedError struct {
+	Err error
+}
+
+func (e ACLDeniedError) Error() string {
+	return fmt.Sprintf("consul acl denied: %s", e.Err)
+}
+
+func (e ACLDeniedError) Unwrap() error {
+	return e.Err
+}
+
+func NewServerError(err error) error {
+	return ServerError{Err: err}
+}
+
+type ServerError struct {
+	Err error
+}
+
+func (e ServerError) Error() string {
+	return fmt.Sprintf("consul server error: %s", e.Err)
+}
+
+func (e ServerError) Unwrap() error {
+	return e.Err
+}
+
+func NewTimeoutError(err error) error {
+	return TimeoutError{Err: err}
+}
+
+// TimeoutError reports a request that did not complete in time, either
+// because of a client timeout or a context deadline.
+type TimeoutError struct {
+	Err error
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("consul request timed out: %s", e.Err)
+}
+
+func (e TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func NewUpdateConflictError(key string) error {
+	return UpdateConflictError(key)
+}
+
+type UpdateConflictError string
+
+func (e UpdateConflictError) Error() string {
+	return fmt.Sprintf("update conflict: '%s'", string(e))
+}
+
+func NewTxnTooLargeError(ops int) error {
+	return TxnTooLargeError{Ops: ops}
+}
+
+// TxnTooLargeError reports a transaction with more operations than Consul
+// accepts. Txn.Split breaks such a transaction into smaller ones.
+type TxnTooLargeError struct {
+	Ops int
+}
+
+func (e TxnTooLargeError) Error() string {
+	return fmt.Sprintf("transaction has %d operations, more than %d", e.Ops, MaxTxnOps)
+}
+
+func NewValueTooLargeError(key string, size int) error {
+	return ValueTooLargeError{Key: key, Size: size, Max: MaxValueSize}
+}
+
+type ValueTooLargeError struct {
+	Key  string
+	Size int
+	Max  int
+}
+
+func (e ValueTooLargeError) Error() string {
+	return fmt.Sprintf("value of '%s' is %d bytes, more than %d", e.Key, e.Size, e.Max)
+}
+
+type TxnFailure struct {
+	Index int
+	Op    *api.KVTxnOp
+	What  string
+}
+
+func NewTxnFailedError(failures []TxnFailure) error {
+	return TxnFailedError{Failures: failures}
+}
+
+// TxnFailedError reports a transaction that was rolled back because some of
+// its operations failed.
+type TxnFailedError struct {
+	Failures []TxnFailure
+}
+
+func (e TxnFailedError) Error() string {
+	reasons := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		if f.Op != nil {
+			reasons = append(reasons, fmt.Sprintf("op %d (%s '%s'): %s", f.Index, f.Op.Verb, f.Op.Key, f.What))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("op %d: %s", f.Index, f.What))
+		}
+	}
+	return fmt.Sprintf("transaction failed: %s", strings.Join(reasons, "; "))
+}
+
+func NewDecodeError(key string, err error) error {
+	return DecodeError{Key: key, Err: err}
+}
+
+// DecodeError reports a stored value that could not be migrated, decrypted or
+// decoded.
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode '%s': %s", e.Key, e.Err)
+}
+
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func NewCorruptValueError(key, reason string) error {
+	return CorruptValueError{Key: key, Reason: reason}
+}
+
+// CorruptValueError reports a chunked value that could not be reassembled.
+type CorruptValueError struct {
+	Key    string
+	Reason string
+}
+
+func (e CorruptValueError) Error() string {
+	return fmt.Sprintf("corrupt value '%s': %s", e.Key, e.Reason)
+}
+
+func NewNoInstancesError(service string) error {
+	return NoInstancesError(service)
+}
+
+// NoInstancesError reports a service without healthy instances to pick from.
+type NoInstancesError string
+
+func (e NoInstancesError) Error() string {
+	return fmt.Sprintf("no healthy instances of service '%s'", string(e))
+}
@@ -0,0 +1,168 @@
+package consuladapter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+)
+
+const (
+	endpointQuarantineInitial = 1 * time.Second
+	endpointQuarantineMax     = 30 * time.Second
+
+	failoverRetryInitial = 100 * time.Millisecond
+	failoverRetryMax     = 2 * time.Second
+)
+
+var errNoEndpoints = errors.New("at least one url is required")
+
+type endpoint struct {
+	scheme   string
+	address  string
+	failures uint
+	retryAt  time.Time
+}
+
+// failoverTransport sends every request to the current healthy endpoint.
+// Endpoints that fail at the connection level are quarantined with an
+// exponential backoff and the request is retried against the next one,
+// unless it is not a GET or HEAD and was already written to the failed one.
+type failoverTransport struct {
+	transport http.RoundTripper
+	endpoints []*endpoint
+	current   int
+
+	mutex sync.Mutex
+}
+
+func newFailoverTransport(transport http.RoundTripper, urls []string) (*failoverTransport, error) {
+	if len(urls) == 0 {
+		return nil, errNoEndpoints
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	endpoints := make([]*endpoint, len(urls))
+	for i, u := range urls {
+		scheme, address, err := Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		endpoints[i] = &endpoint{scheme: scheme, address: address}
+	}
+
+	return &failoverTransport{
+		transport: transport,
+		endpoints: endpoints,
+	}, nil
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < len(t.endpoints); attempt++ {
+		if attempt > 0 {
+			if req.Body != nil && req.GetBody == nil {
+				return nil, lastErr
+			}
+			if !retry.Sleep(req.Context(), retry.Backoff(failoverRetryInitial, failoverRetryMax, uint(attempt-1))) {
+				return nil, lastErr
+			}
+		}
+
+		e := t.pick()
+		outReq, err := rewriteRequest(req, e, attempt > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var wrote atomic.Bool
+		outReq = outReq.WithContext(httptrace.WithClientTrace(outReq.Context(), &httptrace.ClientTrace{
+			WroteRequest: func(httptrace.WroteRequestInfo) { wrote.Store(true) },
+		}))
+
+		resp, err := t.transport.RoundTrip(outReq)
+		if err == nil {
+			t.markHealthy(e)
+			return resp, nil
+		}
+
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+
+		t.markFailed(e)
+		if wrote.Load() && !idempotent(req.Method) {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+func (t *failoverTransport) pick() *endpoint {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(t.endpoints); i++ {
+		index := (t.current + i) % len(t.endpoints)
+		if !t.endpoints[index].retryAt.After(now) {
+			t.current = index
+			return t.endpoints[index]
+		}
+	}
+
+	earliest := 0
+	for i, e := range t.endpoints {
+		if e.retryAt.Before(t.endpoints[earliest].retryAt) {
+			earliest = i
+		}
+	}
+	t.current = earliest
+	return t.endpoints[earliest]
+}
+
+func (t *failoverTransport) markHealthy(e *endpoint) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	e.failures = 0
+	e.retryAt = time.Time{}
+}
+
+func (t *failoverTransport) markFailed(e *endpoint) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	e.retryAt = time.Now().Add(retry.Backoff(endpointQuarantineInitial, endpointQuarantineMax, e.failures))
+	e.failures++
+}
+
+func idempotent(method string) bool {
+	return method == "" || method == http.MethodGet || method == http.MethodHead
+}
+
+func rewriteRequest(req *http.Request, e *endpoint, replayBody bool) (*http.Request, error) {
+	outReq := req.Clone(req.Context())
+	outReq.URL.Scheme = e.scheme
+	outReq.URL.Host = e.address
+	outReq.Host = e.address
+
+	if replayBody && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		outReq.Body = body
+	}
+
+	return outReq, nil
+}
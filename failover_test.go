@@ -0,0 +1,105 @@
+package consuladapter_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Failover", func() {
+	var (
+		healthyServer *httptest.Server
+		deadServer    *httptest.Server
+		healthyHits   int32
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&healthyHits, 0)
+		healthyServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&healthyHits, 1)
+			fmt.Fprint(w, `"127.0.0.1:8300"`)
+		}))
+
+		deadServer = httptest.NewServer(http.NotFoundHandler())
+		deadServer.Close()
+	})
+
+	AfterEach(func() {
+		healthyServer.Close()
+	})
+
+	Describe("NewClientFromUrls", func() {
+		It("errors when no urls are given", func() {
+			_, err := consuladapter.NewClientFromUrls(nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("errors when any url is invalid", func() {
+			_, err := consuladapter.NewClientFromUrls([]string{healthyServer.URL, "sftp://"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails over to the next endpoint on connection errors", func() {
+			client, err := consuladapter.NewClientFromUrls([]string{deadServer.URL, healthyServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			leader, err := client.Status().Leader()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal("127.0.0.1:8300"))
+			Expect(atomic.LoadInt32(&healthyHits)).To(BeEquivalentTo(1))
+		})
+
+		It("keeps routing to the healthy endpoint", func() {
+			client, err := consuladapter.NewClientFromUrls([]string{deadServer.URL, healthyServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 3; i++ {
+				_, err := client.Status().Leader()
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(atomic.LoadInt32(&healthyHits)).To(BeEquivalentTo(3))
+		})
+
+		It("uses the default transport when the http client has none", func() {
+			client, err := consuladapter.NewFromUrls([]string{healthyServer.URL}, consuladapter.WithHTTPClient(&http.Client{}))
+			Expect(err).NotTo(HaveOccurred())
+
+			leader, err := client.Status().Leader()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leader).To(Equal("127.0.0.1:8300"))
+		})
+
+		It("does not replay a write that failed after it was sent", func() {
+			var brokenHits int32
+			brokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&brokenHits, 1)
+				conn, _, err := w.(http.Hijacker).Hijack()
+				Expect(err).NotTo(HaveOccurred())
+				conn.Close()
+			}))
+			defer brokenServer.Close()
+
+			client, err := consuladapter.NewClientFromUrls([]string{brokenServer.URL, healthyServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = client.Session().Create(nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(atomic.LoadInt32(&brokenHits)).To(BeEquivalentTo(1))
+			Expect(atomic.LoadInt32(&healthyHits)).To(BeEquivalentTo(0))
+		})
+
+		It("returns the last error when every endpoint is down", func() {
+			client, err := consuladapter.NewClientFromUrls([]string{deadServer.URL, deadServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.Status().Leader()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -5,6 +5,7 @@ type FakeClientComponents struct {
 	KV      *FakeKV
 	Session *FakeSession
 	Catalog *FakeCatalog
+	Health  *FakeHealth
 }
 
 func NewFakeClient() (*FakeClient, *FakeClientComponents) {
@@ -14,15 +15,25 @@ func NewFakeClient() (*FakeClient, *FakeClientComponents) {
 	kv := &FakeKV{}
 	session := &FakeSession{}
 	catalog := &FakeCatalog{}
+	health := &FakeHealth{}
 
 	client.AgentReturns(agent)
 	client.KVReturns(kv)
 	client.SessionReturns(session)
 	client.CatalogReturns(catalog)
+	client.HealthReturns(health)
+
+	client.WithContextReturns(client)
+	agent.WithContextReturns(agent)
+	kv.WithContextReturns(kv)
+	session.WithContextReturns(session)
+	catalog.WithContextReturns(catalog)
+	health.WithContextReturns(health)
 	return client, &FakeClientComponents{
 		Agent:   agent,
 		KV:      kv,
 		Session: session,
 		Catalog: catalog,
+		Health:  health,
 	}
 }
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -9,6 +10,22 @@ import (
 )
 
 type FakeAgent struct {
+	SelfStub        func() (map[string]map[string]interface{}, error)
+	selfMutex       sync.RWMutex
+	selfArgsForCall []struct{}
+	selfReturns     struct {
+		result1 map[string]map[string]interface{}
+		result2 error
+	}
+	MembersStub        func(wan bool) ([]*api.AgentMember, error)
+	membersMutex       sync.RWMutex
+	membersArgsForCall []struct {
+		wan bool
+	}
+	membersReturns struct {
+		result1 []*api.AgentMember
+		result2 error
+	}
 	ChecksStub        func() (map[string]*api.AgentCheck, error)
 	checksMutex       sync.RWMutex
 	checksArgsForCall []struct{}
@@ -66,6 +83,16 @@ type FakeAgent struct {
 	failTTLReturns struct {
 		result1 error
 	}
+	UpdateTTLStub        func(checkID, output, status string) error
+	updateTTLMutex       sync.RWMutex
+	updateTTLArgsForCall []struct {
+		checkID string
+		output  string
+		status  string
+	}
+	updateTTLReturns struct {
+		result1 error
+	}
 	NodeNameStub        func() (string, error)
 	nodeNameMutex       sync.RWMutex
 	nodeNameArgsForCall []struct{}
@@ -73,6 +100,14 @@ type FakeAgent struct {
 		result1 string
 		result2 error
 	}
+	CheckRegisterStub        func(check *api.AgentCheckRegistration) error
+	checkRegisterMutex       sync.RWMutex
+	checkRegisterArgsForCall []struct {
+		check *api.AgentCheckRegistration
+	}
+	checkRegisterReturns struct {
+		result1 error
+	}
 	CheckDeregisterStub        func(checkID string) error
 	checkDeregisterMutex       sync.RWMutex
 	checkDeregisterArgsForCall []struct {
@@ -81,6 +116,120 @@ type FakeAgent struct {
 	checkDeregisterReturns struct {
 		result1 error
 	}
+	JoinStub        func(addr string, wan bool) error
+	joinMutex       sync.RWMutex
+	joinArgsForCall []struct {
+		addr string
+		wan  bool
+	}
+	joinReturns struct {
+		result1 error
+	}
+	ForceLeaveStub        func(node string) error
+	forceLeaveMutex       sync.RWMutex
+	forceLeaveArgsForCall []struct {
+		node string
+	}
+	forceLeaveReturns struct {
+		result1 error
+	}
+	EnableServiceMaintenanceStub        func(serviceID, reason string) error
+	enableServiceMaintenanceMutex       sync.RWMutex
+	enableServiceMaintenanceArgsForCall []struct {
+		serviceID string
+		reason    string
+	}
+	enableServiceMaintenanceReturns struct {
+		result1 error
+	}
+	DisableServiceMaintenanceStub        func(serviceID string) error
+	disableServiceMaintenanceMutex       sync.RWMutex
+	disableServiceMaintenanceArgsForCall []struct {
+		serviceID string
+	}
+	disableServiceMaintenanceReturns struct {
+		result1 error
+	}
+	EnableNodeMaintenanceStub        func(reason string) error
+	enableNodeMaintenanceMutex       sync.RWMutex
+	enableNodeMaintenanceArgsForCall []struct {
+		reason string
+	}
+	enableNodeMaintenanceReturns struct {
+		result1 error
+	}
+	DisableNodeMaintenanceStub        func() error
+	disableNodeMaintenanceMutex       sync.RWMutex
+	disableNodeMaintenanceArgsForCall []struct{}
+	disableNodeMaintenanceReturns     struct {
+		result1 error
+	}
+	WithContextStub        func(ctx context.Context) consuladapter.Agent
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Agent
+	}
+}
+
+func (fake *FakeAgent) Self() (map[string]map[string]interface{}, error) {
+	fake.selfMutex.Lock()
+	fake.selfArgsForCall = append(fake.selfArgsForCall, struct{}{})
+	fake.selfMutex.Unlock()
+	if fake.SelfStub != nil {
+		return fake.SelfStub()
+	} else {
+		return fake.selfReturns.result1, fake.selfReturns.result2
+	}
+}
+
+func (fake *FakeAgent) SelfCallCount() int {
+	fake.selfMutex.RLock()
+	defer fake.selfMutex.RUnlock()
+	return len(fake.selfArgsForCall)
+}
+
+func (fake *FakeAgent) SelfReturns(result1 map[string]map[string]interface{}, result2 error) {
+	fake.SelfStub = nil
+	fake.selfReturns = struct {
+		result1 map[string]map[string]interface{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgent) Members(wan bool) ([]*api.AgentMember, error) {
+	fake.membersMutex.Lock()
+	fake.membersArgsForCall = append(fake.membersArgsForCall, struct {
+		wan bool
+	}{wan})
+	fake.membersMutex.Unlock()
+	if fake.MembersStub != nil {
+		return fake.MembersStub(wan)
+	} else {
+		return fake.membersReturns.result1, fake.membersReturns.result2
+	}
+}
+
+func (fake *FakeAgent) MembersCallCount() int {
+	fake.membersMutex.RLock()
+	defer fake.membersMutex.RUnlock()
+	return len(fake.membersArgsForCall)
+}
+
+func (fake *FakeAgent) MembersArgsForCall(i int) bool {
+	fake.membersMutex.RLock()
+	defer fake.membersMutex.RUnlock()
+	return fake.membersArgsForCall[i].wan
+}
+
+func (fake *FakeAgent) MembersReturns(result1 []*api.AgentMember, result2 error) {
+	fake.MembersStub = nil
+	fake.membersReturns = struct {
+		result1 []*api.AgentMember
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeAgent) Checks() (map[string]*api.AgentCheck, error) {
@@ -296,6 +445,40 @@ func (fake *FakeAgent) FailTTLReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAgent) UpdateTTL(checkID string, output string, status string) error {
+	fake.updateTTLMutex.Lock()
+	fake.updateTTLArgsForCall = append(fake.updateTTLArgsForCall, struct {
+		checkID string
+		output  string
+		status  string
+	}{checkID, output, status})
+	fake.updateTTLMutex.Unlock()
+	if fake.UpdateTTLStub != nil {
+		return fake.UpdateTTLStub(checkID, output, status)
+	} else {
+		return fake.updateTTLReturns.result1
+	}
+}
+
+func (fake *FakeAgent) UpdateTTLCallCount() int {
+	fake.updateTTLMutex.RLock()
+	defer fake.updateTTLMutex.RUnlock()
+	return len(fake.updateTTLArgsForCall)
+}
+
+func (fake *FakeAgent) UpdateTTLArgsForCall(i int) (string, string, string) {
+	fake.updateTTLMutex.RLock()
+	defer fake.updateTTLMutex.RUnlock()
+	return fake.updateTTLArgsForCall[i].checkID, fake.updateTTLArgsForCall[i].output, fake.updateTTLArgsForCall[i].status
+}
+
+func (fake *FakeAgent) UpdateTTLReturns(result1 error) {
+	fake.UpdateTTLStub = nil
+	fake.updateTTLReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeAgent) NodeName() (string, error) {
 	fake.nodeNameMutex.Lock()
 	fake.nodeNameArgsForCall = append(fake.nodeNameArgsForCall, struct{}{})
@@ -321,6 +504,38 @@ func (fake *FakeAgent) NodeNameReturns(result1 string, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeAgent) CheckRegister(check *api.AgentCheckRegistration) error {
+	fake.checkRegisterMutex.Lock()
+	fake.checkRegisterArgsForCall = append(fake.checkRegisterArgsForCall, struct {
+		check *api.AgentCheckRegistration
+	}{check})
+	fake.checkRegisterMutex.Unlock()
+	if fake.CheckRegisterStub != nil {
+		return fake.CheckRegisterStub(check)
+	} else {
+		return fake.checkRegisterReturns.result1
+	}
+}
+
+func (fake *FakeAgent) CheckRegisterCallCount() int {
+	fake.checkRegisterMutex.RLock()
+	defer fake.checkRegisterMutex.RUnlock()
+	return len(fake.checkRegisterArgsForCall)
+}
+
+func (fake *FakeAgent) CheckRegisterArgsForCall(i int) *api.AgentCheckRegistration {
+	fake.checkRegisterMutex.RLock()
+	defer fake.checkRegisterMutex.RUnlock()
+	return fake.checkRegisterArgsForCall[i].check
+}
+
+func (fake *FakeAgent) CheckRegisterReturns(result1 error) {
+	fake.CheckRegisterStub = nil
+	fake.checkRegisterReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeAgent) CheckDeregister(checkID string) error {
 	fake.checkDeregisterMutex.Lock()
 	fake.checkDeregisterArgsForCall = append(fake.checkDeregisterArgsForCall, struct {
@@ -353,4 +568,222 @@ func (fake *FakeAgent) CheckDeregisterReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAgent) Join(addr string, wan bool) error {
+	fake.joinMutex.Lock()
+	fake.joinArgsForCall = append(fake.joinArgsForCall, struct {
+		addr string
+		wan  bool
+	}{addr, wan})
+	fake.joinMutex.Unlock()
+	if fake.JoinStub != nil {
+		return fake.JoinStub(addr, wan)
+	} else {
+		return fake.joinReturns.result1
+	}
+}
+
+func (fake *FakeAgent) JoinCallCount() int {
+	fake.joinMutex.RLock()
+	defer fake.joinMutex.RUnlock()
+	return len(fake.joinArgsForCall)
+}
+
+func (fake *FakeAgent) JoinArgsForCall(i int) (string, bool) {
+	fake.joinMutex.RLock()
+	defer fake.joinMutex.RUnlock()
+	return fake.joinArgsForCall[i].addr, fake.joinArgsForCall[i].wan
+}
+
+func (fake *FakeAgent) JoinReturns(result1 error) {
+	fake.JoinStub = nil
+	fake.joinReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) ForceLeave(node string) error {
+	fake.forceLeaveMutex.Lock()
+	fake.forceLeaveArgsForCall = append(fake.forceLeaveArgsForCall, struct {
+		node string
+	}{node})
+	fake.forceLeaveMutex.Unlock()
+	if fake.ForceLeaveStub != nil {
+		return fake.ForceLeaveStub(node)
+	} else {
+		return fake.forceLeaveReturns.result1
+	}
+}
+
+func (fake *FakeAgent) ForceLeaveCallCount() int {
+	fake.forceLeaveMutex.RLock()
+	defer fake.forceLeaveMutex.RUnlock()
+	return len(fake.forceLeaveArgsForCall)
+}
+
+func (fake *FakeAgent) ForceLeaveArgsForCall(i int) string {
+	fake.forceLeaveMutex.RLock()
+	defer fake.forceLeaveMutex.RUnlock()
+	return fake.forceLeaveArgsForCall[i].node
+}
+
+func (fake *FakeAgent) ForceLeaveReturns(result1 error) {
+	fake.ForceLeaveStub = nil
+	fake.forceLeaveReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) EnableServiceMaintenance(serviceID string, reason string) error {
+	fake.enableServiceMaintenanceMutex.Lock()
+	fake.enableServiceMaintenanceArgsForCall = append(fake.enableServiceMaintenanceArgsForCall, struct {
+		serviceID string
+		reason    string
+	}{serviceID, reason})
+	fake.enableServiceMaintenanceMutex.Unlock()
+	if fake.EnableServiceMaintenanceStub != nil {
+		return fake.EnableServiceMaintenanceStub(serviceID, reason)
+	} else {
+		return fake.enableServiceMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceCallCount() int {
+	fake.enableServiceMaintenanceMutex.RLock()
+	defer fake.enableServiceMaintenanceMutex.RUnlock()
+	return len(fake.enableServiceMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceArgsForCall(i int) (string, string) {
+	fake.enableServiceMaintenanceMutex.RLock()
+	defer fake.enableServiceMaintenanceMutex.RUnlock()
+	return fake.enableServiceMaintenanceArgsForCall[i].serviceID, fake.enableServiceMaintenanceArgsForCall[i].reason
+}
+
+func (fake *FakeAgent) EnableServiceMaintenanceReturns(result1 error) {
+	fake.EnableServiceMaintenanceStub = nil
+	fake.enableServiceMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) DisableServiceMaintenance(serviceID string) error {
+	fake.disableServiceMaintenanceMutex.Lock()
+	fake.disableServiceMaintenanceArgsForCall = append(fake.disableServiceMaintenanceArgsForCall, struct {
+		serviceID string
+	}{serviceID})
+	fake.disableServiceMaintenanceMutex.Unlock()
+	if fake.DisableServiceMaintenanceStub != nil {
+		return fake.DisableServiceMaintenanceStub(serviceID)
+	} else {
+		return fake.disableServiceMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceCallCount() int {
+	fake.disableServiceMaintenanceMutex.RLock()
+	defer fake.disableServiceMaintenanceMutex.RUnlock()
+	return len(fake.disableServiceMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceArgsForCall(i int) string {
+	fake.disableServiceMaintenanceMutex.RLock()
+	defer fake.disableServiceMaintenanceMutex.RUnlock()
+	return fake.disableServiceMaintenanceArgsForCall[i].serviceID
+}
+
+func (fake *FakeAgent) DisableServiceMaintenanceReturns(result1 error) {
+	fake.DisableServiceMaintenanceStub = nil
+	fake.disableServiceMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) EnableNodeMaintenance(reason string) error {
+	fake.enableNodeMaintenanceMutex.Lock()
+	fake.enableNodeMaintenanceArgsForCall = append(fake.enableNodeMaintenanceArgsForCall, struct {
+		reason string
+	}{reason})
+	fake.enableNodeMaintenanceMutex.Unlock()
+	if fake.EnableNodeMaintenanceStub != nil {
+		return fake.EnableNodeMaintenanceStub(reason)
+	} else {
+		return fake.enableNodeMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) EnableNodeMaintenanceCallCount() int {
+	fake.enableNodeMaintenanceMutex.RLock()
+	defer fake.enableNodeMaintenanceMutex.RUnlock()
+	return len(fake.enableNodeMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) EnableNodeMaintenanceArgsForCall(i int) string {
+	fake.enableNodeMaintenanceMutex.RLock()
+	defer fake.enableNodeMaintenanceMutex.RUnlock()
+	return fake.enableNodeMaintenanceArgsForCall[i].reason
+}
+
+func (fake *FakeAgent) EnableNodeMaintenanceReturns(result1 error) {
+	fake.EnableNodeMaintenanceStub = nil
+	fake.enableNodeMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) DisableNodeMaintenance() error {
+	fake.disableNodeMaintenanceMutex.Lock()
+	fake.disableNodeMaintenanceArgsForCall = append(fake.disableNodeMaintenanceArgsForCall, struct{}{})
+	fake.disableNodeMaintenanceMutex.Unlock()
+	if fake.DisableNodeMaintenanceStub != nil {
+		return fake.DisableNodeMaintenanceStub()
+	} else {
+		return fake.disableNodeMaintenanceReturns.result1
+	}
+}
+
+func (fake *FakeAgent) DisableNodeMaintenanceCallCount() int {
+	fake.disableNodeMaintenanceMutex.RLock()
+	defer fake.disableNodeMaintenanceMutex.RUnlock()
+	return len(fake.disableNodeMaintenanceArgsForCall)
+}
+
+func (fake *FakeAgent) DisableNodeMaintenanceReturns(result1 error) {
+	fake.DisableNodeMaintenanceStub = nil
+	fake.disableNodeMaintenanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgent) WithContext(ctx context.Context) consuladapter.Agent {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeAgent) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeAgent) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeAgent) WithContextReturns(result1 consuladapter.Agent) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Agent
+	}{result1}
+}
+
 var _ consuladapter.Agent = new(FakeAgent)
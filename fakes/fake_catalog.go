@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -19,6 +20,74 @@ type FakeCatalog struct {
 		result2 *api.QueryMeta
 		result3 error
 	}
+	NodeStub        func(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error)
+	nodeMutex       sync.RWMutex
+	nodeArgsForCall []struct {
+		node string
+		q    *api.QueryOptions
+	}
+	nodeReturns struct {
+		result1 *api.CatalogNode
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ServicesStub        func(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error)
+	servicesMutex       sync.RWMutex
+	servicesArgsForCall []struct {
+		q *api.QueryOptions
+	}
+	servicesReturns struct {
+		result1 map[string][]string
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ServiceStub        func(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error)
+	serviceMutex       sync.RWMutex
+	serviceArgsForCall []struct {
+		service string
+		tag     string
+		q       *api.QueryOptions
+	}
+	serviceReturns struct {
+		result1 []*api.CatalogService
+		result2 *api.QueryMeta
+		result3 error
+	}
+	DatacentersStub        func() ([]string, error)
+	datacentersMutex       sync.RWMutex
+	datacentersArgsForCall []struct{}
+	datacentersReturns     struct {
+		result1 []string
+		result2 error
+	}
+	RegisterStub        func(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error)
+	registerMutex       sync.RWMutex
+	registerArgsForCall []struct {
+		reg *api.CatalogRegistration
+		q   *api.WriteOptions
+	}
+	registerReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	DeregisterStub        func(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error)
+	deregisterMutex       sync.RWMutex
+	deregisterArgsForCall []struct {
+		dereg *api.CatalogDeregistration
+		q     *api.WriteOptions
+	}
+	deregisterReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	WithContextStub        func(ctx context.Context) consuladapter.Catalog
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Catalog
+	}
 }
 
 func (fake *FakeCatalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
@@ -55,4 +124,234 @@ func (fake *FakeCatalog) NodesReturns(result1 []*api.Node, result2 *api.QueryMet
 	}{result1, result2, result3}
 }
 
+func (fake *FakeCatalog) Node(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error) {
+	fake.nodeMutex.Lock()
+	fake.nodeArgsForCall = append(fake.nodeArgsForCall, struct {
+		node string
+		q    *api.QueryOptions
+	}{node, q})
+	fake.nodeMutex.Unlock()
+	if fake.NodeStub != nil {
+		return fake.NodeStub(node, q)
+	} else {
+		return fake.nodeReturns.result1, fake.nodeReturns.result2, fake.nodeReturns.result3
+	}
+}
+
+func (fake *FakeCatalog) NodeCallCount() int {
+	fake.nodeMutex.RLock()
+	defer fake.nodeMutex.RUnlock()
+	return len(fake.nodeArgsForCall)
+}
+
+func (fake *FakeCatalog) NodeArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.nodeMutex.RLock()
+	defer fake.nodeMutex.RUnlock()
+	return fake.nodeArgsForCall[i].node, fake.nodeArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) NodeReturns(result1 *api.CatalogNode, result2 *api.QueryMeta, result3 error) {
+	fake.NodeStub = nil
+	fake.nodeReturns = struct {
+		result1 *api.CatalogNode
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeCatalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	fake.servicesMutex.Lock()
+	fake.servicesArgsForCall = append(fake.servicesArgsForCall, struct {
+		q *api.QueryOptions
+	}{q})
+	fake.servicesMutex.Unlock()
+	if fake.ServicesStub != nil {
+		return fake.ServicesStub(q)
+	} else {
+		return fake.servicesReturns.result1, fake.servicesReturns.result2, fake.servicesReturns.result3
+	}
+}
+
+func (fake *FakeCatalog) ServicesCallCount() int {
+	fake.servicesMutex.RLock()
+	defer fake.servicesMutex.RUnlock()
+	return len(fake.servicesArgsForCall)
+}
+
+func (fake *FakeCatalog) ServicesArgsForCall(i int) *api.QueryOptions {
+	fake.servicesMutex.RLock()
+	defer fake.servicesMutex.RUnlock()
+	return fake.servicesArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) ServicesReturns(result1 map[string][]string, result2 *api.QueryMeta, result3 error) {
+	fake.ServicesStub = nil
+	fake.servicesReturns = struct {
+		result1 map[string][]string
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeCatalog) Service(service string, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	fake.serviceMutex.Lock()
+	fake.serviceArgsForCall = append(fake.serviceArgsForCall, struct {
+		service string
+		tag     string
+		q       *api.QueryOptions
+	}{service, tag, q})
+	fake.serviceMutex.Unlock()
+	if fake.ServiceStub != nil {
+		return fake.ServiceStub(service, tag, q)
+	} else {
+		return fake.serviceReturns.result1, fake.serviceReturns.result2, fake.serviceReturns.result3
+	}
+}
+
+func (fake *FakeCatalog) ServiceCallCount() int {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return len(fake.serviceArgsForCall)
+}
+
+func (fake *FakeCatalog) ServiceArgsForCall(i int) (string, string, *api.QueryOptions) {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return fake.serviceArgsForCall[i].service, fake.serviceArgsForCall[i].tag, fake.serviceArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) ServiceReturns(result1 []*api.CatalogService, result2 *api.QueryMeta, result3 error) {
+	fake.ServiceStub = nil
+	fake.serviceReturns = struct {
+		result1 []*api.CatalogService
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeCatalog) Datacenters() ([]string, error) {
+	fake.datacentersMutex.Lock()
+	fake.datacentersArgsForCall = append(fake.datacentersArgsForCall, struct{}{})
+	fake.datacentersMutex.Unlock()
+	if fake.DatacentersStub != nil {
+		return fake.DatacentersStub()
+	} else {
+		return fake.datacentersReturns.result1, fake.datacentersReturns.result2
+	}
+}
+
+func (fake *FakeCatalog) DatacentersCallCount() int {
+	fake.datacentersMutex.RLock()
+	defer fake.datacentersMutex.RUnlock()
+	return len(fake.datacentersArgsForCall)
+}
+
+func (fake *FakeCatalog) DatacentersReturns(result1 []string, result2 error) {
+	fake.DatacentersStub = nil
+	fake.datacentersReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalog) Register(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.registerMutex.Lock()
+	fake.registerArgsForCall = append(fake.registerArgsForCall, struct {
+		reg *api.CatalogRegistration
+		q   *api.WriteOptions
+	}{reg, q})
+	fake.registerMutex.Unlock()
+	if fake.RegisterStub != nil {
+		return fake.RegisterStub(reg, q)
+	} else {
+		return fake.registerReturns.result1, fake.registerReturns.result2
+	}
+}
+
+func (fake *FakeCatalog) RegisterCallCount() int {
+	fake.registerMutex.RLock()
+	defer fake.registerMutex.RUnlock()
+	return len(fake.registerArgsForCall)
+}
+
+func (fake *FakeCatalog) RegisterArgsForCall(i int) (*api.CatalogRegistration, *api.WriteOptions) {
+	fake.registerMutex.RLock()
+	defer fake.registerMutex.RUnlock()
+	return fake.registerArgsForCall[i].reg, fake.registerArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) RegisterReturns(result1 *api.WriteMeta, result2 error) {
+	fake.RegisterStub = nil
+	fake.registerReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalog) Deregister(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.deregisterMutex.Lock()
+	fake.deregisterArgsForCall = append(fake.deregisterArgsForCall, struct {
+		dereg *api.CatalogDeregistration
+		q     *api.WriteOptions
+	}{dereg, q})
+	fake.deregisterMutex.Unlock()
+	if fake.DeregisterStub != nil {
+		return fake.DeregisterStub(dereg, q)
+	} else {
+		return fake.deregisterReturns.result1, fake.deregisterReturns.result2
+	}
+}
+
+func (fake *FakeCatalog) DeregisterCallCount() int {
+	fake.deregisterMutex.RLock()
+	defer fake.deregisterMutex.RUnlock()
+	return len(fake.deregisterArgsForCall)
+}
+
+func (fake *FakeCatalog) DeregisterArgsForCall(i int) (*api.CatalogDeregistration, *api.WriteOptions) {
+	fake.deregisterMutex.RLock()
+	defer fake.deregisterMutex.RUnlock()
+	return fake.deregisterArgsForCall[i].dereg, fake.deregisterArgsForCall[i].q
+}
+
+func (fake *FakeCatalog) DeregisterReturns(result1 *api.WriteMeta, result2 error) {
+	fake.DeregisterStub = nil
+	fake.deregisterReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCatalog) WithContext(ctx context.Context) consuladapter.Catalog {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeCatalog) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeCatalog) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeCatalog) WithContextReturns(result1 consuladapter.Catalog) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Catalog
+	}{result1}
+}
+
 var _ consuladapter.Catalog = new(FakeCatalog)
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -27,6 +28,12 @@ type FakeClient struct {
 	catalogReturns     struct {
 		result1 consuladapter.Catalog
 	}
+	HealthStub        func() consuladapter.Health
+	healthMutex       sync.RWMutex
+	healthArgsForCall []struct{}
+	healthReturns     struct {
+		result1 consuladapter.Health
+	}
 	KVStub        func() consuladapter.KV
 	kVMutex       sync.RWMutex
 	kVArgsForCall []struct{}
@@ -48,6 +55,23 @@ type FakeClient struct {
 		result1 consuladapter.Lock
 		result2 error
 	}
+	SemaphoreOptsStub        func(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error)
+	semaphoreOptsMutex       sync.RWMutex
+	semaphoreOptsArgsForCall []struct {
+		opts *api.SemaphoreOptions
+	}
+	semaphoreOptsReturns struct {
+		result1 consuladapter.Semaphore
+		result2 error
+	}
+	WithContextStub        func(ctx context.Context) consuladapter.Client
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Client
+	}
 }
 
 func (fake *FakeClient) Agent() consuladapter.Agent {
@@ -122,6 +146,30 @@ func (fake *FakeClient) CatalogReturns(result1 consuladapter.Catalog) {
 	}{result1}
 }
 
+func (fake *FakeClient) Health() consuladapter.Health {
+	fake.healthMutex.Lock()
+	fake.healthArgsForCall = append(fake.healthArgsForCall, struct{}{})
+	fake.healthMutex.Unlock()
+	if fake.HealthStub != nil {
+		return fake.HealthStub()
+	} else {
+		return fake.healthReturns.result1
+	}
+}
+
+func (fake *FakeClient) HealthCallCount() int {
+	fake.healthMutex.RLock()
+	defer fake.healthMutex.RUnlock()
+	return len(fake.healthArgsForCall)
+}
+
+func (fake *FakeClient) HealthReturns(result1 consuladapter.Health) {
+	fake.HealthStub = nil
+	fake.healthReturns = struct {
+		result1 consuladapter.Health
+	}{result1}
+}
+
 func (fake *FakeClient) KV() consuladapter.KV {
 	fake.kVMutex.Lock()
 	fake.kVArgsForCall = append(fake.kVArgsForCall, struct{}{})
@@ -203,4 +251,69 @@ func (fake *FakeClient) LockOptsReturns(result1 consuladapter.Lock, result2 erro
 	}{result1, result2}
 }
 
+func (fake *FakeClient) SemaphoreOpts(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error) {
+	fake.semaphoreOptsMutex.Lock()
+	fake.semaphoreOptsArgsForCall = append(fake.semaphoreOptsArgsForCall, struct {
+		opts *api.SemaphoreOptions
+	}{opts})
+	fake.semaphoreOptsMutex.Unlock()
+	if fake.SemaphoreOptsStub != nil {
+		return fake.SemaphoreOptsStub(opts)
+	} else {
+		return fake.semaphoreOptsReturns.result1, fake.semaphoreOptsReturns.result2
+	}
+}
+
+func (fake *FakeClient) SemaphoreOptsCallCount() int {
+	fake.semaphoreOptsMutex.RLock()
+	defer fake.semaphoreOptsMutex.RUnlock()
+	return len(fake.semaphoreOptsArgsForCall)
+}
+
+func (fake *FakeClient) SemaphoreOptsArgsForCall(i int) *api.SemaphoreOptions {
+	fake.semaphoreOptsMutex.RLock()
+	defer fake.semaphoreOptsMutex.RUnlock()
+	return fake.semaphoreOptsArgsForCall[i].opts
+}
+
+func (fake *FakeClient) SemaphoreOptsReturns(result1 consuladapter.Semaphore, result2 error) {
+	fake.SemaphoreOptsStub = nil
+	fake.semaphoreOptsReturns = struct {
+		result1 consuladapter.Semaphore
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) WithContext(ctx context.Context) consuladapter.Client {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeClient) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeClient) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeClient) WithContextReturns(result1 consuladapter.Client) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Client
+	}{result1}
+}
+
 var _ consuladapter.Client = new(FakeClient)
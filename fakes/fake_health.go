@@ -0,0 +1,243 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type FakeHealth struct {
+	NodeStub        func(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+	nodeMutex       sync.RWMutex
+	nodeArgsForCall []struct {
+		node string
+		q    *api.QueryOptions
+	}
+	nodeReturns struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ChecksStub        func(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+	checksMutex       sync.RWMutex
+	checksArgsForCall []struct {
+		service string
+		q       *api.QueryOptions
+	}
+	checksReturns struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}
+	ServiceStub        func(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+	serviceMutex       sync.RWMutex
+	serviceArgsForCall []struct {
+		service     string
+		tag         string
+		passingOnly bool
+		q           *api.QueryOptions
+	}
+	serviceReturns struct {
+		result1 []*api.ServiceEntry
+		result2 *api.QueryMeta
+		result3 error
+	}
+	StateStub        func(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+	stateMutex       sync.RWMutex
+	stateArgsForCall []struct {
+		state string
+		q     *api.QueryOptions
+	}
+	stateReturns struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}
+	WithContextStub        func(ctx context.Context) consuladapter.Health
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Health
+	}
+}
+
+func (fake *FakeHealth) Node(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	fake.nodeMutex.Lock()
+	fake.nodeArgsForCall = append(fake.nodeArgsForCall, struct {
+		node string
+		q    *api.QueryOptions
+	}{node, q})
+	fake.nodeMutex.Unlock()
+	if fake.NodeStub != nil {
+		return fake.NodeStub(node, q)
+	} else {
+		return fake.nodeReturns.result1, fake.nodeReturns.result2, fake.nodeReturns.result3
+	}
+}
+
+func (fake *FakeHealth) NodeCallCount() int {
+	fake.nodeMutex.RLock()
+	defer fake.nodeMutex.RUnlock()
+	return len(fake.nodeArgsForCall)
+}
+
+func (fake *FakeHealth) NodeArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.nodeMutex.RLock()
+	defer fake.nodeMutex.RUnlock()
+	return fake.nodeArgsForCall[i].node, fake.nodeArgsForCall[i].q
+}
+
+func (fake *FakeHealth) NodeReturns(result1 []*api.HealthCheck, result2 *api.QueryMeta, result3 error) {
+	fake.NodeStub = nil
+	fake.nodeReturns = struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHealth) Checks(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	fake.checksMutex.Lock()
+	fake.checksArgsForCall = append(fake.checksArgsForCall, struct {
+		service string
+		q       *api.QueryOptions
+	}{service, q})
+	fake.checksMutex.Unlock()
+	if fake.ChecksStub != nil {
+		return fake.ChecksStub(service, q)
+	} else {
+		return fake.checksReturns.result1, fake.checksReturns.result2, fake.checksReturns.result3
+	}
+}
+
+func (fake *FakeHealth) ChecksCallCount() int {
+	fake.checksMutex.RLock()
+	defer fake.checksMutex.RUnlock()
+	return len(fake.checksArgsForCall)
+}
+
+func (fake *FakeHealth) ChecksArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.checksMutex.RLock()
+	defer fake.checksMutex.RUnlock()
+	return fake.checksArgsForCall[i].service, fake.checksArgsForCall[i].q
+}
+
+func (fake *FakeHealth) ChecksReturns(result1 []*api.HealthCheck, result2 *api.QueryMeta, result3 error) {
+	fake.ChecksStub = nil
+	fake.checksReturns = struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHealth) Service(service string, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	fake.serviceMutex.Lock()
+	fake.serviceArgsForCall = append(fake.serviceArgsForCall, struct {
+		service     string
+		tag         string
+		passingOnly bool
+		q           *api.QueryOptions
+	}{service, tag, passingOnly, q})
+	fake.serviceMutex.Unlock()
+	if fake.ServiceStub != nil {
+		return fake.ServiceStub(service, tag, passingOnly, q)
+	} else {
+		return fake.serviceReturns.result1, fake.serviceReturns.result2, fake.serviceReturns.result3
+	}
+}
+
+func (fake *FakeHealth) ServiceCallCount() int {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return len(fake.serviceArgsForCall)
+}
+
+func (fake *FakeHealth) ServiceArgsForCall(i int) (string, string, bool, *api.QueryOptions) {
+	fake.serviceMutex.RLock()
+	defer fake.serviceMutex.RUnlock()
+	return fake.serviceArgsForCall[i].service, fake.serviceArgsForCall[i].tag, fake.serviceArgsForCall[i].passingOnly, fake.serviceArgsForCall[i].q
+}
+
+func (fake *FakeHealth) ServiceReturns(result1 []*api.ServiceEntry, result2 *api.QueryMeta, result3 error) {
+	fake.ServiceStub = nil
+	fake.serviceReturns = struct {
+		result1 []*api.ServiceEntry
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHealth) State(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	fake.stateMutex.Lock()
+	fake.stateArgsForCall = append(fake.stateArgsForCall, struct {
+		state string
+		q     *api.QueryOptions
+	}{state, q})
+	fake.stateMutex.Unlock()
+	if fake.StateStub != nil {
+		return fake.StateStub(state, q)
+	} else {
+		return fake.stateReturns.result1, fake.stateReturns.result2, fake.stateReturns.result3
+	}
+}
+
+func (fake *FakeHealth) StateCallCount() int {
+	fake.stateMutex.RLock()
+	defer fake.stateMutex.RUnlock()
+	return len(fake.stateArgsForCall)
+}
+
+func (fake *FakeHealth) StateArgsForCall(i int) (string, *api.QueryOptions) {
+	fake.stateMutex.RLock()
+	defer fake.stateMutex.RUnlock()
+	return fake.stateArgsForCall[i].state, fake.stateArgsForCall[i].q
+}
+
+func (fake *FakeHealth) StateReturns(result1 []*api.HealthCheck, result2 *api.QueryMeta, result3 error) {
+	fake.StateStub = nil
+	fake.stateReturns = struct {
+		result1 []*api.HealthCheck
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeHealth) WithContext(ctx context.Context) consuladapter.Health {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeHealth) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeHealth) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeHealth) WithContextReturns(result1 consuladapter.Health) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Health
+	}{result1}
+}
+
+var _ consuladapter.Health = new(FakeHealth)
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -31,6 +32,18 @@ type FakeKV struct {
 		result2 *api.QueryMeta
 		result3 error
 	}
+	KeysStub        func(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error)
+	keysMutex       sync.RWMutex
+	keysArgsForCall []struct {
+		prefix    string
+		separator string
+		q         *api.QueryOptions
+	}
+	keysReturns struct {
+		result1 []string
+		result2 *api.QueryMeta
+		result3 error
+	}
 	PutStub        func(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error)
 	putMutex       sync.RWMutex
 	putArgsForCall []struct {
@@ -41,6 +54,28 @@ type FakeKV struct {
 		result1 *api.WriteMeta
 		result2 error
 	}
+	CASStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	cASMutex       sync.RWMutex
+	cASArgsForCall []struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}
+	cASReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
+	AcquireStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	acquireMutex       sync.RWMutex
+	acquireArgsForCall []struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}
+	acquireReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
 	ReleaseStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	releaseMutex       sync.RWMutex
 	releaseArgsForCall []struct {
@@ -52,6 +87,27 @@ type FakeKV struct {
 		result2 *api.WriteMeta
 		result3 error
 	}
+	DeleteStub        func(key string, w *api.WriteOptions) (*api.WriteMeta, error)
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		key string
+		w   *api.WriteOptions
+	}
+	deleteReturns struct {
+		result1 *api.WriteMeta
+		result2 error
+	}
+	DeleteCASStub        func(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	deleteCASMutex       sync.RWMutex
+	deleteCASArgsForCall []struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}
+	deleteCASReturns struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}
 	DeleteTreeStub        func(prefix string, w *api.WriteOptions) (*api.WriteMeta, error)
 	deleteTreeMutex       sync.RWMutex
 	deleteTreeArgsForCall []struct {
@@ -62,6 +118,26 @@ type FakeKV struct {
 		result1 *api.WriteMeta
 		result2 error
 	}
+	TxnStub        func(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error)
+	txnMutex       sync.RWMutex
+	txnArgsForCall []struct {
+		txn api.KVTxnOps
+		q   *api.QueryOptions
+	}
+	txnReturns struct {
+		result1 bool
+		result2 *api.KVTxnResponse
+		result3 *api.QueryMeta
+		result4 error
+	}
+	WithContextStub        func(ctx context.Context) consuladapter.KV
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.KV
+	}
 }
 
 func (fake *FakeKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
@@ -134,6 +210,42 @@ func (fake *FakeKV) ListReturns(result1 api.KVPairs, result2 *api.QueryMeta, res
 	}{result1, result2, result3}
 }
 
+func (fake *FakeKV) Keys(prefix string, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	fake.keysMutex.Lock()
+	fake.keysArgsForCall = append(fake.keysArgsForCall, struct {
+		prefix    string
+		separator string
+		q         *api.QueryOptions
+	}{prefix, separator, q})
+	fake.keysMutex.Unlock()
+	if fake.KeysStub != nil {
+		return fake.KeysStub(prefix, separator, q)
+	} else {
+		return fake.keysReturns.result1, fake.keysReturns.result2, fake.keysReturns.result3
+	}
+}
+
+func (fake *FakeKV) KeysCallCount() int {
+	fake.keysMutex.RLock()
+	defer fake.keysMutex.RUnlock()
+	return len(fake.keysArgsForCall)
+}
+
+func (fake *FakeKV) KeysArgsForCall(i int) (string, string, *api.QueryOptions) {
+	fake.keysMutex.RLock()
+	defer fake.keysMutex.RUnlock()
+	return fake.keysArgsForCall[i].prefix, fake.keysArgsForCall[i].separator, fake.keysArgsForCall[i].q
+}
+
+func (fake *FakeKV) KeysReturns(result1 []string, result2 *api.QueryMeta, result3 error) {
+	fake.KeysStub = nil
+	fake.keysReturns = struct {
+		result1 []string
+		result2 *api.QueryMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
 	fake.putMutex.Lock()
 	fake.putArgsForCall = append(fake.putArgsForCall, struct {
@@ -168,6 +280,76 @@ func (fake *FakeKV) PutReturns(result1 *api.WriteMeta, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.cASMutex.Lock()
+	fake.cASArgsForCall = append(fake.cASArgsForCall, struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}{p, q})
+	fake.cASMutex.Unlock()
+	if fake.CASStub != nil {
+		return fake.CASStub(p, q)
+	} else {
+		return fake.cASReturns.result1, fake.cASReturns.result2, fake.cASReturns.result3
+	}
+}
+
+func (fake *FakeKV) CASCallCount() int {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return len(fake.cASArgsForCall)
+}
+
+func (fake *FakeKV) CASArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.cASMutex.RLock()
+	defer fake.cASMutex.RUnlock()
+	return fake.cASArgsForCall[i].p, fake.cASArgsForCall[i].q
+}
+
+func (fake *FakeKV) CASReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.CASStub = nil
+	fake.cASReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.acquireMutex.Lock()
+	fake.acquireArgsForCall = append(fake.acquireArgsForCall, struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}{p, q})
+	fake.acquireMutex.Unlock()
+	if fake.AcquireStub != nil {
+		return fake.AcquireStub(p, q)
+	} else {
+		return fake.acquireReturns.result1, fake.acquireReturns.result2, fake.acquireReturns.result3
+	}
+}
+
+func (fake *FakeKV) AcquireCallCount() int {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return len(fake.acquireArgsForCall)
+}
+
+func (fake *FakeKV) AcquireArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return fake.acquireArgsForCall[i].p, fake.acquireArgsForCall[i].q
+}
+
+func (fake *FakeKV) AcquireReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.AcquireStub = nil
+	fake.acquireReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
 	fake.releaseMutex.Lock()
 	fake.releaseArgsForCall = append(fake.releaseArgsForCall, struct {
@@ -203,6 +385,75 @@ func (fake *FakeKV) ReleaseReturns(result1 bool, result2 *api.WriteMeta, result3
 	}{result1, result2, result3}
 }
 
+func (fake *FakeKV) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	fake.deleteMutex.Lock()
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		key string
+		w   *api.WriteOptions
+	}{key, w})
+	fake.deleteMutex.Unlock()
+	if fake.DeleteStub != nil {
+		return fake.DeleteStub(key, w)
+	} else {
+		return fake.deleteReturns.result1, fake.deleteReturns.result2
+	}
+}
+
+func (fake *FakeKV) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+func (fake *FakeKV) DeleteArgsForCall(i int) (string, *api.WriteOptions) {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return fake.deleteArgsForCall[i].key, fake.deleteArgsForCall[i].w
+}
+
+func (fake *FakeKV) DeleteReturns(result1 *api.WriteMeta, result2 error) {
+	fake.DeleteStub = nil
+	fake.deleteReturns = struct {
+		result1 *api.WriteMeta
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeKV) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	fake.deleteCASMutex.Lock()
+	fake.deleteCASArgsForCall = append(fake.deleteCASArgsForCall, struct {
+		p *api.KVPair
+		q *api.WriteOptions
+	}{p, q})
+	fake.deleteCASMutex.Unlock()
+	if fake.DeleteCASStub != nil {
+		return fake.DeleteCASStub(p, q)
+	} else {
+		return fake.deleteCASReturns.result1, fake.deleteCASReturns.result2, fake.deleteCASReturns.result3
+	}
+}
+
+func (fake *FakeKV) DeleteCASCallCount() int {
+	fake.deleteCASMutex.RLock()
+	defer fake.deleteCASMutex.RUnlock()
+	return len(fake.deleteCASArgsForCall)
+}
+
+func (fake *FakeKV) DeleteCASArgsForCall(i int) (*api.KVPair, *api.WriteOptions) {
+	fake.deleteCASMutex.RLock()
+	defer fake.deleteCASMutex.RUnlock()
+	return fake.deleteCASArgsForCall[i].p, fake.deleteCASArgsForCall[i].q
+}
+
+func (fake *FakeKV) DeleteCASReturns(result1 bool, result2 *api.WriteMeta, result3 error) {
+	fake.DeleteCASStub = nil
+	fake.deleteCASReturns = struct {
+		result1 bool
+		result2 *api.WriteMeta
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
 	fake.deleteTreeMutex.Lock()
 	fake.deleteTreeArgsForCall = append(fake.deleteTreeArgsForCall, struct {
@@ -237,4 +488,72 @@ func (fake *FakeKV) DeleteTreeReturns(result1 *api.WriteMeta, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeKV) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	fake.txnMutex.Lock()
+	fake.txnArgsForCall = append(fake.txnArgsForCall, struct {
+		txn api.KVTxnOps
+		q   *api.QueryOptions
+	}{txn, q})
+	fake.txnMutex.Unlock()
+	if fake.TxnStub != nil {
+		return fake.TxnStub(txn, q)
+	} else {
+		return fake.txnReturns.result1, fake.txnReturns.result2, fake.txnReturns.result3, fake.txnReturns.result4
+	}
+}
+
+func (fake *FakeKV) TxnCallCount() int {
+	fake.txnMutex.RLock()
+	defer fake.txnMutex.RUnlock()
+	return len(fake.txnArgsForCall)
+}
+
+func (fake *FakeKV) TxnArgsForCall(i int) (api.KVTxnOps, *api.QueryOptions) {
+	fake.txnMutex.RLock()
+	defer fake.txnMutex.RUnlock()
+	return fake.txnArgsForCall[i].txn, fake.txnArgsForCall[i].q
+}
+
+func (fake *FakeKV) TxnReturns(result1 bool, result2 *api.KVTxnResponse, result3 *api.QueryMeta, result4 error) {
+	fake.TxnStub = nil
+	fake.txnReturns = struct {
+		result1 bool
+		result2 *api.KVTxnResponse
+		result3 *api.QueryMeta
+		result4 error
+	}{result1, result2, result3, result4}
+}
+
+func (fake *FakeKV) WithContext(ctx context.Context) consuladapter.KV {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeKV) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeKV) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeKV) WithContextReturns(result1 consuladapter.KV) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.KV
+	}{result1}
+}
+
 var _ consuladapter.KV = new(FakeKV)
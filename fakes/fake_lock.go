@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -17,6 +18,27 @@ type FakeLock struct {
 		result1 <-chan struct{}
 		result2 error
 	}
+	LockContextStub        func(ctx context.Context) (lostLock <-chan struct{}, err error)
+	lockContextMutex       sync.RWMutex
+	lockContextArgsForCall []struct {
+		ctx context.Context
+	}
+	lockContextReturns struct {
+		result1 <-chan struct{}
+		result2 error
+	}
+	UnlockStub        func() error
+	unlockMutex       sync.RWMutex
+	unlockArgsForCall []struct{}
+	unlockReturns     struct {
+		result1 error
+	}
+	DestroyStub        func() error
+	destroyMutex       sync.RWMutex
+	destroyArgsForCall []struct{}
+	destroyReturns     struct {
+		result1 error
+	}
 }
 
 func (fake *FakeLock) Lock(stopCh <-chan struct{}) (lostLock <-chan struct{}, err error) {
@@ -52,4 +74,85 @@ func (fake *FakeLock) LockReturns(result1 <-chan struct{}, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeLock) LockContext(ctx context.Context) (lostLock <-chan struct{}, err error) {
+	fake.lockContextMutex.Lock()
+	fake.lockContextArgsForCall = append(fake.lockContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.lockContextMutex.Unlock()
+	if fake.LockContextStub != nil {
+		return fake.LockContextStub(ctx)
+	} else {
+		return fake.lockContextReturns.result1, fake.lockContextReturns.result2
+	}
+}
+
+func (fake *FakeLock) LockContextCallCount() int {
+	fake.lockContextMutex.RLock()
+	defer fake.lockContextMutex.RUnlock()
+	return len(fake.lockContextArgsForCall)
+}
+
+func (fake *FakeLock) LockContextArgsForCall(i int) context.Context {
+	fake.lockContextMutex.RLock()
+	defer fake.lockContextMutex.RUnlock()
+	return fake.lockContextArgsForCall[i].ctx
+}
+
+func (fake *FakeLock) LockContextReturns(result1 <-chan struct{}, result2 error) {
+	fake.LockContextStub = nil
+	fake.lockContextReturns = struct {
+		result1 <-chan struct{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLock) Unlock() error {
+	fake.unlockMutex.Lock()
+	fake.unlockArgsForCall = append(fake.unlockArgsForCall, struct{}{})
+	fake.unlockMutex.Unlock()
+	if fake.UnlockStub != nil {
+		return fake.UnlockStub()
+	} else {
+		return fake.unlockReturns.result1
+	}
+}
+
+func (fake *FakeLock) UnlockCallCount() int {
+	fake.unlockMutex.RLock()
+	defer fake.unlockMutex.RUnlock()
+	return len(fake.unlockArgsForCall)
+}
+
+func (fake *FakeLock) UnlockReturns(result1 error) {
+	fake.UnlockStub = nil
+	fake.unlockReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLock) Destroy() error {
+	fake.destroyMutex.Lock()
+	fake.destroyArgsForCall = append(fake.destroyArgsForCall, struct{}{})
+	fake.destroyMutex.Unlock()
+	if fake.DestroyStub != nil {
+		return fake.DestroyStub()
+	} else {
+		return fake.destroyReturns.result1
+	}
+}
+
+func (fake *FakeLock) DestroyCallCount() int {
+	fake.destroyMutex.RLock()
+	defer fake.destroyMutex.RUnlock()
+	return len(fake.destroyArgsForCall)
+}
+
+func (fake *FakeLock) DestroyReturns(result1 error) {
+	fake.DestroyStub = nil
+	fake.destroyReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ consuladapter.Lock = new(FakeLock)
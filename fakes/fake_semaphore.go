@@ -0,0 +1,158 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+type FakeSemaphore struct {
+	AcquireStub        func(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error)
+	acquireMutex       sync.RWMutex
+	acquireArgsForCall []struct {
+		stopCh <-chan struct{}
+	}
+	acquireReturns struct {
+		result1 <-chan struct{}
+		result2 error
+	}
+	AcquireContextStub        func(ctx context.Context) (lostSlot <-chan struct{}, err error)
+	acquireContextMutex       sync.RWMutex
+	acquireContextArgsForCall []struct {
+		ctx context.Context
+	}
+	acquireContextReturns struct {
+		result1 <-chan struct{}
+		result2 error
+	}
+	ReleaseStub        func() error
+	releaseMutex       sync.RWMutex
+	releaseArgsForCall []struct{}
+	releaseReturns     struct {
+		result1 error
+	}
+	DestroyStub        func() error
+	destroyMutex       sync.RWMutex
+	destroyArgsForCall []struct{}
+	destroyReturns     struct {
+		result1 error
+	}
+}
+
+func (fake *FakeSemaphore) Acquire(stopCh <-chan struct{}) (lostSlot <-chan struct{}, err error) {
+	fake.acquireMutex.Lock()
+	fake.acquireArgsForCall = append(fake.acquireArgsForCall, struct {
+		stopCh <-chan struct{}
+	}{stopCh})
+	fake.acquireMutex.Unlock()
+	if fake.AcquireStub != nil {
+		return fake.AcquireStub(stopCh)
+	} else {
+		return fake.acquireReturns.result1, fake.acquireReturns.result2
+	}
+}
+
+func (fake *FakeSemaphore) AcquireCallCount() int {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return len(fake.acquireArgsForCall)
+}
+
+func (fake *FakeSemaphore) AcquireArgsForCall(i int) <-chan struct{} {
+	fake.acquireMutex.RLock()
+	defer fake.acquireMutex.RUnlock()
+	return fake.acquireArgsForCall[i].stopCh
+}
+
+func (fake *FakeSemaphore) AcquireReturns(result1 <-chan struct{}, result2 error) {
+	fake.AcquireStub = nil
+	fake.acquireReturns = struct {
+		result1 <-chan struct{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSemaphore) AcquireContext(ctx context.Context) (lostSlot <-chan struct{}, err error) {
+	fake.acquireContextMutex.Lock()
+	fake.acquireContextArgsForCall = append(fake.acquireContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.acquireContextMutex.Unlock()
+	if fake.AcquireContextStub != nil {
+		return fake.AcquireContextStub(ctx)
+	} else {
+		return fake.acquireContextReturns.result1, fake.acquireContextReturns.result2
+	}
+}
+
+func (fake *FakeSemaphore) AcquireContextCallCount() int {
+	fake.acquireContextMutex.RLock()
+	defer fake.acquireContextMutex.RUnlock()
+	return len(fake.acquireContextArgsForCall)
+}
+
+func (fake *FakeSemaphore) AcquireContextArgsForCall(i int) context.Context {
+	fake.acquireContextMutex.RLock()
+	defer fake.acquireContextMutex.RUnlock()
+	return fake.acquireContextArgsForCall[i].ctx
+}
+
+func (fake *FakeSemaphore) AcquireContextReturns(result1 <-chan struct{}, result2 error) {
+	fake.AcquireContextStub = nil
+	fake.acquireContextReturns = struct {
+		result1 <-chan struct{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSemaphore) Release() error {
+	fake.releaseMutex.Lock()
+	fake.releaseArgsForCall = append(fake.releaseArgsForCall, struct{}{})
+	fake.releaseMutex.Unlock()
+	if fake.ReleaseStub != nil {
+		return fake.ReleaseStub()
+	} else {
+		return fake.releaseReturns.result1
+	}
+}
+
+func (fake *FakeSemaphore) ReleaseCallCount() int {
+	fake.releaseMutex.RLock()
+	defer fake.releaseMutex.RUnlock()
+	return len(fake.releaseArgsForCall)
+}
+
+func (fake *FakeSemaphore) ReleaseReturns(result1 error) {
+	fake.ReleaseStub = nil
+	fake.releaseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSemaphore) Destroy() error {
+	fake.destroyMutex.Lock()
+	fake.destroyArgsForCall = append(fake.destroyArgsForCall, struct{}{})
+	fake.destroyMutex.Unlock()
+	if fake.DestroyStub != nil {
+		return fake.DestroyStub()
+	} else {
+		return fake.destroyReturns.result1
+	}
+}
+
+func (fake *FakeSemaphore) DestroyCallCount() int {
+	fake.destroyMutex.RLock()
+	defer fake.destroyMutex.RUnlock()
+	return len(fake.destroyArgsForCall)
+}
+
+func (fake *FakeSemaphore) DestroyReturns(result1 error) {
+	fake.DestroyStub = nil
+	fake.destroyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+var _ consuladapter.Semaphore = new(FakeSemaphore)
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -95,6 +96,14 @@ type FakeSession struct {
 	renewPeriodicReturns struct {
 		result1 error
 	}
+	WithContextStub        func(ctx context.Context) consuladapter.Session
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Session
+	}
 }
 
 func (fake *FakeSession) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
@@ -375,4 +384,36 @@ func (fake *FakeSession) RenewPeriodicReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeSession) WithContext(ctx context.Context) consuladapter.Session {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeSession) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeSession) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeSession) WithContextReturns(result1 consuladapter.Session) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Session
+	}{result1}
+}
+
 var _ consuladapter.Session = new(FakeSession)
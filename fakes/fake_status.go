@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/consuladapter"
@@ -22,6 +23,14 @@ type FakeStatus struct {
 		result1 []string
 		result2 error
 	}
+	WithContextStub        func(ctx context.Context) consuladapter.Status
+	withContextMutex       sync.RWMutex
+	withContextArgsForCall []struct {
+		ctx context.Context
+	}
+	withContextReturns struct {
+		result1 consuladapter.Status
+	}
 }
 
 func (fake *FakeStatus) Leader() (string, error) {
@@ -74,4 +83,36 @@ func (fake *FakeStatus) PeersReturns(result1 []string, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeStatus) WithContext(ctx context.Context) consuladapter.Status {
+	fake.withContextMutex.Lock()
+	fake.withContextArgsForCall = append(fake.withContextArgsForCall, struct {
+		ctx context.Context
+	}{ctx})
+	fake.withContextMutex.Unlock()
+	if fake.WithContextStub != nil {
+		return fake.WithContextStub(ctx)
+	} else {
+		return fake.withContextReturns.result1
+	}
+}
+
+func (fake *FakeStatus) WithContextCallCount() int {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return len(fake.withContextArgsForCall)
+}
+
+func (fake *FakeStatus) WithContextArgsForCall(i int) context.Context {
+	fake.withContextMutex.RLock()
+	defer fake.withContextMutex.RUnlock()
+	return fake.withContextArgsForCall[i].ctx
+}
+
+func (fake *FakeStatus) WithContextReturns(result1 consuladapter.Status) {
+	fake.WithContextStub = nil
+	fake.withContextReturns = struct {
+		result1 consuladapter.Status
+	}{result1}
+}
+
 var _ consuladapter.Status = new(FakeStatus)
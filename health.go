@@ -0,0 +1,50 @@
+package consuladapter
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
+
+//go:generate counterfeiter -o fakes/fake_health.go . Health
+
+type Health interface {
+	Node(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+	Checks(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+	Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error)
+	State(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error)
+
+	WithContext(ctx context.Context) Health
+}
+
+type health struct {
+	health *api.Health
+	client *client
+}
+
+func NewConsulHealth(h *api.Health) Health {
+	return &health{health: h}
+}
+
+func (h *health) Node(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return h.health.Node(node, q)
+}
+
+func (h *health) Checks(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return h.health.Checks(service, q)
+}
+
+func (h *health) Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	return h.health.Service(service, tag, passingOnly, q)
+}
+
+func (h *health) State(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	return h.health.State(state, q)
+}
+
+func (h *health) WithContext(ctx context.Context) Health {
+	if h.client == nil {
+		return &contextHealth{health: h, ctx: ctx}
+	}
+	return h.client.WithContext(ctx).Health()
+}
@@ -0,0 +1,29 @@
+package acquire
+
+import "context"
+
+// UntilDone calls a blocking acquire function such as api.Lock.Lock with a
+// stop channel that is closed when ctx is done, returning ctx.Err() if that
+// stopped it.
+func UntilDone(ctx context.Context, acquire func(stopCh <-chan struct{}) (<-chan struct{}, error)) (<-chan struct{}, error) {
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stopCh)
+		case <-done:
+		}
+	}()
+
+	lost, err := acquire(stopCh)
+	if err != nil {
+		return nil, err
+	}
+	if lost == nil {
+		return nil, ctx.Err()
+	}
+	return lost, nil
+}
@@ -0,0 +1 @@
+package acquire // import "code.cloudfoundry.org/consuladapter/internal/acquire"
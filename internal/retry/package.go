@@ -0,0 +1 @@
+package retry // import "code.cloudfoundry.org/consuladapter/internal/retry"
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Bounds defaults min and max, making sure max is not below min.
+func Bounds(min, max, defaultMin, defaultMax time.Duration) (time.Duration, time.Duration) {
+	if min <= 0 {
+		min = defaultMin
+	}
+	if max < min {
+		max = defaultMax
+		if max < min {
+			max = min
+		}
+	}
+	return min, max
+}
+
+// Backoff doubles min for every failure, up to max.
+func Backoff(min, max time.Duration, failures uint) time.Duration {
+	if failures > 16 {
+		return max
+	}
+
+	d := min << failures
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// Jitter returns a random duration between d/2 and d.
+func Jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)))
+}
+
+// Sleep waits for d and returns false if ctx is done first.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
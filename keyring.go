@@ -0,0 +1,85 @@
+package consuladapter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const keyIDSize = 4
+
+// Keyring holds the AES keys an EncodedKV encrypts with. Values are always
+// encrypted with the primary key, and decrypted with whichever key they name,
+// so a key can be rotated by adding a new primary and resealing.
+type Keyring struct {
+	primary uint32
+	aeads   map[uint32]cipher.AEAD
+}
+
+// NewKeyring builds a keyring from AES-128, AES-192 or AES-256 keys by ID.
+// primary must be one of them.
+func NewKeyring(primary uint32, keys map[uint32][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, NewInvalidOptionError("primary", fmt.Sprintf("no key with ID %d", primary))
+	}
+
+	aeads := make(map[uint32]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, NewInvalidOptionError("keys", fmt.Sprintf("key %d: %s", id, err))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+
+	return &Keyring{primary: primary, aeads: aeads}, nil
+}
+
+func (k *Keyring) Primary() uint32 {
+	return k.primary
+}
+
+// seal encrypts plaintext with the primary key, authenticating data with it.
+// The result starts with the key ID and the nonce.
+func (k *Keyring) seal(plaintext, data []byte) ([]byte, error) {
+	aead := k.aeads[k.primary]
+
+	out := make([]byte, keyIDSize+aead.NonceSize(), keyIDSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint32(out, k.primary)
+	nonce := out[keyIDSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, data), nil
+}
+
+func (k *Keyring) open(ciphertext, data []byte) ([]byte, error) {
+	id, ok := sealedKeyID(ciphertext)
+	if !ok {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %d", id)
+	}
+
+	ciphertext = ciphertext[keyIDSize:]
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], data)
+}
+
+func sealedKeyID(ciphertext []byte) (uint32, bool) {
+	if len(ciphertext) < keyIDSize {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ciphertext), true
+}
@@ -1,19 +1,32 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_kv.go . KV
 
 type KV interface {
 	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
 	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+	Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error)
 	Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error)
+	CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error)
+	DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
 	DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error)
+	Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error)
+
+	WithContext(ctx context.Context) KV
 }
 
 type keyValue struct {
 	keyValue *api.KV
+	client   *client
 }
 
 func NewConsulKV(kv *api.KV) KV {
@@ -28,14 +41,45 @@ func (kv *keyValue) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.
 	return kv.keyValue.List(prefix, q)
 }
 
+func (kv *keyValue) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	return kv.keyValue.Keys(prefix, separator, q)
+}
+
 func (kv *keyValue) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
 	return kv.keyValue.Put(p, q)
 }
 
+func (kv *keyValue) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return kv.keyValue.CAS(p, q)
+}
+
+func (kv *keyValue) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return kv.keyValue.Acquire(p, q)
+}
+
 func (kv *keyValue) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
 	return kv.keyValue.Release(p, q)
 }
 
+func (kv *keyValue) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return kv.keyValue.Delete(key, w)
+}
+
+func (kv *keyValue) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	return kv.keyValue.DeleteCAS(p, q)
+}
+
 func (kv *keyValue) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
 	return kv.keyValue.DeleteTree(prefix, w)
 }
+
+func (kv *keyValue) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	return kv.keyValue.Txn(txn, q)
+}
+
+func (kv *keyValue) WithContext(ctx context.Context) KV {
+	if kv.client == nil {
+		return &contextKV{kv: kv, ctx: ctx}
+	}
+	return kv.client.WithContext(ctx).KV()
+}
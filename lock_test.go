@@ -0,0 +1,73 @@
+package consuladapter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock", func() {
+	var lock consuladapter.Lock
+
+	BeforeEach(func() {
+		Expect(consulRunner.Reset()).To(Succeed())
+		consulClient = consulRunner.NewClient()
+
+		lock, err = consulClient.LockOpts(&api.LockOptions{Key: "lock", Value: []byte("me")})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Unlock", func() {
+		It("releases the lock so that another client can acquire it", func() {
+			lostCh, err := lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.Unlock()).To(Succeed())
+			Eventually(lostCh).Should(BeClosed())
+
+			other, err := consulClient.LockOpts(&api.LockOptions{Key: "lock", LockTryOnce: true})
+			Expect(err).NotTo(HaveOccurred())
+			otherLostCh, err := other.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(otherLostCh).NotTo(BeNil())
+		})
+
+		It("returns a LockNotHeldError when the lock is not held", func() {
+			err := lock.Unlock()
+			Expect(err).To(Equal(consuladapter.NewLockNotHeldError("lock")))
+			Expect(errors.Is(err, api.ErrLockNotHeld)).To(BeTrue())
+		})
+	})
+
+	Describe("Destroy", func() {
+		It("deletes the lock key once released", func() {
+			_, err := lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Unlock()).To(Succeed())
+
+			Expect(lock.Destroy()).To(Succeed())
+
+			pair, _, err := consulClient.KV().Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+
+		It("returns a LockInUseError while the lock is held", func() {
+			_, err := lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer lock.Unlock()
+
+			Expect(lock.Destroy()).To(Equal(consuladapter.NewLockInUseError("lock")))
+
+			other, err := consulClient.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			err = other.Destroy()
+			Expect(err).To(Equal(consuladapter.NewLockInUseError("lock")))
+			Expect(errors.Is(err, api.ErrLockInUse)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,401 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+// Serf member statuses as reported by Members.
+const (
+	memberAlive = 1
+	memberLeft  = 3
+)
+
+const (
+	nodeMaintenanceCheckID   = "_node_maintenance"
+	serviceMaintenancePrefix = "_service_maintenance:"
+	defaultMaintenanceReason = "Maintenance mode is enabled for this %s, but no reason was provided. This is a default message."
+)
+
+type agent struct {
+	store *store
+	ctx   context.Context
+}
+
+func (a *agent) Self() (map[string]map[string]interface{}, error) {
+	var self map[string]map[string]interface{}
+	_, err := a.store.read(a.ctx, nil, func() {
+		node := a.store.localNode()
+		self = map[string]map[string]interface{}{
+			"Config": {
+				"NodeName":      node.node.Node,
+				"Datacenter":    a.store.datacenter,
+				"AdvertiseAddr": node.node.Address,
+				"Server":        true,
+			},
+			"Member": {
+				"Name":   node.node.Node,
+				"Addr":   node.node.Address,
+				"Status": memberAlive,
+			},
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self, nil
+}
+
+// Members lists the local agent and the members joined with Join. There is
+// no failure detection, so members only leave through ForceLeave.
+func (a *agent) Members(wan bool) ([]*api.AgentMember, error) {
+	var members []*api.AgentMember
+	_, err := a.store.read(a.ctx, nil, func() {
+		local := a.store.localNode().node
+		name := local.Node
+		if wan {
+			name += "." + a.store.datacenter
+		}
+		members = append(members, &api.AgentMember{Name: name, Addr: local.Address, Port: 8301, Status: memberAlive})
+
+		names := make([]string, 0, len(a.store.members))
+		for addr, m := range a.store.members {
+			if m.wan == wan {
+				names = append(names, addr)
+			}
+		}
+		sort.Strings(names)
+		for _, addr := range names {
+			m := a.store.members[addr].member
+			members = append(members, &m)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (a *agent) Checks() (map[string]*api.AgentCheck, error) {
+	checks := map[string]*api.AgentCheck{}
+	_, err := a.store.read(a.ctx, nil, func() {
+		for id, check := range a.store.localNode().checks {
+			c := check.check
+			checks[id] = &c
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+func (a *agent) Services() (map[string]*api.AgentService, error) {
+	services := map[string]*api.AgentService{}
+	_, err := a.store.read(a.ctx, nil, func() {
+		for id, service := range a.store.localNode().services {
+			services[id] = copyService(service)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (a *agent) ServiceRegister(service *api.AgentServiceRegistration) error {
+	id := service.ID
+	if id == "" {
+		id = service.Name
+	}
+	if id == "" {
+		return fmt.Errorf("Unexpected response code: 400 (Missing service name)")
+	}
+
+	checks := service.Checks
+	if service.Check != nil {
+		checks = append(api.AgentServiceChecks{service.Check}, checks...)
+	}
+
+	states := make([]*checkState, len(checks))
+	for i, check := range checks {
+		checkID := "service:" + id
+		if len(checks) > 1 {
+			checkID = fmt.Sprintf("service:%s:%d", id, i+1)
+		}
+
+		state, err := newCheckState(api.AgentCheckRegistration{
+			ID:                checkID,
+			Name:              fmt.Sprintf("Service '%s' check", service.Name),
+			ServiceID:         id,
+			AgentServiceCheck: *check,
+		})
+		if err != nil {
+			return err
+		}
+		state.check.ServiceName = service.Name
+		states[i] = state
+	}
+
+	_, err := a.store.write(a.ctx, func() error {
+		node := a.store.localNode()
+		node.services[id] = &api.AgentService{
+			ID:                id,
+			Service:           service.Name,
+			Tags:              append([]string{}, service.Tags...),
+			Port:              service.Port,
+			Address:           service.Address,
+			EnableTagOverride: service.EnableTagOverride,
+		}
+		a.store.bump(tableServices)
+
+		for _, state := range states {
+			a.store.addCheck(node, state)
+		}
+		return nil
+	})
+	return err
+}
+
+func (a *agent) ServiceDeregister(serviceID string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		a.store.removeService(a.store.localNode(), serviceID)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) CheckRegister(check *api.AgentCheckRegistration) error {
+	state, err := newCheckState(*check)
+	if err != nil {
+		return err
+	}
+	if state.check.CheckID == "" {
+		return fmt.Errorf("Unexpected response code: 400 (Missing check name)")
+	}
+
+	_, err = a.store.write(a.ctx, func() error {
+		node := a.store.localNode()
+		if check.ServiceID != "" {
+			service, ok := node.services[check.ServiceID]
+			if !ok {
+				return fmt.Errorf("Unexpected response code: 500 (ServiceID %q does not exist)", check.ServiceID)
+			}
+			state.check.ServiceName = service.Service
+		}
+		a.store.addCheck(node, state)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) CheckDeregister(checkID string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		a.store.removeCheck(a.store.localNode(), checkID)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) PassTTL(checkID, note string) error {
+	return a.updateTTL(checkID, note, api.HealthPassing)
+}
+
+func (a *agent) WarnTTL(checkID, note string) error {
+	return a.updateTTL(checkID, note, api.HealthWarning)
+}
+
+func (a *agent) FailTTL(checkID, note string) error {
+	return a.updateTTL(checkID, note, api.HealthCritical)
+}
+
+func (a *agent) UpdateTTL(checkID, output, status string) error {
+	switch status {
+	case "pass", api.HealthPassing:
+		status = api.HealthPassing
+	case "warn", api.HealthWarning:
+		status = api.HealthWarning
+	case "fail", api.HealthCritical:
+		status = api.HealthCritical
+	default:
+		return fmt.Errorf("Invalid status: %s", status)
+	}
+	return a.updateTTL(checkID, output, status)
+}
+
+func (a *agent) updateTTL(checkID, output, status string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		node := a.store.localNode()
+		check, ok := node.checks[checkID]
+		if !ok || check.ttl == 0 {
+			return fmt.Errorf("Unexpected response code: 500 (CheckID %q does not have associated TTL)", checkID)
+		}
+
+		a.store.setCheckStatus(node, check, status, output)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) NodeName() (string, error) {
+	if err := a.ctx.Err(); err != nil {
+		return "", err
+	}
+	return a.store.nodeName, nil
+}
+
+// Join adds a member at addr, named after it.
+func (a *agent) Join(addr string, wan bool) error {
+	_, err := a.store.write(a.ctx, func() error {
+		a.store.members[addr] = &memberState{
+			member: api.AgentMember{Name: addr, Addr: addr, Port: 8301, Status: memberAlive},
+			wan:    wan,
+		}
+		return nil
+	})
+	return err
+}
+
+func (a *agent) ForceLeave(node string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		for _, m := range a.store.members {
+			if m.member.Name == node {
+				m.member.Status = memberLeft
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (a *agent) EnableServiceMaintenance(serviceID, reason string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		node := a.store.localNode()
+		service, ok := node.services[serviceID]
+		if !ok {
+			return fmt.Errorf("Unexpected response code: 404 (No service registered with ID %q)", serviceID)
+		}
+		if reason == "" {
+			reason = fmt.Sprintf(defaultMaintenanceReason, "service")
+		}
+
+		a.store.addCheck(node, &checkState{check: api.AgentCheck{
+			CheckID:     serviceMaintenancePrefix + serviceID,
+			Name:        "Service Maintenance Mode",
+			Notes:       reason,
+			Status:      api.HealthCritical,
+			ServiceID:   serviceID,
+			ServiceName: service.Service,
+		}})
+		return nil
+	})
+	return err
+}
+
+func (a *agent) DisableServiceMaintenance(serviceID string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		node := a.store.localNode()
+		if _, ok := node.services[serviceID]; !ok {
+			return fmt.Errorf("Unexpected response code: 404 (No service registered with ID %q)", serviceID)
+		}
+		a.store.removeCheck(node, serviceMaintenancePrefix+serviceID)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) EnableNodeMaintenance(reason string) error {
+	_, err := a.store.write(a.ctx, func() error {
+		if reason == "" {
+			reason = fmt.Sprintf(defaultMaintenanceReason, "node")
+		}
+		a.store.addCheck(a.store.localNode(), &checkState{check: api.AgentCheck{
+			CheckID: nodeMaintenanceCheckID,
+			Name:    "Node Maintenance Mode",
+			Notes:   reason,
+			Status:  api.HealthCritical,
+		}})
+		return nil
+	})
+	return err
+}
+
+func (a *agent) DisableNodeMaintenance() error {
+	_, err := a.store.write(a.ctx, func() error {
+		a.store.removeCheck(a.store.localNode(), nodeMaintenanceCheckID)
+		return nil
+	})
+	return err
+}
+
+func (a *agent) WithContext(ctx context.Context) consuladapter.Agent {
+	return &agent{store: a.store, ctx: ctx}
+}
+
+func newCheckState(reg api.AgentCheckRegistration) (*checkState, error) {
+	state := &checkState{check: api.AgentCheck{
+		CheckID:   reg.ID,
+		Name:      reg.Name,
+		Notes:     reg.Notes,
+		ServiceID: reg.ServiceID,
+		Status:    reg.Status,
+	}}
+	if state.check.CheckID == "" {
+		state.check.CheckID = reg.Name
+	}
+	if state.check.Status == "" {
+		state.check.Status = api.HealthCritical
+	}
+
+	if reg.TTL != "" {
+		ttl, err := time.ParseDuration(reg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected response code: 400 (Invalid TTL %q: %s)", reg.TTL, err)
+		}
+		state.ttl = ttl
+	}
+
+	return state, nil
+}
+
+// The agent operations below must be called with the store mutex held.
+
+func (s *store) localNode() *nodeState {
+	return s.nodes[s.nodeName]
+}
+
+func (s *store) addCheck(node *nodeState, state *checkState) {
+	state.check.Node = node.node.Node
+	if state.ttl > 0 {
+		state.expiresAt = s.clock.Now().Add(state.ttl)
+		s.schedule(state.ttl)
+	}
+
+	node.checks[state.check.CheckID] = state
+	s.bump(tableChecks)
+
+	if state.check.Status == api.HealthCritical {
+		s.invalidateSessionsForCheck(node.node.Node, state.check.CheckID)
+	}
+}
+
+func (s *store) removeCheck(node *nodeState, checkID string) {
+	if _, ok := node.checks[checkID]; !ok {
+		return
+	}
+
+	delete(node.checks, checkID)
+	s.bump(tableChecks)
+	s.invalidateSessionsForCheck(node.node.Node, checkID)
+}
+
+func copyService(service *api.AgentService) *api.AgentService {
+	c := *service
+	c.Tags = append([]string{}, service.Tags...)
+	return &c
+}
@@ -0,0 +1,178 @@
+package memconsul_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Agent", func() {
+	var (
+		clock *memconsul.FakeClock
+		agent consuladapter.Agent
+	)
+
+	BeforeEach(func() {
+		clock = memconsul.NewFakeClock(time.Now())
+		agent = memconsul.NewClientWithClock(clock).Agent()
+	})
+
+	It("registers services along with their checks", func() {
+		err := agent.ServiceRegister(&api.AgentServiceRegistration{
+			ID:     "web-1",
+			Name:   "web",
+			Tags:   []string{"v1"},
+			Port:   8080,
+			Checks: api.AgentServiceChecks{{TTL: "5s"}, {TTL: "10s"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		services, err := agent.Services()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(HaveKey("web-1"))
+		Expect(services["web-1"].Service).To(Equal("web"))
+		Expect(services["web-1"].Port).To(Equal(8080))
+
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveKey("service:web-1:1"))
+		Expect(checks).To(HaveKey("service:web-1:2"))
+		Expect(checks["service:web-1:1"].Status).To(Equal(api.HealthCritical))
+		Expect(checks["service:web-1:1"].ServiceName).To(Equal("web"))
+
+		err = agent.ServiceDeregister("web-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveLen(1))
+		Expect(checks).To(HaveKey("serfHealth"))
+	})
+
+	It("updates TTL checks and marks them critical once the TTL elapses", func() {
+		err := agent.ServiceRegister(&api.AgentServiceRegistration{
+			Name:  "web",
+			Check: &api.AgentServiceCheck{TTL: "5s"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(agent.PassTTL("service:web", "all good")).To(Succeed())
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks["service:web"].Status).To(Equal(api.HealthPassing))
+		Expect(checks["service:web"].Output).To(Equal("all good"))
+
+		Expect(agent.WarnTTL("service:web", "hmm")).To(Succeed())
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks["service:web"].Status).To(Equal(api.HealthWarning))
+
+		clock.Increment(5 * time.Second)
+		Eventually(func() string {
+			checks, err := agent.Checks()
+			Expect(err).NotTo(HaveOccurred())
+			return checks["service:web"].Status
+		}).Should(Equal(api.HealthCritical))
+	})
+
+	It("errors when updating a check without a TTL", func() {
+		Expect(agent.PassTTL("serfHealth", "")).NotTo(Succeed())
+		Expect(agent.FailTTL("missing", "")).NotTo(Succeed())
+	})
+
+	It("reports its node name", func() {
+		Expect(agent.NodeName()).To(Equal(memconsul.DefaultNodeName))
+	})
+
+	It("registers standalone checks and updates them with UpdateTTL", func() {
+		err := agent.CheckRegister(&api.AgentCheckRegistration{
+			ID:                "disk",
+			Name:              "Disk",
+			AgentServiceCheck: api.AgentServiceCheck{TTL: "5s"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(agent.UpdateTTL("disk", "90% full", api.HealthWarning)).To(Succeed())
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks["disk"].Status).To(Equal(api.HealthWarning))
+		Expect(checks["disk"].Output).To(Equal("90% full"))
+
+		Expect(agent.UpdateTTL("disk", "", "pass")).To(Succeed())
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks["disk"].Status).To(Equal(api.HealthPassing))
+
+		Expect(agent.UpdateTTL("disk", "", "sideways")).NotTo(Succeed())
+	})
+
+	It("errors when registering a check for a missing service", func() {
+		err := agent.CheckRegister(&api.AgentCheckRegistration{
+			Name:              "web",
+			ServiceID:         "web",
+			AgentServiceCheck: api.AgentServiceCheck{TTL: "5s"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("does not exist")))
+	})
+
+	It("describes itself", func() {
+		self, err := agent.Self()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(self["Config"]["NodeName"]).To(Equal(memconsul.DefaultNodeName))
+		Expect(self["Member"]["Name"]).To(Equal(memconsul.DefaultNodeName))
+	})
+
+	It("lists joined members until they are forced to leave", func() {
+		Expect(agent.Join("10.0.0.2", false)).To(Succeed())
+
+		members, err := agent.Members(false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(2))
+		Expect(members[0].Name).To(Equal(memconsul.DefaultNodeName))
+		Expect(members[1].Addr).To(Equal("10.0.0.2"))
+		Expect(members[1].Status).To(Equal(1))
+
+		members, err = agent.Members(true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(1))
+
+		Expect(agent.ForceLeave("10.0.0.2")).To(Succeed())
+		members, err = agent.Members(false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members[1].Status).To(Equal(3))
+	})
+
+	It("puts services and the node into maintenance", func() {
+		Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "web"})).To(Succeed())
+
+		Expect(agent.EnableServiceMaintenance("web", "draining")).To(Succeed())
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveKey("_service_maintenance:web"))
+		Expect(checks["_service_maintenance:web"].Status).To(Equal(api.HealthCritical))
+		Expect(checks["_service_maintenance:web"].Notes).To(Equal("draining"))
+
+		Expect(agent.DisableServiceMaintenance("web")).To(Succeed())
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).NotTo(HaveKey("_service_maintenance:web"))
+
+		Expect(agent.EnableServiceMaintenance("missing", "")).NotTo(Succeed())
+
+		Expect(agent.EnableNodeMaintenance("")).To(Succeed())
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveKey("_node_maintenance"))
+		Expect(checks["_node_maintenance"].Notes).To(ContainSubstring("no reason was provided"))
+
+		Expect(agent.DisableNodeMaintenance()).To(Succeed())
+		checks, err = agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).NotTo(HaveKey("_node_maintenance"))
+	})
+})
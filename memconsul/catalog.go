@@ -0,0 +1,268 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type catalog struct {
+	store *store
+	ctx   context.Context
+}
+
+func (c *catalog) Nodes(q *api.QueryOptions) ([]*api.Node, *api.QueryMeta, error) {
+	var nodes []*api.Node
+	meta, err := c.store.read(c.ctx, q, func() {
+		for _, node := range c.store.nodes {
+			nodes = append(nodes, copyNode(&node.node))
+		}
+	}, tableNodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+	return nodes, meta, nil
+}
+
+func (c *catalog) Node(node string, q *api.QueryOptions) (*api.CatalogNode, *api.QueryMeta, error) {
+	var result *api.CatalogNode
+	meta, err := c.store.read(c.ctx, q, func() {
+		n, ok := c.store.nodes[node]
+		if !ok {
+			return
+		}
+
+		result = &api.CatalogNode{
+			Node:     copyNode(&n.node),
+			Services: map[string]*api.AgentService{},
+		}
+		for id, service := range n.services {
+			result.Services[id] = copyService(service)
+		}
+	}, tableNodes, tableServices)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, meta, nil
+}
+
+func (c *catalog) Services(q *api.QueryOptions) (map[string][]string, *api.QueryMeta, error) {
+	services := map[string][]string{}
+	meta, err := c.store.read(c.ctx, q, func() {
+		seen := map[string]map[string]bool{}
+		for _, name := range c.store.sortedNodeNames() {
+			n := c.store.nodes[name]
+			for _, id := range sortedServiceIDs(n) {
+				service := n.services[id]
+				if seen[service.Service] == nil {
+					seen[service.Service] = map[string]bool{}
+					services[service.Service] = []string{}
+				}
+				for _, tag := range service.Tags {
+					if !seen[service.Service][tag] {
+						seen[service.Service][tag] = true
+						services[service.Service] = append(services[service.Service], tag)
+					}
+				}
+			}
+		}
+	}, tableServices)
+	if err != nil {
+		return nil, nil, err
+	}
+	return services, meta, nil
+}
+
+func (c *catalog) Service(service, tag string, q *api.QueryOptions) ([]*api.CatalogService, *api.QueryMeta, error) {
+	var services []*api.CatalogService
+	meta, err := c.store.read(c.ctx, q, func() {
+		for _, name := range c.store.sortedNodeNames() {
+			n := c.store.nodes[name]
+			for _, id := range sortedServiceIDs(n) {
+				s := n.services[id]
+				if s.Service != service || (tag != "" && !hasTag(s.Tags, tag)) {
+					continue
+				}
+
+				node := copyNode(&n.node)
+				services = append(services, &api.CatalogService{
+					Node:                     node.Node,
+					Address:                  node.Address,
+					TaggedAddresses:          node.TaggedAddresses,
+					ServiceID:                s.ID,
+					ServiceName:              s.Service,
+					ServiceAddress:           s.Address,
+					ServiceTags:              append([]string{}, s.Tags...),
+					ServicePort:              s.Port,
+					ServiceEnableTagOverride: s.EnableTagOverride,
+				})
+			}
+		}
+	}, tableNodes, tableServices)
+	if err != nil {
+		return nil, nil, err
+	}
+	return services, meta, nil
+}
+
+func (c *catalog) Datacenters() ([]string, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return []string{c.store.datacenter}, nil
+}
+
+func (c *catalog) Register(reg *api.CatalogRegistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	if reg.Node == "" || reg.Address == "" {
+		return nil, fmt.Errorf("Unexpected response code: 400 (Must provide node and address)")
+	}
+
+	var service *api.AgentService
+	if reg.Service != nil {
+		service = copyService(reg.Service)
+		if service.ID == "" {
+			service.ID = service.Service
+		}
+		if service.Service == "" {
+			return nil, fmt.Errorf("Unexpected response code: 400 (Must provide service name with ID)")
+		}
+	}
+
+	var check *checkState
+	if reg.Check != nil {
+		check = &checkState{check: *reg.Check}
+		if check.check.CheckID == "" {
+			check.check.CheckID = check.check.Name
+		}
+		if check.check.Status == "" {
+			check.check.Status = api.HealthCritical
+		}
+	}
+
+	return c.store.write(c.ctx, func() error {
+		s := c.store
+		n, ok := s.nodes[reg.Node]
+
+		if check != nil && check.check.ServiceID != "" {
+			svc := service
+			if svc == nil || svc.ID != check.check.ServiceID {
+				svc = nil
+				if ok {
+					svc = n.services[check.check.ServiceID]
+				}
+			}
+			if svc == nil {
+				return fmt.Errorf("Unexpected response code: 500 (Unknown service '%s' for check '%s')", check.check.ServiceID, check.check.CheckID)
+			}
+			check.check.ServiceName = svc.Service
+		}
+
+		if !ok {
+			n = &nodeState{
+				services: map[string]*api.AgentService{},
+				checks:   map[string]*checkState{},
+			}
+			s.nodes[reg.Node] = n
+		}
+		n.node = api.Node{Node: reg.Node, Address: reg.Address, TaggedAddresses: copyStringMap(reg.TaggedAddresses)}
+		s.bump(tableNodes)
+
+		if service != nil {
+			n.services[service.ID] = service
+			s.bump(tableServices)
+		}
+
+		if check != nil {
+			s.addCheck(n, check)
+		}
+		return nil
+	})
+}
+
+func (c *catalog) Deregister(dereg *api.CatalogDeregistration, q *api.WriteOptions) (*api.WriteMeta, error) {
+	if dereg.Node == "" {
+		return nil, fmt.Errorf("Unexpected response code: 400 (Must provide node)")
+	}
+
+	return c.store.write(c.ctx, func() error {
+		s := c.store
+		n, ok := s.nodes[dereg.Node]
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case dereg.ServiceID != "":
+			s.removeService(n, dereg.ServiceID)
+		case dereg.CheckID != "":
+			s.removeCheck(n, dereg.CheckID)
+		default:
+			s.removeNode(n)
+		}
+		return nil
+	})
+}
+
+func (c *catalog) WithContext(ctx context.Context) consuladapter.Catalog {
+	return &catalog{store: c.store, ctx: ctx}
+}
+
+// The catalog operations below must be called with the store mutex held.
+
+func (s *store) removeService(node *nodeState, serviceID string) {
+	if _, ok := node.services[serviceID]; !ok {
+		return
+	}
+
+	delete(node.services, serviceID)
+	s.bump(tableServices)
+
+	for id, check := range node.checks {
+		if check.check.ServiceID == serviceID {
+			s.removeCheck(node, id)
+		}
+	}
+}
+
+func (s *store) removeNode(node *nodeState) {
+	for id := range node.services {
+		s.removeService(node, id)
+	}
+	for id := range node.checks {
+		s.removeCheck(node, id)
+	}
+
+	for id, session := range s.sessions {
+		if session.entry.Node == node.node.Node {
+			s.invalidateSession(id)
+		}
+	}
+
+	delete(s.nodes, node.node.Node)
+	s.bump(tableNodes)
+}
+
+func copyNode(node *api.Node) *api.Node {
+	c := *node
+	c.TaggedAddresses = copyStringMap(node.TaggedAddresses)
+	return &c
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,166 @@
+package memconsul_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog", func() {
+	var (
+		client  *memconsul.Client
+		catalog consuladapter.Catalog
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		catalog = client.Catalog()
+
+		_, err := catalog.Register(&api.CatalogRegistration{
+			Node:    "external",
+			Address: "10.0.0.1",
+			Service: &api.AgentService{ID: "db-1", Service: "db", Tags: []string{"primary"}, Port: 5432},
+			Check:   &api.AgentCheck{Name: "db alive", ServiceID: "db-1", Status: api.HealthPassing},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+			ID:   "db-2",
+			Name: "db",
+			Tags: []string{"replica"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("lists nodes and services", func() {
+		nodes, _, err := catalog.Nodes(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(2))
+		Expect(nodes[0].Node).To(Equal("external"))
+		Expect(nodes[1].Node).To(Equal(memconsul.DefaultNodeName))
+
+		services, _, err := catalog.Services(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(Equal(map[string][]string{"db": {"primary", "replica"}}))
+	})
+
+	It("returns the instances of a service, optionally filtered by tag", func() {
+		instances, _, err := catalog.Service("db", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instances).To(HaveLen(2))
+		Expect(instances[0].Node).To(Equal("external"))
+		Expect(instances[0].Address).To(Equal("10.0.0.1"))
+		Expect(instances[0].ServicePort).To(Equal(5432))
+
+		instances, _, err = catalog.Service("db", "replica", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instances).To(HaveLen(1))
+		Expect(instances[0].ServiceID).To(Equal("db-2"))
+	})
+
+	It("returns a node with its services", func() {
+		node, _, err := catalog.Node("external", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Node.Address).To(Equal("10.0.0.1"))
+		Expect(node.Services).To(HaveKey("db-1"))
+
+		node, _, err = catalog.Node("missing", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node).To(BeNil())
+	})
+
+	It("reports its datacenter", func() {
+		Expect(catalog.Datacenters()).To(Equal([]string{memconsul.DefaultDatacenter}))
+	})
+
+	It("validates registrations", func() {
+		_, err := catalog.Register(&api.CatalogRegistration{Node: "n"}, nil)
+		Expect(err).To(HaveOccurred())
+
+		_, err = catalog.Register(&api.CatalogRegistration{
+			Node:    "n",
+			Address: "10.0.0.2",
+			Check:   &api.AgentCheck{Name: "orphan", ServiceID: "missing"},
+		}, nil)
+		Expect(err).To(HaveOccurred())
+
+		nodes, _, err := catalog.Nodes(nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, node := range nodes {
+			Expect(node.Node).NotTo(Equal("n"))
+		}
+
+		_, err = catalog.Register(&api.CatalogRegistration{
+			Node:    "external",
+			Address: "10.0.0.3",
+			Service: &api.AgentService{ID: "db-3", Service: "db"},
+			Check:   &api.AgentCheck{Name: "orphan", ServiceID: "missing"},
+		}, nil)
+		Expect(err).To(HaveOccurred())
+
+		services, _, err := catalog.Service("db", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, service := range services {
+			Expect(service.ServiceID).NotTo(Equal("db-3"))
+			Expect(service.Address).To(Or(Equal("10.0.0.1"), Equal("127.0.0.1")))
+		}
+	})
+
+	Describe("Deregister", func() {
+		It("removes a service along with its checks", func() {
+			_, err := catalog.Deregister(&api.CatalogDeregistration{Node: "external", ServiceID: "db-1"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			node, _, err := catalog.Node("external", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Services).To(BeEmpty())
+
+			checks, _, err := client.Health().Node("external", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checks).To(BeEmpty())
+		})
+
+		It("removes a whole node and invalidates its sessions", func() {
+			_, err := catalog.Register(&api.CatalogRegistration{
+				Node:    "external",
+				Address: "10.0.0.1",
+				Check:   &api.AgentCheck{CheckID: "serfHealth", Name: "Serf", Status: api.HealthPassing},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			id, _, err := client.Session().Create(&api.SessionEntry{Node: "external"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = catalog.Deregister(&api.CatalogDeregistration{Node: "external"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			node, _, err := catalog.Node("external", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node).To(BeNil())
+
+			session, _, err := client.Session().Info(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session).To(BeNil())
+		})
+	})
+
+	It("unblocks queries when a service is registered", func() {
+		_, meta, err := catalog.Service("web", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		result := make(chan []*api.CatalogService)
+		go func() {
+			defer GinkgoRecover()
+			instances, _, err := catalog.Service("web", "", &api.QueryOptions{WaitIndex: meta.LastIndex})
+			Expect(err).NotTo(HaveOccurred())
+			result <- instances
+		}()
+
+		Consistently(result).ShouldNot(Receive())
+		Expect(client.Agent().ServiceRegister(&api.AgentServiceRegistration{Name: "web"})).To(Succeed())
+		Eventually(result).Should(Receive(HaveLen(1)))
+	})
+})
@@ -0,0 +1,101 @@
+package memconsul
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	DefaultNodeName   = "memconsul"
+	DefaultDatacenter = "dc1"
+)
+
+// Client is an in-memory consuladapter.Client for unit tests. It keeps a
+// single agent node and implements the KV, session, lock and check semantics
+// of a real cluster, with TTLs measured by its Clock.
+//
+// Unlike Consul, sessions have no lock-delay unless one is requested
+// explicitly, and session TTLs are not subject to a minimum.
+type Client struct {
+	store   *store
+	session *session
+	ctx     context.Context
+}
+
+func NewClient() *Client {
+	return NewClientWithClock(realClock{})
+}
+
+func NewClientWithClock(clock Clock) *Client {
+	s := newStore(clock, DefaultNodeName, DefaultDatacenter)
+	return newClient(s, context.Background())
+}
+
+func newClient(s *store, ctx context.Context) *Client {
+	return &Client{
+		store:   s,
+		session: &session{store: s, ctx: ctx},
+		ctx:     ctx,
+	}
+}
+
+func (c *Client) Agent() consuladapter.Agent {
+	return &agent{store: c.store, ctx: c.ctx}
+}
+
+func (c *Client) Session() consuladapter.Session {
+	return c.session
+}
+
+func (c *Client) Catalog() consuladapter.Catalog {
+	return &catalog{store: c.store, ctx: c.ctx}
+}
+
+func (c *Client) Health() consuladapter.Health {
+	return &health{store: c.store, ctx: c.ctx}
+}
+
+func (c *Client) KV() consuladapter.KV {
+	return &keyValue{store: c.store, ctx: c.ctx}
+}
+
+func (c *Client) Status() consuladapter.Status {
+	return status{ctx: c.ctx}
+}
+
+func (c *Client) LockOpts(opts *api.LockOptions) (consuladapter.Lock, error) {
+	l, err := newLock(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (c *Client) SemaphoreOpts(opts *api.SemaphoreOptions) (consuladapter.Semaphore, error) {
+	sem, err := newSemaphore(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	return sem, nil
+}
+
+// SetRTT sets the estimated round trip time from the agent's node to node.
+// Health.Service sorts instances by it when asked for those near the agent,
+// with the agent's node first unless given another RTT and nodes without one
+// last.
+func (c *Client) SetRTT(node string, rtt time.Duration) {
+	c.store.mutex.Lock()
+	c.store.rtts[node] = rtt
+	c.store.mutex.Unlock()
+}
+
+// WithContext returns a Client sharing c's state whose calls, blocking queries
+// and locks are abandoned when ctx is done.
+func (c *Client) WithContext(ctx context.Context) consuladapter.Client {
+	return newClient(c.store, ctx)
+}
+
+var _ consuladapter.Client = new(Client)
@@ -0,0 +1,195 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type health struct {
+	store *store
+	ctx   context.Context
+}
+
+func (h *health) Node(node string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	var checks []*api.HealthCheck
+	meta, err := h.store.read(h.ctx, q, func() {
+		if n, ok := h.store.nodes[node]; ok {
+			checks = nodeChecks(n, func(*checkState) bool { return true })
+		}
+	}, tableChecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checks, meta, nil
+}
+
+func (h *health) Checks(service string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	var checks []*api.HealthCheck
+	meta, err := h.store.read(h.ctx, q, func() {
+		checks = h.store.healthChecks(func(c *checkState) bool {
+			return c.check.ServiceName == service
+		})
+	}, tableChecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checks, meta, nil
+}
+
+func (h *health) Service(service, tag string, passingOnly bool, q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	var entries []*api.ServiceEntry
+	meta, err := h.store.read(h.ctx, q, func() {
+		for _, name := range h.store.sortedNodeNames() {
+			n := h.store.nodes[name]
+			for _, id := range sortedServiceIDs(n) {
+				s := n.services[id]
+				if s.Service != service || (tag != "" && !hasTag(s.Tags, tag)) {
+					continue
+				}
+
+				checks := nodeChecks(n, func(c *checkState) bool {
+					return c.check.ServiceID == "" || c.check.ServiceID == id
+				})
+				if passingOnly && !allPassing(checks) {
+					continue
+				}
+
+				node := n.node
+				entries = append(entries, &api.ServiceEntry{
+					Node:    &node,
+					Service: copyService(s),
+					Checks:  checks,
+				})
+			}
+		}
+		if q != nil && (q.Near == "_agent" || q.Near == h.store.nodeName) {
+			h.store.sortNear(entries)
+		}
+	}, tableNodes, tableServices, tableChecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, meta, nil
+}
+
+// sortNear sorts entries by the RTT from the agent's node. It must be called
+// with the mutex held.
+func (s *store) sortNear(entries []*api.ServiceEntry) {
+	rtt := func(node string) (time.Duration, bool) {
+		rtt, ok := s.rtts[node]
+		if !ok && node == s.nodeName {
+			return 0, true
+		}
+		return rtt, ok
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, aok := rtt(entries[i].Node.Node)
+		b, bok := rtt(entries[j].Node.Node)
+		if aok != bok {
+			return aok
+		}
+		return a < b
+	})
+}
+
+func (h *health) State(state string, q *api.QueryOptions) ([]*api.HealthCheck, *api.QueryMeta, error) {
+	switch state {
+	case api.HealthAny, api.HealthPassing, api.HealthWarning, api.HealthCritical:
+	default:
+		return nil, nil, fmt.Errorf("Unsupported state: %v", state)
+	}
+
+	var checks []*api.HealthCheck
+	meta, err := h.store.read(h.ctx, q, func() {
+		checks = h.store.healthChecks(func(c *checkState) bool {
+			return state == api.HealthAny || c.check.Status == state
+		})
+	}, tableChecks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checks, meta, nil
+}
+
+func (h *health) WithContext(ctx context.Context) consuladapter.Health {
+	return &health{store: h.store, ctx: ctx}
+}
+
+// The health operations below must be called with the store mutex held.
+
+func (s *store) healthChecks(match func(*checkState) bool) []*api.HealthCheck {
+	var checks []*api.HealthCheck
+	for _, name := range s.sortedNodeNames() {
+		checks = append(checks, nodeChecks(s.nodes[name], match)...)
+	}
+	return checks
+}
+
+func (s *store) sortedNodeNames() []string {
+	names := make([]string, 0, len(s.nodes))
+	for name := range s.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func nodeChecks(n *nodeState, match func(*checkState) bool) []*api.HealthCheck {
+	ids := make([]string, 0, len(n.checks))
+	for id := range n.checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var checks []*api.HealthCheck
+	for _, id := range ids {
+		c := n.checks[id]
+		if !match(c) {
+			continue
+		}
+		checks = append(checks, &api.HealthCheck{
+			Node:        n.node.Node,
+			CheckID:     c.check.CheckID,
+			Name:        c.check.Name,
+			Status:      c.check.Status,
+			Notes:       c.check.Notes,
+			Output:      c.check.Output,
+			ServiceID:   c.check.ServiceID,
+			ServiceName: c.check.ServiceName,
+		})
+	}
+	return checks
+}
+
+func sortedServiceIDs(n *nodeState) []string {
+	ids := make([]string, 0, len(n.services))
+	for id := range n.services {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func allPassing(checks []*api.HealthCheck) bool {
+	for _, c := range checks {
+		if c.Status != api.HealthPassing {
+			return false
+		}
+	}
+	return true
+}
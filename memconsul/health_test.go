@@ -0,0 +1,131 @@
+package memconsul_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		agent  consuladapter.Agent
+		health consuladapter.Health
+	)
+
+	BeforeEach(func() {
+		client := memconsul.NewClient()
+		agent = client.Agent()
+		health = client.Health()
+
+		for _, id := range []string{"web-1", "web-2"} {
+			err := agent.ServiceRegister(&api.AgentServiceRegistration{
+				ID:    id,
+				Name:  "web",
+				Tags:  []string{id},
+				Check: &api.AgentServiceCheck{TTL: "1m"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(agent.PassTTL("service:web-1", "")).To(Succeed())
+	})
+
+	It("returns the checks of a node", func() {
+		checks, _, err := health.Node(memconsul.DefaultNodeName, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveLen(3))
+		Expect(checks[0].CheckID).To(Equal("serfHealth"))
+		Expect(checks[0].Node).To(Equal(memconsul.DefaultNodeName))
+
+		checks, _, err = health.Node("missing", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(BeEmpty())
+	})
+
+	It("returns the checks of a service", func() {
+		checks, _, err := health.Checks("web", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks).To(HaveLen(2))
+		Expect(checks[0].ServiceID).To(Equal("web-1"))
+		Expect(checks[1].ServiceID).To(Equal("web-2"))
+	})
+
+	Describe("Service", func() {
+		It("returns every instance with its node and service checks", func() {
+			entries, _, err := health.Service("web", "", false, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Node.Node).To(Equal(memconsul.DefaultNodeName))
+			Expect(entries[0].Service.ID).To(Equal("web-1"))
+
+			var ids []string
+			for _, check := range entries[0].Checks {
+				ids = append(ids, check.CheckID)
+			}
+			Expect(ids).To(ConsistOf("serfHealth", "service:web-1"))
+		})
+
+		It("filters by tag", func() {
+			entries, _, err := health.Service("web", "web-2", false, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Service.ID).To(Equal("web-2"))
+		})
+
+		It("returns only passing instances when asked", func() {
+			entries, _, err := health.Service("web", "", true, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Service.ID).To(Equal("web-1"))
+		})
+
+		It("sorts instances by their RTT from the agent when asked", func() {
+			client := memconsul.NewClient()
+			for _, node := range []string{"cell-1", "cell-2", "cell-3"} {
+				_, err := client.Catalog().Register(&api.CatalogRegistration{
+					Node:    node,
+					Address: "10.0.0.1",
+					Service: &api.AgentService{ID: "db", Service: "db"},
+				}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			client.SetRTT("cell-1", 30*time.Millisecond)
+			client.SetRTT("cell-2", 10*time.Millisecond)
+
+			nodes := func(q *api.QueryOptions) []string {
+				entries, _, err := client.Health().Service("db", "", false, q)
+				Expect(err).NotTo(HaveOccurred())
+				var nodes []string
+				for _, entry := range entries {
+					nodes = append(nodes, entry.Node.Node)
+				}
+				return nodes
+			}
+
+			Expect(nodes(nil)).To(Equal([]string{"cell-1", "cell-2", "cell-3"}))
+			Expect(nodes(&api.QueryOptions{Near: "_agent"})).To(Equal([]string{"cell-2", "cell-1", "cell-3"}))
+		})
+	})
+
+	Describe("State", func() {
+		It("returns the checks in the given state", func() {
+			checks, _, err := health.State(api.HealthCritical, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checks).To(HaveLen(1))
+			Expect(checks[0].CheckID).To(Equal("service:web-2"))
+
+			checks, _, err = health.State(api.HealthAny, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checks).To(HaveLen(3))
+		})
+
+		It("rejects unknown states", func() {
+			_, _, err := health.State("sad", nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,258 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type keyValue struct {
+	store *store
+	ctx   context.Context
+}
+
+func (kv *keyValue) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	var pair *api.KVPair
+	meta, err := kv.store.read(kv.ctx, q, func() {
+		pair = copyPair(kv.store.kvs[key])
+	}, tableKVs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, meta, nil
+}
+
+func (kv *keyValue) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var pairs api.KVPairs
+	meta, err := kv.store.read(kv.ctx, q, func() {
+		for _, key := range kv.store.sortedKeys(prefix) {
+			pairs = append(pairs, copyPair(kv.store.kvs[key]))
+		}
+	}, tableKVs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pairs, meta, nil
+}
+
+func (kv *keyValue) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	var keys []string
+	meta, err := kv.store.read(kv.ctx, q, func() {
+		keys = kv.store.keys(prefix, separator)
+	}, tableKVs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, meta, nil
+}
+
+func (kv *keyValue) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return kv.store.write(kv.ctx, func() error {
+		kv.store.put(p)
+		return nil
+	})
+}
+
+func (kv *keyValue) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	var ok bool
+	meta, err := kv.store.write(kv.ctx, func() error {
+		ok = kv.store.cas(p)
+		return nil
+	})
+	return ok, meta, err
+}
+
+func (kv *keyValue) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	var acquired bool
+	meta, err := kv.store.write(kv.ctx, func() error {
+		var err error
+		acquired, err = kv.store.acquire(p)
+		return err
+	})
+	return acquired, meta, err
+}
+
+func (kv *keyValue) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	var released bool
+	meta, err := kv.store.write(kv.ctx, func() error {
+		released = kv.store.release(p)
+		return nil
+	})
+	return released, meta, err
+}
+
+func (kv *keyValue) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return kv.store.write(kv.ctx, func() error {
+		kv.store.delete(key)
+		return nil
+	})
+}
+
+func (kv *keyValue) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	var ok bool
+	meta, err := kv.store.write(kv.ctx, func() error {
+		ok = kv.store.deleteCAS(p)
+		return nil
+	})
+	return ok, meta, err
+}
+
+func (kv *keyValue) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	return kv.store.write(kv.ctx, func() error {
+		kv.store.deleteTree(prefix)
+		return nil
+	})
+}
+
+func (kv *keyValue) WithContext(ctx context.Context) consuladapter.KV {
+	return &keyValue{store: kv.store, ctx: ctx}
+}
+
+// The KV operations below must be called with the store mutex held.
+
+func (s *store) put(p *api.KVPair) {
+	index := s.bump(tableKVs)
+
+	existing, ok := s.kvs[p.Key]
+	if !ok {
+		s.kvs[p.Key] = &api.KVPair{
+			Key:         p.Key,
+			CreateIndex: index,
+			ModifyIndex: index,
+			Flags:       p.Flags,
+			Value:       append([]byte{}, p.Value...),
+		}
+		return
+	}
+
+	existing.ModifyIndex = index
+	existing.Flags = p.Flags
+	existing.Value = append([]byte{}, p.Value...)
+}
+
+// cas writes p only if the stored ModifyIndex matches p.ModifyIndex. An index
+// of 0 only succeeds when the key does not exist.
+func (s *store) cas(p *api.KVPair) bool {
+	existing, ok := s.kvs[p.Key]
+	if p.ModifyIndex == 0 && ok {
+		return false
+	}
+	if p.ModifyIndex != 0 && (!ok || existing.ModifyIndex != p.ModifyIndex) {
+		return false
+	}
+
+	s.put(p)
+	return true
+}
+
+func (s *store) acquire(p *api.KVPair) (bool, error) {
+	if _, ok := s.sessions[p.Session]; !ok {
+		return false, fmt.Errorf("Unexpected response code: 500 (invalid session %q)", p.Session)
+	}
+
+	if until, ok := s.lockDelays[p.Key]; ok {
+		if s.clock.Now().Before(until) {
+			return false, nil
+		}
+		delete(s.lockDelays, p.Key)
+	}
+
+	existing, ok := s.kvs[p.Key]
+	if ok && existing.Session != "" && existing.Session != p.Session {
+		return false, nil
+	}
+
+	index := s.bump(tableKVs)
+	if !ok {
+		s.kvs[p.Key] = &api.KVPair{
+			Key:         p.Key,
+			CreateIndex: index,
+			ModifyIndex: index,
+			LockIndex:   1,
+			Flags:       p.Flags,
+			Value:       append([]byte{}, p.Value...),
+			Session:     p.Session,
+		}
+		return true, nil
+	}
+
+	if existing.Session != p.Session {
+		existing.LockIndex++
+		existing.Session = p.Session
+	}
+	existing.ModifyIndex = index
+	existing.Flags = p.Flags
+	existing.Value = append([]byte{}, p.Value...)
+	return true, nil
+}
+
+func (s *store) release(p *api.KVPair) bool {
+	existing, ok := s.kvs[p.Key]
+	if !ok || existing.Session == "" || existing.Session != p.Session {
+		return false
+	}
+
+	existing.ModifyIndex = s.bump(tableKVs)
+	existing.Session = ""
+	existing.Flags = p.Flags
+	existing.Value = append([]byte{}, p.Value...)
+	return true
+}
+
+func (s *store) delete(key string) {
+	if _, ok := s.kvs[key]; !ok {
+		return
+	}
+
+	delete(s.kvs, key)
+	s.bump(tableKVs)
+}
+
+// deleteCAS deletes the key only if the stored ModifyIndex matches
+// p.ModifyIndex. Like Consul, it succeeds when the key does not exist.
+func (s *store) deleteCAS(p *api.KVPair) bool {
+	existing, ok := s.kvs[p.Key]
+	if !ok {
+		return true
+	}
+	if existing.ModifyIndex != p.ModifyIndex {
+		return false
+	}
+
+	s.delete(p.Key)
+	return true
+}
+
+// keys lists the keys under prefix. With a separator, keys are truncated
+// after the first separator following the prefix and deduplicated.
+func (s *store) keys(prefix, separator string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, key := range s.sortedKeys(prefix) {
+		if separator != "" {
+			if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+				key = key[:len(prefix)+i+len(separator)]
+			}
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *store) deleteTree(prefix string) {
+	keys := s.sortedKeys(prefix)
+	if len(keys) == 0 {
+		return
+	}
+
+	for _, key := range keys {
+		delete(s.kvs, key)
+	}
+	s.bump(tableKVs)
+}
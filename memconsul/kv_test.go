@@ -0,0 +1,288 @@
+package memconsul_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KV", func() {
+	var (
+		clock  *memconsul.FakeClock
+		client *memconsul.Client
+		kv     consuladapter.KV
+	)
+
+	BeforeEach(func() {
+		clock = memconsul.NewFakeClock(time.Now())
+		client = memconsul.NewClientWithClock(clock)
+		kv = client.KV()
+	})
+
+	It("returns nil for a missing key", func() {
+		pair, meta, err := kv.Get("missing", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).To(BeNil())
+		Expect(meta.LastIndex).To(BeNumerically(">", 0))
+	})
+
+	It("tracks create and modify indexes", func() {
+		_, err := kv.Put(&api.KVPair{Key: "foo", Value: []byte("one"), Flags: 42}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		created, _, err := kv.Get("foo", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created.Value).To(Equal([]byte("one")))
+		Expect(created.Flags).To(BeEquivalentTo(42))
+		Expect(created.CreateIndex).To(Equal(created.ModifyIndex))
+
+		_, err = kv.Put(&api.KVPair{Key: "foo", Value: []byte("two")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		modified, _, err := kv.Get("foo", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(modified.Value).To(Equal([]byte("two")))
+		Expect(modified.CreateIndex).To(Equal(created.CreateIndex))
+		Expect(modified.ModifyIndex).To(BeNumerically(">", created.ModifyIndex))
+	})
+
+	It("lists and deletes by prefix in key order", func() {
+		for _, key := range []string{"a/2", "a/1", "b/1"} {
+			_, err := kv.Put(&api.KVPair{Key: key}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		pairs, _, err := kv.List("a/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(2))
+		Expect(pairs[0].Key).To(Equal("a/1"))
+		Expect(pairs[1].Key).To(Equal("a/2"))
+
+		_, err = kv.DeleteTree("a/", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pairs, _, err = kv.List("", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(1))
+		Expect(pairs[0].Key).To(Equal("b/1"))
+	})
+
+	It("does not share values with callers", func() {
+		value := []byte("value")
+		_, err := kv.Put(&api.KVPair{Key: "foo", Value: value}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		value[0] = 'X'
+
+		pair, _, err := kv.Get("foo", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("value")))
+	})
+
+	Describe("blocking queries", func() {
+		It("blocks until the index moves past WaitIndex", func() {
+			_, meta, err := kv.Get("foo", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			result := make(chan *api.KVPair)
+			go func() {
+				defer GinkgoRecover()
+				pair, _, err := kv.Get("foo", &api.QueryOptions{WaitIndex: meta.LastIndex})
+				Expect(err).NotTo(HaveOccurred())
+				result <- pair
+			}()
+
+			Consistently(result).ShouldNot(Receive())
+
+			_, err = kv.Put(&api.KVPair{Key: "foo", Value: []byte("bar")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var pair *api.KVPair
+			Eventually(result).Should(Receive(&pair))
+			Expect(pair.Value).To(Equal([]byte("bar")))
+		})
+
+		It("returns once the wait time has elapsed on the clock", func() {
+			_, meta, err := kv.List("", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			done := make(chan *api.QueryMeta)
+			go func() {
+				defer GinkgoRecover()
+				_, meta, err := kv.List("", &api.QueryOptions{WaitIndex: meta.LastIndex, WaitTime: time.Second})
+				Expect(err).NotTo(HaveOccurred())
+				done <- meta
+			}()
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			Consistently(done).ShouldNot(Receive())
+
+			clock.Increment(time.Second)
+
+			var timedOut *api.QueryMeta
+			Eventually(done).Should(Receive(&timedOut))
+			Expect(timedOut.LastIndex).To(Equal(meta.LastIndex))
+		})
+
+		It("returns the context error when the context is done", func() {
+			_, meta, err := kv.List("", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error)
+			go func() {
+				_, _, err := kv.WithContext(ctx).List("", &api.QueryOptions{WaitIndex: meta.LastIndex})
+				errs <- err
+			}()
+
+			Consistently(errs).ShouldNot(Receive())
+			cancel()
+			Eventually(errs).Should(Receive(Equal(context.Canceled)))
+
+			_, _, err = kv.List("", nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Release", func() {
+		It("releases only keys held by the given session", func() {
+			sessionID, _, err := client.Session().CreateNoChecks(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			lock, err := client.LockOpts(&api.LockOptions{Key: "lock", Session: sessionID})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			released, _, err := kv.Release(&api.KVPair{Key: "lock", Session: "other"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeFalse())
+
+			released, _, err = kv.Release(&api.KVPair{Key: "lock", Session: sessionID}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeTrue())
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Session).To(BeEmpty())
+		})
+	})
+
+	Describe("CAS", func() {
+		It("only creates a missing key with index 0", func() {
+			ok, _, err := kv.CAS(&api.KVPair{Key: "key", Value: []byte("a")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			ok, _, err = kv.CAS(&api.KVPair{Key: "key", Value: []byte("b")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("only updates when the modify index matches", func() {
+			_, err := kv.Put(&api.KVPair{Key: "key", Value: []byte("a")}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			pair, _, err := kv.Get("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ok, _, err := kv.CAS(&api.KVPair{Key: "key", Value: []byte("b"), ModifyIndex: pair.ModifyIndex + 1}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			ok, _, err = kv.CAS(&api.KVPair{Key: "key", Value: []byte("b"), ModifyIndex: pair.ModifyIndex}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			pair, _, err = kv.Get("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Value).To(Equal([]byte("b")))
+		})
+	})
+
+	Describe("Acquire", func() {
+		It("acquires a key for a session until released", func() {
+			sessionID, _, err := client.Session().CreateNoChecks(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			otherID, _, err := client.Session().CreateNoChecks(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, _, err := kv.Acquire(&api.KVPair{Key: "key", Session: sessionID}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+
+			acquired, _, err = kv.Acquire(&api.KVPair{Key: "key", Session: otherID}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+		})
+
+		It("fails for an unknown session", func() {
+			_, _, err := kv.Acquire(&api.KVPair{Key: "key", Session: "missing"}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Delete", func() {
+		It("deletes a single key", func() {
+			_, err := kv.Put(&api.KVPair{Key: "key"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = kv.Put(&api.KVPair{Key: "key/child"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = kv.Delete("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			keys, _, err := kv.Keys("", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{"key/child"}))
+		})
+
+		It("deletes with check-and-set", func() {
+			_, err := kv.Put(&api.KVPair{Key: "key"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			pair, _, err := kv.Get("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			ok, _, err := kv.DeleteCAS(&api.KVPair{Key: "key", ModifyIndex: pair.ModifyIndex - 1}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			ok, _, err = kv.DeleteCAS(&api.KVPair{Key: "key", ModifyIndex: pair.ModifyIndex}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			pair, _, err = kv.Get("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+	})
+
+	Describe("Keys", func() {
+		BeforeEach(func() {
+			for _, key := range []string{"a/1", "a/b/2", "a/b/3", "c"} {
+				_, err := kv.Put(&api.KVPair{Key: key}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("lists keys under a prefix", func() {
+			keys, _, err := kv.Keys("a/", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a/1", "a/b/2", "a/b/3"}))
+		})
+
+		It("stops at the separator", func() {
+			keys, _, err := kv.Keys("a/", "/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a/1", "a/b/"}))
+
+			keys, _, err = kv.Keys("", "/", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a/", "c"}))
+		})
+	})
+})
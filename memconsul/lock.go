@@ -0,0 +1,234 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/internal/acquire"
+	"github.com/hashicorp/consul/api"
+)
+
+// lock follows the algorithm of api.Lock against the in-memory store.
+type lock struct {
+	client *Client
+	opts   api.LockOptions
+
+	isHeld       bool
+	sessionRenew chan struct{}
+	lockSession  string
+
+	mutex sync.Mutex
+}
+
+func newLock(c *Client, opts *api.LockOptions) (*lock, error) {
+	if opts.Key == "" {
+		return nil, fmt.Errorf("missing key")
+	}
+
+	o := *opts
+	if o.SessionName == "" {
+		o.SessionName = api.DefaultLockSessionName
+	}
+	if o.SessionTTL == "" {
+		o.SessionTTL = api.DefaultLockSessionTTL
+	} else if _, err := time.ParseDuration(o.SessionTTL); err != nil {
+		return nil, fmt.Errorf("invalid SessionTTL: %v", err)
+	}
+	if o.LockWaitTime == 0 {
+		o.LockWaitTime = api.DefaultLockWaitTime
+	}
+
+	return &lock{client: c, opts: o}, nil
+}
+
+func (l *lock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.isHeld {
+		return nil, api.ErrLockHeld
+	}
+
+	l.lockSession = l.opts.Session
+	if l.lockSession == "" {
+		id, _, err := l.client.session.Create(&api.SessionEntry{
+			Name: l.opts.SessionName,
+			TTL:  l.opts.SessionTTL,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session: %v", err)
+		}
+
+		l.sessionRenew = make(chan struct{})
+		l.lockSession = id
+		go l.client.session.RenewPeriodic(l.opts.SessionTTL, id, nil, l.sessionRenew)
+
+		defer func() {
+			if !l.isHeld {
+				close(l.sessionRenew)
+				l.sessionRenew = nil
+			}
+		}()
+	}
+
+	var timeout <-chan time.Time
+	if l.opts.LockTryOnce {
+		timeout = l.client.store.clock.After(l.opts.LockWaitTime)
+	}
+
+	ctx := l.client.ctx
+	s := l.client.store
+	entry := l.lockEntry(l.lockSession)
+	for {
+		select {
+		case <-stopCh:
+			return nil, nil
+		default:
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read lock: %v", err)
+		}
+
+		s.mutex.Lock()
+		s.expire()
+
+		pair := s.kvs[l.opts.Key]
+		if pair != nil && pair.Flags != api.LockFlagValue {
+			s.mutex.Unlock()
+			return nil, api.ErrLockConflict
+		}
+
+		acquired := pair != nil && pair.Session == l.lockSession
+		if !acquired && (pair == nil || pair.Session == "") {
+			var err error
+			acquired, err = s.acquire(entry)
+			if err != nil {
+				s.mutex.Unlock()
+				return nil, fmt.Errorf("failed to acquire lock: %v", err)
+			}
+		}
+
+		if acquired {
+			s.mutex.Unlock()
+			break
+		}
+
+		var retry <-chan time.Time
+		if until, ok := s.lockDelays[l.opts.Key]; ok {
+			retry = s.clock.After(until.Sub(s.clock.Now()))
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-retry:
+		case <-timeout:
+			return nil, nil
+		case <-stopCh:
+			return nil, nil
+		case <-ctx.Done():
+		}
+	}
+
+	leaderCh := make(chan struct{})
+	go l.monitorLock(l.lockSession, leaderCh)
+
+	l.isHeld = true
+	return leaderCh, nil
+}
+
+func (l *lock) LockContext(ctx context.Context) (<-chan struct{}, error) {
+	return acquire.UntilDone(ctx, l.Lock)
+}
+
+func (l *lock) Unlock() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.isHeld {
+		return consuladapter.NewLockNotHeldError(l.opts.Key)
+	}
+	l.isHeld = false
+
+	if l.sessionRenew != nil {
+		defer func() {
+			close(l.sessionRenew)
+			l.sessionRenew = nil
+		}()
+	}
+
+	entry := l.lockEntry(l.lockSession)
+	l.lockSession = ""
+
+	_, _, err := l.client.KV().Release(entry, nil)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %v", err)
+	}
+	return nil
+}
+
+func (l *lock) Destroy() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.isHeld {
+		return consuladapter.NewLockInUseError(l.opts.Key)
+	}
+
+	_, err := l.client.store.write(l.client.ctx, func() error {
+		s := l.client.store
+		pair, ok := s.kvs[l.opts.Key]
+		if !ok {
+			return nil
+		}
+		if pair.Flags != api.LockFlagValue {
+			return api.ErrLockConflict
+		}
+		if pair.Session != "" {
+			return consuladapter.NewLockInUseError(l.opts.Key)
+		}
+
+		delete(s.kvs, l.opts.Key)
+		s.bump(tableKVs)
+		return nil
+	})
+	return err
+}
+
+func (l *lock) lockEntry(session string) *api.KVPair {
+	return &api.KVPair{
+		Key:     l.opts.Key,
+		Value:   l.opts.Value,
+		Session: session,
+		Flags:   api.LockFlagValue,
+	}
+}
+
+// monitorLock closes stopCh once the key is no longer held by session, or the
+// client context is done.
+func (l *lock) monitorLock(session string, stopCh chan struct{}) {
+	defer close(stopCh)
+
+	s := l.client.store
+	for {
+		s.mutex.Lock()
+		s.expire()
+		pair := s.kvs[l.opts.Key]
+		if pair == nil || pair.Session != session {
+			s.mutex.Unlock()
+			return
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-l.client.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package memconsul_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock", func() {
+	var (
+		clock  *memconsul.FakeClock
+		client *memconsul.Client
+	)
+
+	BeforeEach(func() {
+		clock = memconsul.NewFakeClock(time.Now())
+		client = memconsul.NewClientWithClock(clock)
+	})
+
+	It("requires a key", func() {
+		_, err := client.LockOpts(&api.LockOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("acquires the key with the lock value and flags", func() {
+		lock, err := client.LockOpts(&api.LockOptions{Key: "lock", Value: []byte("me")})
+		Expect(err).NotTo(HaveOccurred())
+
+		lostCh, err := lock.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lostCh).NotTo(BeNil())
+
+		pair, _, err := client.KV().Get("lock", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("me")))
+		Expect(pair.Flags).To(BeEquivalentTo(uint64(api.LockFlagValue)))
+		Expect(pair.Session).NotTo(BeEmpty())
+
+		_, err = lock.Lock(nil)
+		Expect(err).To(Equal(api.ErrLockHeld))
+	})
+
+	It("refuses keys that are not used as locks", func() {
+		_, err := client.KV().Put(&api.KVPair{Key: "lock"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = lock.Lock(nil)
+		Expect(err).To(Equal(api.ErrLockConflict))
+	})
+
+	It("waits for the holder to lose the lock", func() {
+		holder, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+		holderLost, err := holder.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := client.KV().Get("lock", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		contender, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+
+		acquired := make(chan (<-chan struct{}))
+		go func() {
+			defer GinkgoRecover()
+			lostCh, err := contender.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			acquired <- lostCh
+		}()
+
+		Consistently(acquired).ShouldNot(Receive())
+
+		_, err = client.Session().Destroy(pair.Session, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(holderLost).Should(BeClosed())
+		Eventually(acquired).Should(Receive())
+	})
+
+	It("stops waiting when stopCh is closed", func() {
+		holder, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = holder.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		contender, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+
+		stopCh := make(chan struct{})
+		result := make(chan (<-chan struct{}))
+		go func() {
+			defer GinkgoRecover()
+			lostCh, err := contender.Lock(stopCh)
+			Expect(err).NotTo(HaveOccurred())
+			result <- lostCh
+		}()
+
+		close(stopCh)
+		Eventually(result).Should(Receive(BeNil()))
+	})
+
+	It("stops waiting with the context error when the context is done", func() {
+		holder, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = holder.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		contender, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			lostCh, err := contender.LockContext(ctx)
+			Expect(lostCh).To(BeNil())
+			errs <- err
+		}()
+
+		Consistently(errs).ShouldNot(Receive())
+		cancel()
+		Eventually(errs).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("loses the lock when the context of its client is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		lock, err := client.WithContext(ctx).LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+		lostCh, err := lock.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Consistently(lostCh).ShouldNot(BeClosed())
+		cancel()
+		Eventually(lostCh).Should(BeClosed())
+	})
+
+	It("loses the lock when its session TTL elapses", func() {
+		sessionID, _, err := client.Session().CreateNoChecks(&api.SessionEntry{TTL: "10s"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err := client.LockOpts(&api.LockOptions{Key: "lock", Session: sessionID})
+		Expect(err).NotTo(HaveOccurred())
+		lostCh, err := lock.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Consistently(lostCh).ShouldNot(BeClosed())
+		clock.Increment(10 * time.Second)
+		Eventually(lostCh).Should(BeClosed())
+	})
+
+	It("honors the lock delay of the previous holder", func() {
+		sessionID, _, err := client.Session().CreateNoChecks(&api.SessionEntry{LockDelay: 5 * time.Second}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		holder, err := client.LockOpts(&api.LockOptions{Key: "lock", Session: sessionID})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = holder.Lock(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Session().Destroy(sessionID, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		contender, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+		Expect(err).NotTo(HaveOccurred())
+
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := contender.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			close(acquired)
+		}()
+
+		Consistently(acquired).ShouldNot(BeClosed())
+		clock.Increment(5 * time.Second)
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	Describe("Unlock", func() {
+		It("releases the key and stops renewing its session", func() {
+			lock, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			lostCh, err := lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.Unlock()).To(Succeed())
+			Eventually(lostCh).Should(BeClosed())
+
+			pair, _, err := client.KV().Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Session).To(BeEmpty())
+
+			Eventually(func() ([]*api.SessionEntry, error) {
+				sessions, _, err := client.Session().List(nil)
+				return sessions, err
+			}).Should(BeEmpty())
+		})
+
+		It("returns a LockNotHeldError when the lock is not held", func() {
+			lock, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = lock.Unlock()
+			Expect(err).To(Equal(consuladapter.NewLockNotHeldError("lock")))
+			Expect(errors.Is(err, api.ErrLockNotHeld)).To(BeTrue())
+		})
+	})
+
+	Describe("Destroy", func() {
+		It("deletes a released lock key", func() {
+			lock, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Unlock()).To(Succeed())
+
+			Expect(lock.Destroy()).To(Succeed())
+
+			pair, _, err := client.KV().Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+
+		It("returns a LockInUseError while the lock is held", func() {
+			holder, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = holder.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(holder.Destroy()).To(Equal(consuladapter.NewLockInUseError("lock")))
+
+			other, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			err = other.Destroy()
+			Expect(err).To(Equal(consuladapter.NewLockInUseError("lock")))
+			Expect(errors.Is(err, api.ErrLockInUse)).To(BeTrue())
+		})
+
+		It("succeeds when the key does not exist", func() {
+			lock, err := client.LockOpts(&api.LockOptions{Key: "lock"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Destroy()).To(Succeed())
+		})
+	})
+})
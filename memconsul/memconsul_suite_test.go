@@ -0,0 +1,13 @@
+package memconsul_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMemconsul(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Memconsul Suite")
+}
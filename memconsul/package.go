@@ -0,0 +1 @@
+package memconsul // import "code.cloudfoundry.org/consuladapter/memconsul"
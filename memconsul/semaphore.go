@@ -0,0 +1,307 @@
+package memconsul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/internal/acquire"
+	"github.com/hashicorp/consul/api"
+)
+
+// semaphore follows the algorithm of api.Semaphore against the in-memory
+// store. Its coordination key has the same layout, so it interoperates with
+// anything reading the prefix.
+type semaphore struct {
+	client *Client
+	opts   api.SemaphoreOptions
+
+	isHeld       bool
+	sessionRenew chan struct{}
+	lockSession  string
+
+	mutex sync.Mutex
+}
+
+type semaphoreLock struct {
+	Limit   int
+	Holders map[string]bool
+}
+
+func newSemaphore(c *Client, opts *api.SemaphoreOptions) (*semaphore, error) {
+	if opts.Prefix == "" {
+		return nil, fmt.Errorf("missing prefix")
+	}
+	if opts.Limit <= 0 {
+		return nil, fmt.Errorf("semaphore limit must be positive")
+	}
+
+	o := *opts
+	if o.SessionName == "" {
+		o.SessionName = api.DefaultSemaphoreSessionName
+	}
+	if o.SessionTTL == "" {
+		o.SessionTTL = api.DefaultSemaphoreSessionTTL
+	} else if _, err := time.ParseDuration(o.SessionTTL); err != nil {
+		return nil, fmt.Errorf("invalid SessionTTL: %v", err)
+	}
+	if o.SemaphoreWaitTime == 0 {
+		o.SemaphoreWaitTime = api.DefaultSemaphoreWaitTime
+	}
+
+	return &semaphore{client: c, opts: o}, nil
+}
+
+func (sem *semaphore) Acquire(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	sem.mutex.Lock()
+	defer sem.mutex.Unlock()
+
+	if sem.isHeld {
+		return nil, api.ErrSemaphoreHeld
+	}
+
+	sem.lockSession = sem.opts.Session
+	if sem.lockSession == "" {
+		id, _, err := sem.client.session.Create(&api.SessionEntry{
+			Name:     sem.opts.SessionName,
+			TTL:      sem.opts.SessionTTL,
+			Behavior: api.SessionBehaviorDelete,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session: %v", err)
+		}
+
+		sem.sessionRenew = make(chan struct{})
+		sem.lockSession = id
+		go sem.client.session.RenewPeriodic(sem.opts.SessionTTL, id, nil, sem.sessionRenew)
+
+		defer func() {
+			if !sem.isHeld {
+				close(sem.sessionRenew)
+				sem.sessionRenew = nil
+			}
+		}()
+	}
+
+	s := sem.client.store
+	_, err := s.write(sem.client.ctx, func() error {
+		made, err := s.acquire(sem.contenderEntry(sem.lockSession))
+		if err == nil && !made {
+			err = fmt.Errorf("contender entry is held by another session")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to make contender entry: %v", err)
+	}
+
+	var timeout <-chan time.Time
+	if sem.opts.SemaphoreTryOnce {
+		timeout = s.clock.After(sem.opts.SemaphoreWaitTime)
+	}
+
+	ctx := sem.client.ctx
+	for {
+		select {
+		case <-stopCh:
+			return nil, nil
+		default:
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read prefix: %v", err)
+		}
+
+		s.mutex.Lock()
+		s.expire()
+
+		_, lock, err := sem.readLock()
+		if err != nil {
+			s.mutex.Unlock()
+			return nil, err
+		}
+		if lock.Limit != sem.opts.Limit {
+			s.mutex.Unlock()
+			return nil, fmt.Errorf("semaphore limit conflict (lock: %d, local: %d)", lock.Limit, sem.opts.Limit)
+		}
+
+		if len(lock.Holders) < lock.Limit {
+			lock.Holders[sem.lockSession] = true
+			err := sem.writeLock(lock)
+			s.mutex.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return nil, nil
+		case <-stopCh:
+			return nil, nil
+		case <-ctx.Done():
+		}
+	}
+
+	lostSlot := make(chan struct{})
+	go sem.monitorSlot(sem.lockSession, lostSlot)
+
+	sem.isHeld = true
+	return lostSlot, nil
+}
+
+func (sem *semaphore) AcquireContext(ctx context.Context) (<-chan struct{}, error) {
+	return acquire.UntilDone(ctx, sem.Acquire)
+}
+
+func (sem *semaphore) Release() error {
+	sem.mutex.Lock()
+	defer sem.mutex.Unlock()
+
+	if !sem.isHeld {
+		return consuladapter.NewSemaphoreNotHeldError(sem.opts.Prefix)
+	}
+	sem.isHeld = false
+
+	if sem.sessionRenew != nil {
+		defer func() {
+			close(sem.sessionRenew)
+			sem.sessionRenew = nil
+		}()
+	}
+
+	session := sem.lockSession
+	sem.lockSession = ""
+
+	s := sem.client.store
+	_, err := s.write(sem.client.ctx, func() error {
+		_, lock, err := sem.readLock()
+		if err != nil {
+			return err
+		}
+		if lock.Holders[session] {
+			delete(lock.Holders, session)
+			if err := sem.writeLock(lock); err != nil {
+				return err
+			}
+		}
+
+		s.delete(path.Join(sem.opts.Prefix, session))
+		return nil
+	})
+	return err
+}
+
+func (sem *semaphore) Destroy() error {
+	sem.mutex.Lock()
+	defer sem.mutex.Unlock()
+
+	if sem.isHeld {
+		return consuladapter.NewSemaphoreInUseError(sem.opts.Prefix)
+	}
+
+	s := sem.client.store
+	_, err := s.write(sem.client.ctx, func() error {
+		lockPair, lock, err := sem.readLock()
+		if err != nil {
+			return err
+		}
+		if lockPair == nil {
+			return nil
+		}
+		if len(lock.Holders) > 0 {
+			return consuladapter.NewSemaphoreInUseError(sem.opts.Prefix)
+		}
+
+		s.delete(lockPair.Key)
+		return nil
+	})
+	return err
+}
+
+func (sem *semaphore) contenderEntry(session string) *api.KVPair {
+	return &api.KVPair{
+		Key:     path.Join(sem.opts.Prefix, session),
+		Value:   sem.opts.Value,
+		Session: session,
+		Flags:   api.SemaphoreFlagValue,
+	}
+}
+
+// readLock decodes the coordination key and prunes holders whose contender
+// entry is gone. The pair is nil if the key does not exist. It must be called
+// with the store mutex held.
+func (sem *semaphore) readLock() (*api.KVPair, *semaphoreLock, error) {
+	s := sem.client.store
+	lockPair := s.kvs[path.Join(sem.opts.Prefix, api.DefaultSemaphoreKey)]
+
+	lock := &semaphoreLock{Limit: sem.opts.Limit, Holders: map[string]bool{}}
+	if lockPair != nil {
+		if lockPair.Flags != api.SemaphoreFlagValue {
+			return nil, nil, api.ErrSemaphoreConflict
+		}
+		if lockPair.Value != nil {
+			lock = &semaphoreLock{}
+			if err := json.Unmarshal(lockPair.Value, lock); err != nil {
+				return nil, nil, fmt.Errorf("lock decoding failed: %v", err)
+			}
+		}
+	}
+
+	for holder := range lock.Holders {
+		contender := s.kvs[path.Join(sem.opts.Prefix, holder)]
+		if contender == nil || contender.Session == "" {
+			delete(lock.Holders, holder)
+		}
+	}
+	return lockPair, lock, nil
+}
+
+// writeLock stores lock under the coordination key. It must be called with the
+// store mutex held.
+func (sem *semaphore) writeLock(lock *semaphoreLock) error {
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("lock encoding failed: %v", err)
+	}
+
+	sem.client.store.put(&api.KVPair{
+		Key:   path.Join(sem.opts.Prefix, api.DefaultSemaphoreKey),
+		Value: value,
+		Flags: api.SemaphoreFlagValue,
+	})
+	return nil
+}
+
+// monitorSlot closes stopCh once session no longer holds a slot, or the client
+// context is done.
+func (sem *semaphore) monitorSlot(session string, stopCh chan struct{}) {
+	defer close(stopCh)
+
+	s := sem.client.store
+	for {
+		s.mutex.Lock()
+		s.expire()
+		_, lock, err := sem.readLock()
+		if err != nil || !lock.Holders[session] {
+			s.mutex.Unlock()
+			return
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-sem.client.ctx.Done():
+			return
+		}
+	}
+}
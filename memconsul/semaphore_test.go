@@ -0,0 +1,137 @@
+package memconsul_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Semaphore", func() {
+	var (
+		clock  *memconsul.FakeClock
+		client *memconsul.Client
+		opts   *api.SemaphoreOptions
+	)
+
+	BeforeEach(func() {
+		clock = memconsul.NewFakeClock(time.Now())
+		client = memconsul.NewClientWithClock(clock)
+		opts = &api.SemaphoreOptions{Prefix: "downloads", Limit: 2}
+	})
+
+	newSemaphore := func() consuladapter.Semaphore {
+		sem, err := client.SemaphoreOpts(opts)
+		Expect(err).NotTo(HaveOccurred())
+		return sem
+	}
+
+	It("validates its options", func() {
+		_, err := client.SemaphoreOpts(&api.SemaphoreOptions{Limit: 1})
+		Expect(err).To(HaveOccurred())
+
+		_, err = client.SemaphoreOpts(&api.SemaphoreOptions{Prefix: "downloads"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("admits up to Limit holders and blocks the rest", func() {
+		first, second, third := newSemaphore(), newSemaphore(), newSemaphore()
+
+		firstLost, err := first.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = second.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := third.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			close(acquired)
+		}()
+
+		Consistently(acquired).ShouldNot(BeClosed())
+		Expect(first.Release()).To(Succeed())
+		Eventually(firstLost).Should(BeClosed())
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	It("frees the slot of a holder whose session is invalidated", func() {
+		opts.Limit = 1
+		holder, waiter := newSemaphore(), newSemaphore()
+
+		_, err := holder.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		sessions, _, err := client.Session().List(nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Session().Destroy(sessions[0].ID, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = waiter.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("refuses contenders that disagree on the limit", func() {
+		_, err := newSemaphore().Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		opts.Limit = 3
+		_, err = newSemaphore().Acquire(nil)
+		Expect(err).To(MatchError(ContainSubstring("limit conflict")))
+	})
+
+	It("stops waiting when the context is done", func() {
+		opts.Limit = 1
+		_, err := newSemaphore().Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			_, err := newSemaphore().AcquireContext(ctx)
+			errs <- err
+		}()
+
+		Consistently(errs).ShouldNot(Receive())
+		cancel()
+		Eventually(errs).Should(Receive(Equal(context.Canceled)))
+	})
+
+	It("returns a SemaphoreNotHeldError when releasing a slot that is not held", func() {
+		err := newSemaphore().Release()
+		Expect(err).To(Equal(consuladapter.NewSemaphoreNotHeldError("downloads")))
+		Expect(errors.Is(err, api.ErrSemaphoreNotHeld)).To(BeTrue())
+	})
+
+	Describe("Destroy", func() {
+		It("returns a SemaphoreInUseError while a slot is held", func() {
+			sem := newSemaphore()
+			_, err := sem.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = newSemaphore().Destroy()
+			Expect(err).To(Equal(consuladapter.NewSemaphoreInUseError("downloads")))
+			Expect(errors.Is(err, api.ErrSemaphoreInUse)).To(BeTrue())
+		})
+
+		It("deletes the coordination key once every slot is released", func() {
+			sem := newSemaphore()
+			_, err := sem.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sem.Release()).To(Succeed())
+
+			Expect(sem.Destroy()).To(Succeed())
+
+			pairs, _, err := client.KV().List("downloads", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(BeEmpty())
+		})
+	})
+})
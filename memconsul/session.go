@@ -0,0 +1,199 @@
+package memconsul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+type session struct {
+	store *store
+	ctx   context.Context
+}
+
+func (s *session) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	var checks []string
+	if se != nil {
+		checks = se.Checks
+	}
+	if checks == nil {
+		checks = []string{serfHealth}
+	}
+	return s.create(se, checks)
+}
+
+func (s *session) CreateNoChecks(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	return s.create(se, []string{})
+}
+
+func (s *session) create(se *api.SessionEntry, checks []string) (string, *api.WriteMeta, error) {
+	entry := api.SessionEntry{}
+	if se != nil {
+		entry = *se
+	}
+	entry.ID = generateID()
+	entry.Checks = append([]string{}, checks...)
+
+	if entry.Node == "" {
+		entry.Node = s.store.nodeName
+	}
+	if entry.Behavior == "" {
+		entry.Behavior = api.SessionBehaviorRelease
+	}
+	if entry.Behavior != api.SessionBehaviorRelease && entry.Behavior != api.SessionBehaviorDelete {
+		return "", nil, fmt.Errorf("Unexpected response code: 400 (Invalid Behavior setting '%s')", entry.Behavior)
+	}
+
+	var ttl time.Duration
+	if entry.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(entry.TTL)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unexpected response code: 400 (Request decode failed: %s)", err)
+		}
+	}
+
+	meta, err := s.store.write(s.ctx, func() error {
+		node, ok := s.store.nodes[entry.Node]
+		if !ok {
+			return fmt.Errorf("Unexpected response code: 500 (Missing node registration)")
+		}
+
+		for _, checkID := range entry.Checks {
+			check, ok := node.checks[checkID]
+			if !ok {
+				return fmt.Errorf("Unexpected response code: 500 (Missing check '%s' registration)", checkID)
+			}
+			if check.check.Status == api.HealthCritical {
+				return fmt.Errorf("Unexpected response code: 500 (Check '%s' is in critical state)", checkID)
+			}
+		}
+
+		entry.CreateIndex = s.store.bump(tableSessions)
+		state := &sessionState{entry: entry, ttl: ttl}
+		if ttl > 0 {
+			state.expiresAt = s.store.clock.Now().Add(ttl)
+			s.store.schedule(ttl)
+		}
+		s.store.sessions[entry.ID] = state
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return entry.ID, meta, nil
+}
+
+func (s *session) Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	return s.store.write(s.ctx, func() error {
+		s.store.invalidateSession(id)
+		return nil
+	})
+}
+
+func (s *session) Info(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
+	var entry *api.SessionEntry
+	meta, err := s.store.read(s.ctx, q, func() {
+		if state, ok := s.store.sessions[id]; ok {
+			entry = copySession(state)
+		}
+	}, tableSessions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, meta, nil
+}
+
+func (s *session) List(q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return s.list("", q)
+}
+
+func (s *session) Node(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	return s.list(node, q)
+}
+
+func (s *session) list(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error) {
+	var entries []*api.SessionEntry
+	meta, err := s.store.read(s.ctx, q, func() {
+		for _, state := range s.store.sessions {
+			if node == "" || state.entry.Node == node {
+				entries = append(entries, copySession(state))
+			}
+		}
+	}, tableSessions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreateIndex < entries[j].CreateIndex
+	})
+	return entries, meta, nil
+}
+
+func (s *session) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+	var entry *api.SessionEntry
+	meta, err := s.store.write(s.ctx, func() error {
+		state, ok := s.store.sessions[id]
+		if !ok {
+			return nil
+		}
+
+		if state.ttl > 0 {
+			state.expiresAt = s.store.clock.Now().Add(state.ttl)
+			s.store.schedule(state.ttl)
+		}
+		entry = copySession(state)
+		return nil
+	})
+	return entry, meta, err
+}
+
+// RenewPeriodic follows api.Session.RenewPeriodic, timed by the store clock.
+func (s *session) RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
+	ttl, err := time.ParseDuration(initialTTL)
+	if err != nil {
+		return err
+	}
+
+	wait := ttl / 2
+	lastRenew := s.store.clock.Now()
+	var lastErr error
+	for {
+		if s.store.clock.Now().Sub(lastRenew) > ttl {
+			return lastErr
+		}
+
+		select {
+		case <-s.store.clock.After(wait):
+			entry, _, err := s.Renew(id, q)
+			if err != nil {
+				wait = time.Second
+				lastErr = err
+				continue
+			}
+			if entry == nil {
+				return api.ErrSessionExpired
+			}
+
+			if d, err := time.ParseDuration(entry.TTL); err == nil && d > 0 {
+				ttl = d
+			}
+			wait = ttl / 2
+			lastRenew = s.store.clock.Now()
+
+		case <-doneCh:
+			s.Destroy(id, q)
+			return nil
+		}
+	}
+}
+
+func (s *session) WithContext(ctx context.Context) consuladapter.Session {
+	return &session{store: s.store, ctx: ctx}
+}
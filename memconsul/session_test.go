@@ -0,0 +1,186 @@
+package memconsul_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session", func() {
+	var (
+		clock   *memconsul.FakeClock
+		client  *memconsul.Client
+		session consuladapter.Session
+	)
+
+	BeforeEach(func() {
+		clock = memconsul.NewFakeClock(time.Now())
+		client = memconsul.NewClientWithClock(clock)
+		session = client.Session()
+	})
+
+	It("creates sessions on the agent node with the serf health check", func() {
+		id, _, err := session.Create(&api.SessionEntry{Name: "my-session"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		entry, _, err := session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Name).To(Equal("my-session"))
+		Expect(entry.Node).To(Equal(memconsul.DefaultNodeName))
+		Expect(entry.Checks).To(ConsistOf("serfHealth"))
+		Expect(entry.Behavior).To(Equal(api.SessionBehaviorRelease))
+
+		entries, _, err := session.Node(memconsul.DefaultNodeName, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("refuses checks that do not exist", func() {
+		_, _, err := session.Create(&api.SessionEntry{Checks: []string{"missing"}}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("expires sessions when the TTL elapses on the clock", func() {
+		id, _, err := session.CreateNoChecks(&api.SessionEntry{TTL: "10s"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		clock.Increment(9 * time.Second)
+		entry, _, err := session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).NotTo(BeNil())
+
+		renewed, _, err := session.Renew(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renewed.ID).To(Equal(id))
+
+		clock.Increment(9 * time.Second)
+		entry, _, err = session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).NotTo(BeNil())
+
+		clock.Increment(time.Second)
+		entry, _, err = session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).To(BeNil())
+
+		renewed, _, err = session.Renew(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renewed).To(BeNil())
+	})
+
+	It("keeps sessions alive with RenewPeriodic until done", func() {
+		id, _, err := session.CreateNoChecks(&api.SessionEntry{TTL: "10s"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		doneCh := make(chan struct{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- session.RenewPeriodic("10s", id, nil, doneCh)
+		}()
+
+		for i := 0; i < 4; i++ {
+			Eventually(clock.WatcherCount).Should(BeNumerically(">=", 2))
+			clock.Increment(5 * time.Second)
+		}
+
+		entry, _, err := session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).NotTo(BeNil())
+
+		close(doneCh)
+		Eventually(errCh).Should(Receive(BeNil()))
+
+		entry, _, err = session.Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).To(BeNil())
+	})
+
+	It("retries failed renewals with RenewPeriodic until the TTL has passed", func() {
+		id, _, err := session.CreateNoChecks(&api.SessionEntry{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- client.WithContext(ctx).Session().RenewPeriodic("10s", id, nil, make(chan struct{}))
+		}()
+
+		Eventually(clock.WatcherCount).Should(Equal(1))
+		clock.Increment(5 * time.Second)
+		for i := 0; i < 5; i++ {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(time.Second)
+		}
+
+		Eventually(clock.WatcherCount).Should(Equal(1))
+		Consistently(errCh).ShouldNot(Receive())
+		clock.Increment(time.Second)
+		Eventually(errCh).Should(Receive(MatchError(context.Canceled)))
+	})
+
+	Describe("invalidation", func() {
+		var kv consuladapter.KV
+
+		BeforeEach(func() {
+			kv = client.KV()
+		})
+
+		acquire := func(key, sessionID string) {
+			lock, err := client.LockOpts(&api.LockOptions{Key: key, Session: sessionID})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = lock.Lock(nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("releases held keys with the release behavior", func() {
+			id, _, err := session.CreateNoChecks(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			acquire("lock", id)
+
+			_, err = session.Destroy(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Session).To(BeEmpty())
+		})
+
+		It("deletes held keys with the delete behavior", func() {
+			id, _, err := session.CreateNoChecks(&api.SessionEntry{Behavior: api.SessionBehaviorDelete}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			acquire("lock", id)
+
+			_, err = session.Destroy(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			pair, _, err := kv.Get("lock", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair).To(BeNil())
+		})
+
+		It("invalidates sessions whose checks go critical", func() {
+			err := client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+				Name:  "my-service",
+				Check: &api.AgentServiceCheck{TTL: "10s", Status: api.HealthPassing},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			id, _, err := session.Create(&api.SessionEntry{Checks: []string{"serfHealth", "service:my-service"}}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			clock.Increment(10 * time.Second)
+
+			entry, _, err := session.Info(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package memconsul
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/consuladapter"
+)
+
+const serverAddress = "127.0.0.1:8300"
+
+type status struct {
+	ctx context.Context
+}
+
+func (s status) Leader() (string, error) {
+	if err := s.ctx.Err(); err != nil {
+		return "", err
+	}
+	return serverAddress, nil
+}
+
+func (s status) Peers() ([]string, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return []string{serverAddress}, nil
+}
+
+func (s status) WithContext(ctx context.Context) consuladapter.Status {
+	return status{ctx: ctx}
+}
@@ -0,0 +1,344 @@
+package memconsul
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	defaultWaitTime = 5 * time.Minute
+	maxWaitTime     = 10 * time.Minute
+
+	serfHealth = "serfHealth"
+)
+
+const (
+	tableKVs      = "kvs"
+	tableSessions = "sessions"
+	tableNodes    = "nodes"
+	tableServices = "services"
+	tableChecks   = "checks"
+)
+
+type sessionState struct {
+	entry     api.SessionEntry
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+type checkState struct {
+	check     api.AgentCheck
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+// memberState is a gossip member joined through Agent.Join. The local agent
+// is always a member and is not stored.
+type memberState struct {
+	member api.AgentMember
+	wan    bool
+}
+
+type nodeState struct {
+	node     api.Node
+	services map[string]*api.AgentService
+	checks   map[string]*checkState
+}
+
+// store holds the state shared by every adapter of a Client. All indexes come
+// from a single counter, like the raft index of a real cluster, and every
+// table remembers the index of its last modification for blocking queries.
+type store struct {
+	clock      Clock
+	nodeName   string
+	datacenter string
+
+	index  uint64
+	tables map[string]uint64
+
+	// txnIndex is the index every write of a running transaction shares.
+	txnIndex uint64
+
+	kvs        map[string]*api.KVPair
+	lockDelays map[string]time.Time
+	sessions   map[string]*sessionState
+	nodes      map[string]*nodeState
+	members    map[string]*memberState
+	rtts       map[string]time.Duration
+
+	changed chan struct{}
+
+	mutex sync.Mutex
+}
+
+func newStore(clock Clock, nodeName, datacenter string) *store {
+	s := &store{
+		clock:      clock,
+		nodeName:   nodeName,
+		datacenter: datacenter,
+		index:      1,
+		tables:     map[string]uint64{},
+		kvs:        map[string]*api.KVPair{},
+		lockDelays: map[string]time.Time{},
+		sessions:   map[string]*sessionState{},
+		nodes:      map[string]*nodeState{},
+		members:    map[string]*memberState{},
+		rtts:       map[string]time.Duration{},
+		changed:    make(chan struct{}),
+	}
+
+	s.nodes[nodeName] = &nodeState{
+		node:     api.Node{Node: nodeName, Address: "127.0.0.1"},
+		services: map[string]*api.AgentService{},
+		checks: map[string]*checkState{
+			serfHealth: {check: api.AgentCheck{
+				Node:    nodeName,
+				CheckID: serfHealth,
+				Name:    "Serf Health Status",
+				Status:  api.HealthPassing,
+				Output:  "Agent alive and reachable",
+			}},
+		},
+	}
+	s.bump(tableNodes, tableChecks)
+
+	return s
+}
+
+// bump advances the index for the given tables and wakes up blocked queries.
+// Inside a transaction it only records the transaction index. It must be
+// called with the mutex held.
+func (s *store) bump(tables ...string) uint64 {
+	if s.txnIndex != 0 {
+		for _, table := range tables {
+			s.tables[table] = s.txnIndex
+		}
+		return s.txnIndex
+	}
+
+	s.index++
+	for _, table := range tables {
+		s.tables[table] = s.index
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return s.index
+}
+
+func (s *store) tableIndex(tables ...string) uint64 {
+	var index uint64 = 1
+	for _, table := range tables {
+		if s.tables[table] > index {
+			index = s.tables[table]
+		}
+	}
+	return index
+}
+
+// read runs fn with the mutex held, once the index of the given tables has
+// moved past q.WaitIndex or the wait time has elapsed. It gives up when ctx is
+// done.
+func (s *store) read(ctx context.Context, q *api.QueryOptions, fn func(), tables ...string) (*api.QueryMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	for {
+		s.expire()
+
+		index := s.tableIndex(tables...)
+		if q == nil || q.WaitIndex == 0 || index > q.WaitIndex {
+			fn()
+			return &api.QueryMeta{LastIndex: index, KnownLeader: true}, nil
+		}
+
+		if timeout == nil {
+			waitTime := q.WaitTime
+			if waitTime <= 0 {
+				waitTime = defaultWaitTime
+			}
+			if waitTime > maxWaitTime {
+				waitTime = maxWaitTime
+			}
+			timeout = s.clock.After(waitTime)
+		}
+
+		changed := s.changed
+		s.mutex.Unlock()
+
+		select {
+		case <-changed:
+			s.mutex.Lock()
+		case <-timeout:
+			s.mutex.Lock()
+			s.expire()
+			fn()
+			return &api.QueryMeta{LastIndex: s.tableIndex(tables...), KnownLeader: true}, nil
+		case <-ctx.Done():
+			s.mutex.Lock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// write runs fn with the mutex held after expiring stale sessions and checks.
+func (s *store) write(ctx context.Context, fn func() error) (*api.WriteMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expire()
+	if err := fn(); err != nil {
+		return nil, err
+	}
+	return &api.WriteMeta{}, nil
+}
+
+func (s *store) schedule(d time.Duration) {
+	after := s.clock.After(d)
+	go func() {
+		<-after
+		s.mutex.Lock()
+		s.expire()
+		s.mutex.Unlock()
+	}()
+}
+
+// expire invalidates sessions and marks checks critical whose TTL elapsed. It
+// must be called with the mutex held.
+func (s *store) expire() {
+	now := s.clock.Now()
+
+	for _, node := range s.nodes {
+		for _, check := range node.checks {
+			if check.ttl > 0 && !now.Before(check.expiresAt) && check.check.Status != api.HealthCritical {
+				s.setCheckStatus(node, check, api.HealthCritical, "TTL expired")
+			}
+		}
+	}
+
+	for id, session := range s.sessions {
+		if session.ttl > 0 && !now.Before(session.expiresAt) {
+			s.invalidateSession(id)
+		}
+	}
+}
+
+// setCheckStatus updates a check and invalidates sessions bound to it when it
+// turns critical. It must be called with the mutex held.
+func (s *store) setCheckStatus(node *nodeState, check *checkState, status, output string) {
+	check.check.Status = status
+	check.check.Output = output
+	if check.ttl > 0 {
+		check.expiresAt = s.clock.Now().Add(check.ttl)
+		if status != api.HealthCritical {
+			s.schedule(check.ttl)
+		}
+	}
+	s.bump(tableChecks)
+
+	if status == api.HealthCritical {
+		s.invalidateSessionsForCheck(node.node.Node, check.check.CheckID)
+	}
+}
+
+func (s *store) invalidateSessionsForCheck(node, checkID string) {
+	for id, session := range s.sessions {
+		if session.entry.Node != node {
+			continue
+		}
+		for _, c := range session.entry.Checks {
+			if c == checkID {
+				s.invalidateSession(id)
+				break
+			}
+		}
+	}
+}
+
+// invalidateSession destroys a session and releases or deletes the keys it
+// holds, according to its behavior. It must be called with the mutex held.
+func (s *store) invalidateSession(id string) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	delete(s.sessions, id)
+
+	index := s.bump(tableSessions)
+
+	kvChanged := false
+	for key, pair := range s.kvs {
+		if pair.Session != id {
+			continue
+		}
+		kvChanged = true
+
+		if session.entry.LockDelay > 0 {
+			s.lockDelays[key] = s.clock.Now().Add(session.entry.LockDelay)
+		}
+
+		if session.entry.Behavior == api.SessionBehaviorDelete {
+			delete(s.kvs, key)
+			continue
+		}
+		pair.Session = ""
+		pair.ModifyIndex = index
+	}
+
+	if kvChanged {
+		s.tables[tableKVs] = index
+	}
+}
+
+func (s *store) sortedKeys(prefix string) []string {
+	keys := []string{}
+	for key := range s.kvs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyPair(p *api.KVPair) *api.KVPair {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+	if p.Value != nil {
+		c.Value = append([]byte{}, p.Value...)
+	}
+	return &c
+}
+
+func copySession(s *sessionState) *api.SessionEntry {
+	entry := s.entry
+	entry.Checks = append([]string{}, s.entry.Checks...)
+	return &entry
+}
+
+func generateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,147 @@
+package memconsul
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+)
+
+func (kv *keyValue) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	if len(txn) > consuladapter.MaxTxnOps {
+		return false, nil, nil, fmt.Errorf("Failed request: Transaction contains too many operations (%d > %d)", len(txn), consuladapter.MaxTxnOps)
+	}
+
+	var resp *api.KVTxnResponse
+	var index uint64
+	_, err := kv.store.write(kv.ctx, func() error {
+		resp = kv.store.txn(txn)
+		index = kv.store.index
+		return nil
+	})
+	if err != nil {
+		return false, nil, nil, err
+	}
+	return len(resp.Errors) == 0, resp, &api.QueryMeta{LastIndex: index, KnownLeader: true}, nil
+}
+
+// txn applies ops at a single index. If any of them fails, every change is
+// rolled back and the response only carries the errors. It must be called
+// with the store mutex held.
+func (s *store) txn(ops api.KVTxnOps) *api.KVTxnResponse {
+	kvs := make(map[string]*api.KVPair, len(s.kvs))
+	for key, pair := range s.kvs {
+		kvs[key] = copyPair(pair)
+	}
+	kvsIndex := s.tables[tableKVs]
+	lockDelays := make(map[string]time.Time, len(s.lockDelays))
+	for key, until := range s.lockDelays {
+		lockDelays[key] = until
+	}
+
+	index := s.index + 1
+	s.txnIndex = index
+	defer func() { s.txnIndex = 0 }()
+
+	resp := &api.KVTxnResponse{}
+	for i, op := range ops {
+		results, what := s.txnOp(op)
+		if what != "" {
+			resp.Errors = append(resp.Errors, &api.TxnError{OpIndex: i, What: what})
+			continue
+		}
+		resp.Results = append(resp.Results, results...)
+	}
+
+	if len(resp.Errors) > 0 {
+		s.kvs = kvs
+		s.tables[tableKVs] = kvsIndex
+		s.lockDelays = lockDelays
+		resp.Results = nil
+		return resp
+	}
+
+	if s.tables[tableKVs] == index {
+		s.txnIndex = 0
+		s.bump(tableKVs)
+	}
+	return resp
+}
+
+func (s *store) txnOp(op *api.KVTxnOp) ([]*api.KVPair, string) {
+	pair := &api.KVPair{
+		Key:         op.Key,
+		Value:       op.Value,
+		Flags:       op.Flags,
+		ModifyIndex: op.Index,
+		Session:     op.Session,
+	}
+
+	switch op.Verb {
+	case string(api.KVSet):
+		s.put(pair)
+	case api.KVCAS:
+		if !s.cas(pair) {
+			return nil, fmt.Sprintf("failed to set key %q, index is stale", op.Key)
+		}
+	case api.KVLock:
+		acquired, err := s.acquire(pair)
+		if err != nil {
+			return nil, fmt.Sprintf("failed to lock key %q: invalid session %q", op.Key, op.Session)
+		}
+		if !acquired {
+			return nil, fmt.Sprintf("failed to lock key %q, lock is already held", op.Key)
+		}
+	case api.KVUnlock:
+		if !s.release(pair) {
+			return nil, fmt.Sprintf("failed to unlock key %q, lock isn't held, or is held by another session", op.Key)
+		}
+	case api.KVGet:
+		existing, ok := s.kvs[op.Key]
+		if !ok {
+			return nil, fmt.Sprintf("key %q doesn't exist", op.Key)
+		}
+		return []*api.KVPair{copyPair(existing)}, ""
+	case api.KVGetTree:
+		var results []*api.KVPair
+		for _, key := range s.sortedKeys(op.Key) {
+			results = append(results, copyPair(s.kvs[key]))
+		}
+		return results, ""
+	case api.KVCheckSession:
+		existing, ok := s.kvs[op.Key]
+		if !ok {
+			return nil, fmt.Sprintf("key %q doesn't exist", op.Key)
+		}
+		if existing.Session != op.Session {
+			return nil, fmt.Sprintf("failed session check for key %q, current session %q != %q", op.Key, existing.Session, op.Session)
+		}
+	case api.KVCheckIndex:
+		existing, ok := s.kvs[op.Key]
+		if !ok {
+			return nil, fmt.Sprintf("key %q doesn't exist", op.Key)
+		}
+		if existing.ModifyIndex != op.Index {
+			return nil, fmt.Sprintf("failed index check for key %q, current modify index %d != %d", op.Key, existing.ModifyIndex, op.Index)
+		}
+	case api.KVDelete:
+		s.delete(op.Key)
+		return nil, ""
+	case api.KVDeleteCAS:
+		if !s.deleteCAS(pair) {
+			return nil, fmt.Sprintf("failed to delete key %q, index is stale", op.Key)
+		}
+		return nil, ""
+	case api.KVDeleteTree:
+		s.deleteTree(op.Key)
+		return nil, ""
+	default:
+		return nil, fmt.Sprintf("unknown KV verb %q", op.Verb)
+	}
+
+	// Like Consul, writes and checks return the entry without its value.
+	result := copyPair(s.kvs[op.Key])
+	result.Value = nil
+	return []*api.KVPair{result}, ""
+}
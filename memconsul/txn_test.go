@@ -0,0 +1,128 @@
+package memconsul_test
+
+import (
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Txn", func() {
+	var (
+		client *memconsul.Client
+		kv     consuladapter.KV
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		kv = client.KV()
+	})
+
+	It("applies every operation at a single index", func() {
+		ok, resp, meta, err := kv.Txn(api.KVTxnOps{
+			{Verb: string(api.KVSet), Key: "a", Value: []byte("1")},
+			{Verb: string(api.KVSet), Key: "b", Value: []byte("2"), Flags: 7},
+			{Verb: api.KVGet, Key: "a"},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.Errors).To(BeEmpty())
+		Expect(resp.Results).To(HaveLen(3))
+		Expect(resp.Results[0].Value).To(BeNil())
+		Expect(resp.Results[2].Value).To(Equal([]byte("1")))
+
+		a, _, err := kv.Get("a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		b, _, err := kv.Get("b", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a.ModifyIndex).To(Equal(b.ModifyIndex))
+		Expect(meta.LastIndex).To(Equal(a.ModifyIndex))
+		Expect(b.Flags).To(Equal(uint64(7)))
+	})
+
+	It("rolls back and reports every failed operation", func() {
+		_, err := kv.Put(&api.KVPair{Key: "a", Value: []byte("old")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		before, meta, err := kv.Get("a", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, resp, _, err := kv.Txn(api.KVTxnOps{
+			{Verb: string(api.KVSet), Key: "a", Value: []byte("new")},
+			{Verb: api.KVDelete, Key: "a"},
+			{Verb: api.KVCheckIndex, Key: "missing", Index: 1},
+			{Verb: api.KVCAS, Key: "b", Index: 99},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(resp.Results).To(BeEmpty())
+		Expect(resp.Errors).To(HaveLen(2))
+		Expect(resp.Errors[0].OpIndex).To(Equal(2))
+		Expect(resp.Errors[0].What).To(ContainSubstring("doesn't exist"))
+		Expect(resp.Errors[1].OpIndex).To(Equal(3))
+		Expect(resp.Errors[1].What).To(ContainSubstring("index is stale"))
+
+		after, afterMeta, err := kv.Get("a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).To(Equal(before))
+		Expect(afterMeta.LastIndex).To(Equal(meta.LastIndex))
+	})
+
+	It("locks and unlocks keys with a session", func() {
+		sessionID, _, err := client.Session().CreateNoChecks(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, resp, _, err := kv.Txn(api.KVTxnOps{
+			{Verb: api.KVLock, Key: "lock", Session: sessionID},
+			{Verb: api.KVCheckSession, Key: "lock", Session: sessionID},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.Results).To(HaveLen(2))
+		Expect(resp.Results[1].Session).To(Equal(sessionID))
+
+		ok, resp, _, err = kv.Txn(api.KVTxnOps{
+			{Verb: api.KVUnlock, Key: "lock", Session: "other"},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(resp.Errors[0].What).To(ContainSubstring("failed to unlock"))
+
+		ok, _, _, err = kv.Txn(api.KVTxnOps{
+			{Verb: api.KVUnlock, Key: "lock", Session: sessionID},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("deletes trees and checks indexes", func() {
+		_, err := kv.Put(&api.KVPair{Key: "dir/a"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		pair, _, err := kv.Get("dir/a", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, resp, _, err := kv.Txn(api.KVTxnOps{
+			{Verb: api.KVCheckIndex, Key: "dir/a", Index: pair.ModifyIndex},
+			{Verb: api.KVDeleteTree, Key: "dir/"},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.Results).To(HaveLen(1))
+		Expect(resp.Results[0].Key).To(Equal("dir/a"))
+		Expect(resp.Results[0].ModifyIndex).To(Equal(pair.ModifyIndex))
+
+		pairs, _, err := kv.List("dir/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(BeEmpty())
+	})
+
+	It("rejects transactions with too many operations", func() {
+		ops := make(api.KVTxnOps, consuladapter.MaxTxnOps+1)
+		for i := range ops {
+			ops[i] = &api.KVTxnOp{Verb: string(api.KVSet), Key: "key"}
+		}
+		_, _, _, err := kv.Txn(ops, nil)
+		Expect(err).To(MatchError(ContainSubstring("too many operations")))
+	})
+})
@@ -0,0 +1,219 @@
+package consuladapter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	cfhttp "code.cloudfoundry.org/cfhttp/v2"
+	"github.com/hashicorp/consul/api"
+)
+
+type Option func(*clientConfig) error
+
+type clientConfig struct {
+	datacenter string
+	token      string
+	httpAuth   *api.HttpBasicAuth
+	waitTime   time.Duration
+
+	httpClient  *http.Client
+	httpOptions []cfhttp.Option
+	tlsFiles    *api.TLSConfig
+	tlsConfig   *tls.Config
+}
+
+func WithDatacenter(datacenter string) Option {
+	return func(c *clientConfig) error {
+		if datacenter == "" {
+			return NewInvalidOptionError("WithDatacenter", "datacenter must not be empty")
+		}
+		c.datacenter = datacenter
+		return nil
+	}
+}
+
+func WithToken(token string) Option {
+	return func(c *clientConfig) error {
+		if token == "" {
+			return NewInvalidOptionError("WithToken", "token must not be empty")
+		}
+		c.token = token
+		return nil
+	}
+}
+
+func WithHTTPBasicAuth(username, password string) Option {
+	return func(c *clientConfig) error {
+		if username == "" {
+			return NewInvalidOptionError("WithHTTPBasicAuth", "username must not be empty")
+		}
+		c.httpAuth = &api.HttpBasicAuth{Username: username, Password: password}
+		return nil
+	}
+}
+
+func WithWaitTime(waitTime time.Duration) Option {
+	return func(c *clientConfig) error {
+		if waitTime < 0 {
+			return NewInvalidOptionError("WithWaitTime", "wait time must not be negative")
+		}
+		c.waitTime = waitTime
+		return nil
+	}
+}
+
+// WithHTTPClient cannot be combined with the HTTP and TLS options.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *clientConfig) error {
+		if httpClient == nil {
+			return NewInvalidOptionError("WithHTTPClient", "http client must not be nil")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithRequestTimeout also applies to blocking queries, so it must exceed the
+// wait time in use.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) error {
+		if timeout < 0 {
+			return NewInvalidOptionError("WithRequestTimeout", "timeout must not be negative")
+		}
+		c.httpOptions = append(c.httpOptions, cfhttp.WithRequestTimeout(timeout))
+		return nil
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) error {
+		if timeout < 0 {
+			return NewInvalidOptionError("WithDialTimeout", "timeout must not be negative")
+		}
+		c.httpOptions = append(c.httpOptions, cfhttp.WithDialTimeout(timeout))
+		return nil
+	}
+}
+
+func WithTCPKeepAliveTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) error {
+		if timeout < 0 {
+			return NewInvalidOptionError("WithTCPKeepAliveTimeout", "timeout must not be negative")
+		}
+		c.httpOptions = append(c.httpOptions, cfhttp.WithTCPKeepAliveTimeout(timeout))
+		return nil
+	}
+}
+
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) error {
+		if timeout < 0 {
+			return NewInvalidOptionError("WithIdleConnTimeout", "timeout must not be negative")
+		}
+		c.httpOptions = append(c.httpOptions, cfhttp.WithIdleConnTimeout(timeout))
+		return nil
+	}
+}
+
+func WithMaxIdleConnsPerHost(max int) Option {
+	return func(c *clientConfig) error {
+		if max < 0 {
+			return NewInvalidOptionError("WithMaxIdleConnsPerHost", "max must not be negative")
+		}
+		c.httpOptions = append(c.httpOptions, cfhttp.WithMaxIdleConnsPerHost(max))
+		return nil
+	}
+}
+
+func WithDisableKeepAlives() Option {
+	return func(c *clientConfig) error {
+		c.httpOptions = append(c.httpOptions, cfhttp.WithDisableKeepAlives())
+		return nil
+	}
+}
+
+// WithTLSFiles accepts empty paths, but a client certificate requires a key
+// and vice versa.
+func WithTLSFiles(caCert, clientCert, clientKey string) Option {
+	return func(c *clientConfig) error {
+		if (clientCert == "") != (clientKey == "") {
+			return NewInvalidOptionError("WithTLSFiles", "client cert and client key must be provided together")
+		}
+		c.tlsFiles = &api.TLSConfig{
+			CAFile:   caCert,
+			CertFile: clientCert,
+			KeyFile:  clientKey,
+		}
+		return nil
+	}
+}
+
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *clientConfig) error {
+		if tlsConfig == nil {
+			return NewInvalidOptionError("WithTLSConfig", "tls config must not be nil")
+		}
+		c.tlsConfig = tlsConfig
+		return nil
+	}
+}
+
+func newClientConfig(opts []Option) (*clientConfig, error) {
+	config := &clientConfig{}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.tlsFiles != nil && config.tlsConfig != nil {
+		return nil, NewInvalidOptionError("WithTLSConfig", "cannot be combined with WithTLSFiles")
+	}
+
+	if config.httpClient != nil && (len(config.httpOptions) > 0 || config.tlsFiles != nil || config.tlsConfig != nil) {
+		return nil, NewInvalidOptionError("WithHTTPClient", "cannot be combined with http or tls options")
+	}
+
+	return config, nil
+}
+
+// tlsAddress derives the expected server name and is empty when requests go
+// to several hosts.
+func (c *clientConfig) buildHTTPClient(tlsAddress string) (*http.Client, error) {
+	if c.httpClient != nil {
+		return c.httpClient, nil
+	}
+
+	httpOptions := append([]cfhttp.Option{cfhttp.WithStreamingDefaults()}, c.httpOptions...)
+
+	tlsConfig := c.tlsConfig
+	if c.tlsFiles != nil {
+		tlsFiles := *c.tlsFiles
+		tlsFiles.Address = tlsAddress
+
+		var err error
+		tlsConfig, err = api.SetupTLSConfig(&tlsFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tlsConfig != nil {
+		httpOptions = append(httpOptions, cfhttp.WithTLSConfig(tlsConfig))
+	}
+
+	return cfhttp.NewClient(httpOptions...), nil
+}
+
+func (c *clientConfig) apiConfig(scheme, address string, httpClient *http.Client) *api.Config {
+	return &api.Config{
+		Address:    address,
+		Scheme:     scheme,
+		Datacenter: c.datacenter,
+		HttpClient: httpClient,
+		HttpAuth:   c.httpAuth,
+		WaitTime:   c.waitTime,
+		Token:      c.token,
+	}
+}
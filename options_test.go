@@ -0,0 +1,142 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options", func() {
+	var (
+		server   *httptest.Server
+		requests chan *http.Request
+		delay    time.Duration
+	)
+
+	BeforeEach(func() {
+		delay = 0
+		requests = make(chan *http.Request, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests <- r
+			time.Sleep(delay)
+			fmt.Fprint(w, `[]`)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("New", func() {
+		It("errors when passed an invalid URL", func() {
+			_, err := consuladapter.New("sftp://")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("sends the configured datacenter, token and basic auth", func() {
+			client, err := consuladapter.New(
+				server.URL,
+				consuladapter.WithDatacenter("dc2"),
+				consuladapter.WithToken("some-token"),
+				consuladapter.WithHTTPBasicAuth("user", "pass"),
+				consuladapter.WithWaitTime(3*time.Second),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = client.KV().List("some-prefix", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var req *http.Request
+			Eventually(requests).Should(Receive(&req))
+			Expect(req.URL.Query().Get("dc")).To(Equal("dc2"))
+			Expect(req.URL.Query().Get("wait")).To(Equal("3000ms"))
+			Expect(req.Header.Get("X-Consul-Token")).To(Equal("some-token"))
+			username, password, ok := req.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("user"))
+			Expect(password).To(Equal("pass"))
+		})
+
+		It("applies the request timeout", func() {
+			delay = 200 * time.Millisecond
+			client, err := consuladapter.New(server.URL, consuladapter.WithRequestTimeout(50*time.Millisecond))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = client.KV().List("some-prefix", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("uses the given HTTP client", func() {
+			client, err := consuladapter.New(server.URL, consuladapter.WithHTTPClient(&http.Client{}))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = client.KV().List("some-prefix", nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when an option is invalid", func() {
+			invalidOptions := []struct {
+				option string
+				opts   []consuladapter.Option
+			}{
+				{"WithDatacenter", []consuladapter.Option{consuladapter.WithDatacenter("")}},
+				{"WithToken", []consuladapter.Option{consuladapter.WithToken("")}},
+				{"WithHTTPBasicAuth", []consuladapter.Option{consuladapter.WithHTTPBasicAuth("", "pass")}},
+				{"WithWaitTime", []consuladapter.Option{consuladapter.WithWaitTime(-time.Second)}},
+				{"WithRequestTimeout", []consuladapter.Option{consuladapter.WithRequestTimeout(-time.Second)}},
+				{"WithDialTimeout", []consuladapter.Option{consuladapter.WithDialTimeout(-time.Second)}},
+				{"WithTCPKeepAliveTimeout", []consuladapter.Option{consuladapter.WithTCPKeepAliveTimeout(-time.Second)}},
+				{"WithIdleConnTimeout", []consuladapter.Option{consuladapter.WithIdleConnTimeout(-time.Second)}},
+				{"WithMaxIdleConnsPerHost", []consuladapter.Option{consuladapter.WithMaxIdleConnsPerHost(-1)}},
+				{"WithTLSFiles", []consuladapter.Option{consuladapter.WithTLSFiles("ca", "cert", "")}},
+				{"WithTLSConfig", []consuladapter.Option{consuladapter.WithTLSConfig(nil)}},
+				{"WithHTTPClient", []consuladapter.Option{consuladapter.WithHTTPClient(nil)}},
+				{"WithHTTPClient", []consuladapter.Option{
+					consuladapter.WithHTTPClient(&http.Client{}),
+					consuladapter.WithRequestTimeout(time.Second),
+				}},
+			}
+
+			for _, invalid := range invalidOptions {
+				invalid := invalid
+
+				It("names "+invalid.option+" in the error", func() {
+					_, err := consuladapter.New(server.URL, invalid.opts...)
+					var invalidOption consuladapter.InvalidOptionError
+					Expect(errors.As(err, &invalidOption)).To(BeTrue())
+					Expect(invalidOption.Option).To(Equal(invalid.option))
+					Expect(err.Error()).To(ContainSubstring(invalid.option))
+				})
+			}
+		})
+	})
+
+	Describe("NewFromUrls", func() {
+		It("applies the options to every endpoint", func() {
+			client, err := consuladapter.NewFromUrls(
+				[]string{"http://127.0.0.1:1", server.URL},
+				consuladapter.WithToken("some-token"),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = client.KV().List("some-prefix", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var req *http.Request
+			Eventually(requests).Should(Receive(&req))
+			Expect(req.Header.Get("X-Consul-Token")).To(Equal("some-token"))
+		})
+
+		It("validates the options", func() {
+			_, err := consuladapter.NewFromUrls([]string{server.URL}, consuladapter.WithToken(""))
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+	})
+})
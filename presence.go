@@ -0,0 +1,254 @@
+package consuladapter
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+type PresenceStatus string
+
+const (
+	// PresenceAbsent is the status before the key is first acquired and after
+	// it or the session holding it has been lost.
+	PresenceAbsent PresenceStatus = "absent"
+	// PresencePresent is the status while the key is held.
+	PresencePresent PresenceStatus = "present"
+	// PresenceContended is the status while another session holds the key or
+	// its lock-delay keeps it from being acquired.
+	PresenceContended PresenceStatus = "contended"
+)
+
+type PresenceOptions struct {
+	// Key is published with Value while this node is present.
+	Key   string
+	Value []byte
+
+	// Session configures the session the key is bound to. It is always
+	// recreated after it is lost.
+	Session SessionManagerOptions
+
+	// MinBackoff and MaxBackoff bound the retries to acquire the key, and
+	// those of Session.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every failed attempt to create a session
+	// or acquire the key.
+	OnError func(err error)
+}
+
+// Presence is an ifrit.Runner that publishes a key bound to a self-renewing
+// session for as long as it runs. If the session is lost it acquires the key
+// again with a new one, and if another holder releases or deletes the key it
+// acquires it back. On shutdown it deletes the key and destroys its session.
+type Presence struct {
+	client Client
+	opts   PresenceOptions
+
+	status  PresenceStatus
+	changes chan PresenceStatus
+	mutex   sync.Mutex
+}
+
+func NewPresence(client Client, opts PresenceOptions) (*Presence, error) {
+	if opts.Key == "" {
+		return nil, NewInvalidOptionError("Key", "must not be empty")
+	}
+	if opts.Session.TTL == "" {
+		opts.Session.TTL = DefaultSessionTTL
+	} else if ttl, err := time.ParseDuration(opts.Session.TTL); err != nil || ttl <= 0 {
+		return nil, NewInvalidOptionError("Session.TTL", "must be a positive duration")
+	}
+	opts.MinBackoff, opts.MaxBackoff = retry.Bounds(opts.MinBackoff, opts.MaxBackoff, DefaultMinBackoff, DefaultMaxBackoff)
+	opts.Session.Recreate = true
+	opts.Session.MinBackoff = opts.MinBackoff
+	opts.Session.MaxBackoff = opts.MaxBackoff
+	opts.Session.OnError = opts.OnError
+
+	return &Presence{
+		client:  client,
+		opts:    opts,
+		status:  PresenceAbsent,
+		changes: make(chan PresenceStatus, 1),
+	}, nil
+}
+
+func (p *Presence) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	close(ready)
+
+	var manager *SessionManager
+	var failures uint
+	for manager == nil {
+		var err error
+		manager, err = NewSessionManager(p.client.Session(), p.opts.Session)
+		if err != nil {
+			if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(p.opts.MinBackoff, p.opts.MaxBackoff, failures))) {
+				return nil
+			}
+			failures++
+		}
+	}
+
+	failures = 0
+	for ctx.Err() == nil {
+		id, lost := manager.Session()
+		select {
+		case <-lost:
+			// The manager is creating a new session.
+			p.setStatus(PresenceAbsent)
+			retry.Sleep(ctx, retry.Jitter(p.opts.MinBackoff))
+			continue
+		default:
+		}
+
+		held, err := p.hold(ctx, id, lost)
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			p.setStatus(PresenceAbsent)
+			p.reportError(err)
+			retry.Sleep(ctx, retry.Jitter(retry.Backoff(p.opts.MinBackoff, p.opts.MaxBackoff, failures)))
+			failures++
+		case !held:
+			p.setStatus(PresenceContended)
+			retry.Sleep(ctx, retry.Jitter(retry.Backoff(p.opts.MinBackoff, p.opts.MaxBackoff, failures)))
+			failures++
+		default:
+			p.setStatus(PresenceAbsent)
+			failures = 0
+		}
+	}
+
+	p.withdraw(manager.ID())
+	manager.Close()
+	p.setStatus(PresenceAbsent)
+	return nil
+}
+
+// hold acquires the key with session id and then watches it, returning true
+// once it has been held and lost, and false if it could not be acquired.
+func (p *Presence) hold(ctx context.Context, id string, lost <-chan struct{}) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	kv := p.client.KV().WithContext(ctx)
+	acquired, _, err := kv.Acquire(&api.KVPair{Key: p.opts.Key, Value: p.opts.Value, Session: id}, nil)
+	if err != nil || !acquired {
+		return false, err
+	}
+	p.setStatus(PresencePresent)
+
+	var index uint64
+	for {
+		pair, meta, err := kv.Get(p.opts.Key, &api.QueryOptions{WaitIndex: index})
+		if ctx.Err() != nil {
+			return true, nil
+		}
+		if err != nil {
+			p.reportError(err)
+			if !retry.Sleep(ctx, retry.Jitter(p.opts.MinBackoff)) {
+				return true, nil
+			}
+			continue
+		}
+		if pair == nil || pair.Session != id {
+			return true, nil
+		}
+		if meta == nil || meta.LastIndex < index {
+			index = 0
+			if !retry.Sleep(ctx, retry.Jitter(p.opts.MinBackoff)) {
+				return true, nil
+			}
+			continue
+		}
+		index = meta.LastIndex
+	}
+}
+
+// withdraw deletes the key if session id still holds it.
+func (p *Presence) withdraw(id string) {
+	_, _, err := NewTxn().
+		Op(&api.KVTxnOp{Verb: api.KVCheckSession, Key: p.opts.Key, Session: id}).
+		Delete(p.opts.Key).
+		Commit(p.client.KV(), nil)
+	if _, failed := err.(TxnFailedError); err != nil && !failed {
+		p.reportError(err)
+	}
+}
+
+func (p *Presence) setStatus(status PresenceStatus) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.status == status {
+		return
+	}
+	p.status = status
+
+	// Only the latest transition is kept for a receiver that falls behind.
+	select {
+	case <-p.changes:
+	default:
+	}
+	p.changes <- status
+}
+
+func (p *Presence) Status() PresenceStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.status
+}
+
+// Changes reports the status whenever it changes. A receiver that falls behind
+// only sees the latest status.
+func (p *Presence) Changes() <-chan PresenceStatus {
+	return p.changes
+}
+
+func (p *Presence) reportError(err error) {
+	if p.opts.OnError != nil {
+		p.opts.OnError(err)
+	}
+}
+
+// ListPresent returns the keys under prefix that are held by a session, which
+// leaves out those whose holder has gone away under the release behavior.
+func ListPresent(kv KV, prefix string) (api.KVPairs, error) {
+	pairs, _, err := kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var present api.KVPairs
+	for _, pair := range pairs {
+		if pair.Session != "" {
+			present = append(present, pair)
+		}
+	}
+	return present, nil
+}
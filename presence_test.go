@@ -0,0 +1,203 @@
+package consuladapter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Presence", func() {
+	var (
+		client   *memconsul.Client
+		opts     consuladapter.PresenceOptions
+		presence *consuladapter.Presence
+		process  ifrit.Process
+	)
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		opts = consuladapter.PresenceOptions{
+			Key:        "cells/cell-1",
+			Value:      []byte("10.0.0.1"),
+			Session:    consuladapter.SessionManagerOptions{Name: "cell-1", TTL: "10s"},
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+		}
+		process = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		presence, err = consuladapter.NewPresence(client, opts)
+		Expect(err).NotTo(HaveOccurred())
+		process = ginkgomon.Invoke(presence)
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ginkgomon.Interrupt(process)
+		}
+	})
+
+	holder := func() string {
+		pair, _, err := client.KV().Get("cells/cell-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		if pair == nil {
+			return ""
+		}
+		return pair.Session
+	}
+
+	It("publishes the key bound to a session", func() {
+		Eventually(presence.Changes()).Should(Receive(Equal(consuladapter.PresencePresent)))
+		Expect(presence.Status()).To(Equal(consuladapter.PresencePresent))
+
+		pair, _, err := client.KV().Get("cells/cell-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("10.0.0.1")))
+		Expect(pair.Session).NotTo(BeEmpty())
+
+		entry, _, err := client.Session().Info(pair.Session, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Name).To(Equal("cell-1"))
+	})
+
+	It("acquires the key again with a new session after losing its session", func() {
+		Eventually(holder).ShouldNot(BeEmpty())
+		lost := holder()
+
+		_, err := client.Session().Destroy(lost, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(holder).ShouldNot(Or(BeEmpty(), Equal(lost)))
+		Eventually(presence.Status).Should(Equal(consuladapter.PresencePresent))
+	})
+
+	It("acquires the key back after it is deleted", func() {
+		Eventually(holder).ShouldNot(BeEmpty())
+
+		_, err := client.KV().Delete("cells/cell-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(holder).ShouldNot(BeEmpty())
+		Expect(presence.Status()).To(Equal(consuladapter.PresencePresent))
+	})
+
+	It("deletes the key and destroys its session on shutdown", func() {
+		Eventually(holder).ShouldNot(BeEmpty())
+		id := holder()
+
+		ginkgomon.Interrupt(process)
+		process = nil
+
+		Expect(holder()).To(BeEmpty())
+		Expect(presence.Status()).To(Equal(consuladapter.PresenceAbsent))
+		entry, _, err := client.Session().Info(id, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).To(BeNil())
+	})
+
+	Context("when another session holds the key", func() {
+		var other string
+
+		BeforeEach(func() {
+			var err error
+			other, _, err = client.Session().Create(&api.SessionEntry{TTL: "10s"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, _, err := client.KV().Acquire(&api.KVPair{Key: "cells/cell-1", Value: []byte("10.0.0.2"), Session: other}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+
+		It("reports contention until the key is released", func() {
+			Eventually(presence.Status).Should(Equal(consuladapter.PresenceContended))
+			Consistently(holder).Should(Equal(other))
+
+			_, _, err := client.KV().Release(&api.KVPair{Key: "cells/cell-1", Session: other}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(presence.Status).Should(Equal(consuladapter.PresencePresent))
+			Expect(holder()).NotTo(Equal(other))
+		})
+	})
+
+	It("backs off while its watch returns no meta", func() {
+		ginkgomon.Interrupt(process)
+
+		live := client.KV()
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.WithContextReturns(fakeKV)
+		fakeKV.AcquireStub = live.Acquire
+		fakeKV.TxnStub = live.Txn
+		fakeKV.GetStub = func(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+			pair, _, err := live.Get(key, q)
+			return pair, nil, err
+		}
+		fakeClient := &fakes.FakeClient{}
+		fakeClient.KVReturns(fakeKV)
+		fakeClient.SessionReturns(client.Session())
+
+		var err error
+		presence, err = consuladapter.NewPresence(fakeClient, opts)
+		Expect(err).NotTo(HaveOccurred())
+		process = ginkgomon.Invoke(presence)
+
+		Eventually(presence.Status).Should(Equal(consuladapter.PresencePresent))
+		Consistently(presence.Status, 100*time.Millisecond).Should(Equal(consuladapter.PresencePresent))
+		Expect(fakeKV.GetCallCount()).To(BeNumerically("<", 50))
+	})
+
+	Describe("NewPresence", func() {
+		JustBeforeEach(func() {
+			ginkgomon.Interrupt(process)
+			process = nil
+		})
+
+		It("requires a key", func() {
+			_, err := consuladapter.NewPresence(client, consuladapter.PresenceOptions{})
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+
+		It("rejects an invalid session TTL", func() {
+			opts.Session.TTL = "forever"
+			_, err := consuladapter.NewPresence(client, opts)
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+	})
+})
+
+var _ = Describe("ListPresent", func() {
+	It("returns only the keys held by a session", func() {
+		client := memconsul.NewClient()
+		kv := client.KV()
+
+		session, _, err := client.Session().Create(&api.SessionEntry{TTL: "10s"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = kv.Acquire(&api.KVPair{Key: "cells/cell-1", Value: []byte("up"), Session: session}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = kv.Put(&api.KVPair{Key: "cells/cell-2", Value: []byte("stale")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		present, err := consuladapter.ListPresent(kv, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(HaveLen(1))
+		Expect(present[0].Key).To(Equal("cells/cell-1"))
+
+		_, err = client.Session().Destroy(session, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		present, err = consuladapter.ListPresent(kv, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeEmpty())
+	})
+})
@@ -0,0 +1,470 @@
+package consuladapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultReconcileInterval = 30 * time.Second
+
+type DriftKind string
+
+const (
+	// DriftMissing is a desired service or check the agent does not have.
+	DriftMissing DriftKind = "missing"
+	// DriftChanged is a service or check registered differently than
+	// desired.
+	DriftChanged DriftKind = "changed"
+	// DriftExtra is an owned service or check that is not desired.
+	DriftExtra DriftKind = "extra"
+)
+
+// DriftEvent describes a difference between the desired and the actual state
+// that a reconciliation corrected. CheckID is set for drift in checks only,
+// and ServiceID is empty for standalone checks.
+type DriftEvent struct {
+	Kind      DriftKind
+	ServiceID string
+	CheckID   string
+}
+
+type ReconcilerOptions struct {
+	// OwnerTag and IDPrefix scope the services the reconciler owns: those
+	// carrying OwnerTag, which is added to every desired service, or whose ID
+	// starts with IDPrefix. At least one is required, and services outside
+	// the scope are never touched.
+	OwnerTag string
+	IDPrefix string
+
+	// Services and Checks are the desired state. Checks of services are
+	// registered with them, so Checks only holds standalone checks. Those
+	// are owned if their ID starts with IDPrefix or if the reconciler
+	// registered them.
+	Services []*api.AgentServiceRegistration
+	Checks   []*api.AgentCheckRegistration
+
+	Interval time.Duration
+
+	// OnDrift, if set, is called with every difference corrected.
+	OnDrift func(DriftEvent)
+	// OnError, if set, is called with every reconciliation that fails.
+	OnError func(err error)
+}
+
+// Reconciler converges the services and checks the local agent has within its
+// scope to a desired state, registering what is missing or changed and
+// deregistering what is extra.
+//
+// The agent does not report how checks are defined, so a check counts as
+// changed when its desired definition differs from the one the reconciler
+// last registered. Checks it did not register are adopted as they are if the
+// agent reports them with the expected name and service, since registering
+// again would reset TTL checks to critical.
+type Reconciler struct {
+	agent Agent
+	opts  ReconcilerOptions
+
+	desired       map[string]*api.AgentServiceRegistration
+	desiredChecks map[string]*api.AgentCheckRegistration
+	trigger       chan struct{}
+	mutex         sync.Mutex
+
+	// registered holds the checks the reconciler registered, by ID.
+	registered   map[string]api.AgentCheckRegistration
+	reconcileMux sync.Mutex
+}
+
+func NewReconciler(agent Agent, opts ReconcilerOptions) (*Reconciler, error) {
+	if opts.OwnerTag == "" && opts.IDPrefix == "" {
+		return nil, NewInvalidOptionError("OwnerTag", "either OwnerTag or IDPrefix must be set")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultReconcileInterval
+	}
+
+	r := &Reconciler{
+		agent:      agent,
+		opts:       opts,
+		trigger:    make(chan struct{}, 1),
+		registered: map[string]api.AgentCheckRegistration{},
+	}
+	if err := r.SetServices(opts.Services); err != nil {
+		return nil, err
+	}
+	if err := r.SetChecks(opts.Checks); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetServices replaces the desired state, which Run applies right away.
+func (r *Reconciler) SetServices(services []*api.AgentServiceRegistration) error {
+	desired := map[string]*api.AgentServiceRegistration{}
+	for _, s := range services {
+		if s.Name == "" {
+			return NewInvalidOptionError("Services", "every service must have a name")
+		}
+
+		service := *s
+		if service.ID == "" {
+			service.ID = service.Name
+		}
+		if r.opts.IDPrefix != "" && !strings.HasPrefix(service.ID, r.opts.IDPrefix) {
+			return NewInvalidOptionError("Services", fmt.Sprintf("service '%s' is outside IDPrefix", service.ID))
+		}
+		if _, ok := desired[service.ID]; ok {
+			return NewInvalidOptionError("Services", fmt.Sprintf("service '%s' is listed more than once", service.ID))
+		}
+		if r.opts.OwnerTag != "" && !containsString(service.Tags, r.opts.OwnerTag) {
+			service.Tags = append(append([]string{}, service.Tags...), r.opts.OwnerTag)
+		}
+		desired[service.ID] = &service
+	}
+
+	r.mutex.Lock()
+	r.desired = desired
+	r.mutex.Unlock()
+
+	r.triggerReconcile()
+	return nil
+}
+
+// SetChecks replaces the desired standalone checks, which Run applies right
+// away.
+func (r *Reconciler) SetChecks(checks []*api.AgentCheckRegistration) error {
+	desired := map[string]*api.AgentCheckRegistration{}
+	for _, c := range checks {
+		check := *c
+		if check.ID == "" {
+			check.ID = check.Name
+		}
+		if check.ID == "" {
+			return NewInvalidOptionError("Checks", "every check must have an ID or a name")
+		}
+		if check.ServiceID != "" {
+			return NewInvalidOptionError("Checks", fmt.Sprintf("check '%s' belongs to a service, register it with the service", check.ID))
+		}
+		if r.opts.IDPrefix != "" && !strings.HasPrefix(check.ID, r.opts.IDPrefix) {
+			return NewInvalidOptionError("Checks", fmt.Sprintf("check '%s' is outside IDPrefix", check.ID))
+		}
+		if _, ok := desired[check.ID]; ok {
+			return NewInvalidOptionError("Checks", fmt.Sprintf("check '%s' is listed more than once", check.ID))
+		}
+		desired[check.ID] = &check
+	}
+
+	r.mutex.Lock()
+	r.desiredChecks = desired
+	r.mutex.Unlock()
+
+	r.triggerReconcile()
+	return nil
+}
+
+func (r *Reconciler) triggerReconcile() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run reconciles every Interval and whenever the desired state changes. It is
+// ready after the first reconciliation, whether or not that succeeded.
+func (r *Reconciler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	agent := r.agent.WithContext(ctx)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		select {
+		case <-r.trigger:
+		default:
+		}
+		if _, err := r.reconcile(agent); err != nil && ctx.Err() == nil && r.opts.OnError != nil {
+			r.opts.OnError(err)
+		}
+		if first {
+			close(ready)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.trigger:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Reconcile converges the agent once and returns the drift it corrected. On
+// error it returns the drift corrected before the failure.
+func (r *Reconciler) Reconcile() ([]DriftEvent, error) {
+	return r.reconcile(r.agent)
+}
+
+func (r *Reconciler) reconcile(agent Agent) ([]DriftEvent, error) {
+	r.reconcileMux.Lock()
+	defer r.reconcileMux.Unlock()
+
+	r.mutex.Lock()
+	desired, desiredChecks := r.desired, r.desiredChecks
+	r.mutex.Unlock()
+
+	services, err := agent.Services()
+	if err != nil {
+		return nil, err
+	}
+	checks, err := agent.Checks()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []DriftEvent
+	apply := func(event DriftEvent, op func() error) error {
+		if err := op(); err != nil {
+			return err
+		}
+		events = append(events, event)
+		if r.opts.OnDrift != nil {
+			r.opts.OnDrift(event)
+		}
+		return nil
+	}
+
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		want := desired[id]
+		event := DriftEvent{ServiceID: id}
+		if have, ok := services[id]; !ok {
+			event.Kind = DriftMissing
+		} else if !sameService(want, have) {
+			event.Kind = DriftChanged
+		} else if missing := missingCheck(want, checks); missing != "" {
+			event.Kind, event.CheckID = DriftMissing, missing
+		} else if changed := r.changedCheck(want, checks); changed != "" {
+			event.Kind, event.CheckID = DriftChanged, changed
+		} else {
+			continue
+		}
+
+		if err := apply(event, func() error { return agent.ServiceRegister(want) }); err != nil {
+			return events, err
+		}
+		for _, check := range serviceChecks(want) {
+			r.registered[check.ID] = check
+		}
+	}
+
+	ids = ids[:0]
+	for id := range desiredChecks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		want := desiredChecks[id]
+		event := DriftEvent{CheckID: id}
+		if _, ok := checks[id]; !ok {
+			event.Kind = DriftMissing
+		} else if r.checkChanged(*want, checks[id]) {
+			event.Kind = DriftChanged
+		} else {
+			continue
+		}
+
+		if err := apply(event, func() error { return agent.CheckRegister(want) }); err != nil {
+			return events, err
+		}
+		r.registered[id] = *want
+	}
+
+	ids = ids[:0]
+	for id, have := range services {
+		if _, ok := desired[id]; !ok && r.owns(have) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := apply(DriftEvent{Kind: DriftExtra, ServiceID: id}, func() error { return agent.ServiceDeregister(id) }); err != nil {
+			return events, err
+		}
+		for checkID, check := range r.registered {
+			if check.ServiceID == id {
+				delete(r.registered, checkID)
+			}
+		}
+	}
+
+	// Owned standalone checks that are not desired, checks left behind by
+	// owned services that are gone, and checks added to desired services
+	// beyond those registered with them.
+	ids = ids[:0]
+	for id, check := range checks {
+		want, isDesired := desired[check.ServiceID]
+		switch {
+		case isMaintenanceCheck(id):
+			continue
+		case check.ServiceID == "":
+			_, registered := r.registered[id]
+			if desiredChecks[id] != nil || !(registered || r.ownsID(id)) {
+				continue
+			}
+		case isDesired && !expectedCheck(want, id):
+		case !isDesired && services[check.ServiceID] == nil && r.ownsID(check.ServiceID):
+		default:
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		event := DriftEvent{Kind: DriftExtra, ServiceID: checks[id].ServiceID, CheckID: id}
+		if err := apply(event, func() error { return agent.CheckDeregister(id) }); err != nil {
+			return events, err
+		}
+		delete(r.registered, id)
+	}
+
+	return events, nil
+}
+
+func (r *Reconciler) owns(service *api.AgentService) bool {
+	return (r.opts.OwnerTag != "" && containsString(service.Tags, r.opts.OwnerTag)) || r.ownsID(service.ID)
+}
+
+func (r *Reconciler) ownsID(id string) bool {
+	return r.opts.IDPrefix != "" && strings.HasPrefix(id, r.opts.IDPrefix)
+}
+
+func sameService(want *api.AgentServiceRegistration, have *api.AgentService) bool {
+	return want.Name == have.Service &&
+		want.Port == have.Port &&
+		want.Address == have.Address &&
+		want.EnableTagOverride == have.EnableTagOverride &&
+		sameStrings(want.Tags, have.Tags)
+}
+
+// serviceChecks returns the checks of a service as the agent registers them.
+func serviceChecks(service *api.AgentServiceRegistration) []api.AgentCheckRegistration {
+	checks := service.Checks
+	if service.Check != nil {
+		checks = append(api.AgentServiceChecks{service.Check}, checks...)
+	}
+
+	regs := make([]api.AgentCheckRegistration, len(checks))
+	for i, check := range checks {
+		regs[i] = api.AgentCheckRegistration{
+			ID:                "service:" + service.ID,
+			Name:              fmt.Sprintf("Service '%s' check", service.Name),
+			ServiceID:         service.ID,
+			AgentServiceCheck: *check,
+		}
+		if len(checks) > 1 {
+			regs[i].ID = fmt.Sprintf("service:%s:%d", service.ID, i+1)
+		}
+	}
+	return regs
+}
+
+func serviceCheckIDs(service *api.AgentServiceRegistration) []string {
+	var ids []string
+	for _, check := range serviceChecks(service) {
+		ids = append(ids, check.ID)
+	}
+	return ids
+}
+
+func missingCheck(service *api.AgentServiceRegistration, checks map[string]*api.AgentCheck) string {
+	for _, id := range serviceCheckIDs(service) {
+		if _, ok := checks[id]; !ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// changedCheck returns the ID of a check of service that checkChanged
+// reports.
+func (r *Reconciler) changedCheck(service *api.AgentServiceRegistration, checks map[string]*api.AgentCheck) string {
+	for _, check := range serviceChecks(service) {
+		if r.checkChanged(check, checks[check.ID]) {
+			return check.ID
+		}
+	}
+	return ""
+}
+
+// checkChanged reports whether want differs from the check last registered,
+// adopting have if want was never registered and have matches it.
+func (r *Reconciler) checkChanged(want api.AgentCheckRegistration, have *api.AgentCheck) bool {
+	registered, ok := r.registered[want.ID]
+	if !ok {
+		if have == nil || have.Name != want.Name || have.ServiceID != want.ServiceID {
+			return true
+		}
+		r.registered[want.ID] = want
+		return false
+	}
+	return registered != want
+}
+
+// isMaintenanceCheck reports whether checkID is one the agent registers for
+// maintenance mode, which belongs to whoever enabled it.
+func isMaintenanceCheck(checkID string) bool {
+	return checkID == "_node_maintenance" || strings.HasPrefix(checkID, "_service_maintenance:")
+}
+
+func expectedCheck(service *api.AgentServiceRegistration, checkID string) bool {
+	return containsString(serviceCheckIDs(service), checkID)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
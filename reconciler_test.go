@@ -0,0 +1,309 @@
+package consuladapter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		agent      consuladapter.Agent
+		opts       consuladapter.ReconcilerOptions
+		reconciler *consuladapter.Reconciler
+	)
+
+	BeforeEach(func() {
+		agent = memconsul.NewClient().Agent()
+		opts = consuladapter.ReconcilerOptions{
+			OwnerTag: "owner:rep",
+			Services: []*api.AgentServiceRegistration{
+				{Name: "rep", Port: 1800, Check: &api.AgentServiceCheck{TTL: "30s"}},
+				{ID: "rep-metrics", Name: "metrics", Port: 9100},
+			},
+			Interval: 20 * time.Millisecond,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		reconciler, err = consuladapter.NewReconciler(agent, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	services := func() map[string]*api.AgentService {
+		services, err := agent.Services()
+		Expect(err).NotTo(HaveOccurred())
+		return services
+	}
+
+	checks := func() map[string]*api.AgentCheck {
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		return checks
+	}
+
+	Describe("Reconcile", func() {
+		It("registers missing services with the owner tag", func() {
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(Equal([]consuladapter.DriftEvent{
+				{Kind: consuladapter.DriftMissing, ServiceID: "rep"},
+				{Kind: consuladapter.DriftMissing, ServiceID: "rep-metrics"},
+			}))
+
+			Expect(services()).To(HaveKey("rep"))
+			Expect(services()["rep"].Tags).To(ConsistOf("owner:rep"))
+			Expect(services()).To(HaveKey("rep-metrics"))
+			Expect(checks()).To(HaveKey("service:rep"))
+		})
+
+		It("makes no changes once converged", func() {
+			_, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(BeEmpty())
+		})
+
+		It("re-registers changed services", func() {
+			Expect(agent.ServiceRegister(&api.AgentServiceRegistration{
+				ID: "rep-metrics", Name: "metrics", Port: 9200, Tags: []string{"owner:rep"},
+			})).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftChanged, ServiceID: "rep-metrics"}))
+			Expect(services()["rep-metrics"].Port).To(Equal(9100))
+		})
+
+		It("re-registers services whose checks are missing", func() {
+			_, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.CheckDeregister("service:rep")).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(Equal([]consuladapter.DriftEvent{
+				{Kind: consuladapter.DriftMissing, ServiceID: "rep", CheckID: "service:rep"},
+			}))
+			Expect(checks()).To(HaveKey("service:rep"))
+		})
+
+		It("re-registers services whose checks changed", func() {
+			_, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			services := append([]*api.AgentServiceRegistration{}, opts.Services...)
+			services[0] = &api.AgentServiceRegistration{Name: "rep", Port: 1800, Check: &api.AgentServiceCheck{TTL: "1m"}}
+			Expect(reconciler.SetServices(services)).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(Equal([]consuladapter.DriftEvent{
+				{Kind: consuladapter.DriftChanged, ServiceID: "rep", CheckID: "service:rep"},
+			}))
+		})
+
+		It("adopts matching checks it did not register without registering them again", func() {
+			opts.Checks = []*api.AgentCheckRegistration{
+				{ID: "rep-disk", Name: "disk", AgentServiceCheck: api.AgentServiceCheck{TTL: "1m"}},
+			}
+			first, err := consuladapter.NewReconciler(agent, opts)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = first.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.PassTTL("service:rep", "")).To(Succeed())
+			Expect(agent.PassTTL("rep-disk", "")).To(Succeed())
+
+			fresh, err := consuladapter.NewReconciler(agent, opts)
+			Expect(err).NotTo(HaveOccurred())
+			events, err := fresh.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(BeEmpty())
+
+			Expect(checks()["service:rep"].Status).To(Equal(api.HealthPassing))
+			Expect(checks()["rep-disk"].Status).To(Equal(api.HealthPassing))
+		})
+
+		It("registers checks it did not register again when the agent reports them differently", func() {
+			Expect(agent.ServiceRegister(&api.AgentServiceRegistration{
+				Name: "rep", Port: 1800, Tags: []string{"owner:rep"},
+			})).To(Succeed())
+			Expect(agent.CheckRegister(&api.AgentCheckRegistration{
+				ID: "service:rep", Name: "other", ServiceID: "rep", AgentServiceCheck: api.AgentServiceCheck{TTL: "30s"},
+			})).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftChanged, ServiceID: "rep", CheckID: "service:rep"}))
+			Expect(checks()["service:rep"].Name).To(Equal("Service 'rep' check"))
+		})
+
+		It("leaves services and the node in maintenance", func() {
+			_, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.EnableServiceMaintenance("rep", "draining")).To(Succeed())
+			Expect(agent.EnableNodeMaintenance("draining")).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(BeEmpty())
+			Expect(checks()).To(HaveKey("_service_maintenance:rep"))
+			Expect(checks()).To(HaveKey("_node_maintenance"))
+		})
+
+		Context("with standalone checks", func() {
+			BeforeEach(func() {
+				opts.Checks = []*api.AgentCheckRegistration{
+					{ID: "rep-disk", Name: "disk", AgentServiceCheck: api.AgentServiceCheck{TTL: "1m"}},
+				}
+			})
+
+			It("registers them and re-registers them when they change", func() {
+				events, err := reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftMissing, CheckID: "rep-disk"}))
+				Expect(checks()).To(HaveKey("rep-disk"))
+				Expect(checks()["rep-disk"].ServiceID).To(BeEmpty())
+
+				Expect(reconciler.SetChecks([]*api.AgentCheckRegistration{
+					{ID: "rep-disk", Name: "disk", AgentServiceCheck: api.AgentServiceCheck{TTL: "2m"}},
+				})).To(Succeed())
+
+				events, err = reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(Equal([]consuladapter.DriftEvent{{Kind: consuladapter.DriftChanged, CheckID: "rep-disk"}}))
+			})
+
+			It("deregisters those it registered once they are not desired and leaves others alone", func() {
+				Expect(agent.CheckRegister(&api.AgentCheckRegistration{
+					ID: "garden-disk", Name: "disk", AgentServiceCheck: api.AgentServiceCheck{TTL: "1m"},
+				})).To(Succeed())
+
+				_, err := reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(reconciler.SetChecks(nil)).To(Succeed())
+				events, err := reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(Equal([]consuladapter.DriftEvent{{Kind: consuladapter.DriftExtra, CheckID: "rep-disk"}}))
+
+				Expect(checks()).NotTo(HaveKey("rep-disk"))
+				Expect(checks()).To(HaveKey("garden-disk"))
+			})
+
+			It("rejects checks of services", func() {
+				err := reconciler.SetChecks([]*api.AgentCheckRegistration{{ID: "rep-http", ServiceID: "rep"}})
+				Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+			})
+		})
+
+		It("deregisters owned services that are not desired and leaves others alone", func() {
+			Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "old", Tags: []string{"owner:rep"}})).To(Succeed())
+			Expect(agent.ServiceRegister(&api.AgentServiceRegistration{Name: "garden", Tags: []string{"owner:garden"}})).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftExtra, ServiceID: "old"}))
+
+			Expect(services()).NotTo(HaveKey("old"))
+			Expect(services()).To(HaveKey("garden"))
+		})
+
+		It("deregisters checks added to desired services", func() {
+			Expect(agent.ServiceRegister(&api.AgentServiceRegistration{
+				Name: "rep", Port: 1800, Tags: []string{"owner:rep"},
+				Checks: api.AgentServiceChecks{{TTL: "30s"}, {TTL: "1m"}},
+			})).To(Succeed())
+
+			events, err := reconciler.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftExtra, ServiceID: "rep", CheckID: "service:rep:1"}))
+			Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftExtra, ServiceID: "rep", CheckID: "service:rep:2"}))
+
+			Expect(checks()).To(HaveKey("service:rep"))
+			Expect(checks()).NotTo(HaveKey("service:rep:1"))
+			Expect(checks()).NotTo(HaveKey("service:rep:2"))
+		})
+
+		Context("when scoped by ID prefix", func() {
+			BeforeEach(func() {
+				opts.OwnerTag = ""
+				opts.IDPrefix = "rep"
+			})
+
+			It("owns services by ID", func() {
+				Expect(agent.ServiceRegister(&api.AgentServiceRegistration{ID: "rep-old", Name: "old"})).To(Succeed())
+				Expect(agent.ServiceRegister(&api.AgentServiceRegistration{ID: "garden", Name: "garden"})).To(Succeed())
+
+				_, err := reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(services()).To(HaveKey("rep"))
+				Expect(services()["rep"].Tags).To(BeEmpty())
+				Expect(services()).NotTo(HaveKey("rep-old"))
+				Expect(services()).To(HaveKey("garden"))
+			})
+
+			It("owns standalone checks by ID", func() {
+				Expect(agent.CheckRegister(&api.AgentCheckRegistration{
+					ID: "rep-old", Name: "old", AgentServiceCheck: api.AgentServiceCheck{TTL: "1m"},
+				})).To(Succeed())
+
+				events, err := reconciler.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(events).To(ContainElement(consuladapter.DriftEvent{Kind: consuladapter.DriftExtra, CheckID: "rep-old"}))
+				Expect(checks()).NotTo(HaveKey("rep-old"))
+			})
+
+			It("rejects desired services outside the prefix", func() {
+				err := reconciler.SetServices([]*api.AgentServiceRegistration{{Name: "garden"}})
+				Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		It("reconciles periodically and reports drift", func() {
+			drift := make(chan consuladapter.DriftEvent, 10)
+			opts.OnDrift = func(event consuladapter.DriftEvent) { drift <- event }
+			reconciler, err := consuladapter.NewReconciler(agent, opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			process := ginkgomon.Invoke(reconciler)
+			defer ginkgomon.Interrupt(process)
+
+			Expect(services()).To(HaveKey("rep"))
+			Expect(drift).To(HaveLen(2))
+
+			Expect(agent.ServiceDeregister("rep")).To(Succeed())
+			Eventually(services).Should(HaveKey("rep"))
+			Eventually(drift).Should(Receive(Equal(consuladapter.DriftEvent{Kind: consuladapter.DriftMissing, ServiceID: "rep"})))
+		})
+
+		It("applies new desired services right away", func() {
+			opts.Interval = time.Hour
+			reconciler, err := consuladapter.NewReconciler(agent, opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			process := ginkgomon.Invoke(reconciler)
+			defer ginkgomon.Interrupt(process)
+
+			Expect(reconciler.SetServices(opts.Services[:1])).To(Succeed())
+			Eventually(services).ShouldNot(HaveKey("rep-metrics"))
+		})
+	})
+
+	It("requires an owner tag or ID prefix", func() {
+		_, err := consuladapter.NewReconciler(agent, consuladapter.ReconcilerOptions{})
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+	})
+})
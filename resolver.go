@@ -0,0 +1,364 @@
+package consuladapter
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultResolverIdleTimeout = 10 * time.Minute
+
+type BalancePolicy int
+
+const (
+	RoundRobin BalancePolicy = iota
+	Random
+	// LeastRecentlyUsed picks the instance picked longest ago, preferring
+	// those never picked.
+	LeastRecentlyUsed
+	// Nearest picks the instance with the lowest estimated round trip time
+	// from the local agent, according to its network coordinates. These do
+	// not extend to other datacenters, so with a Datacenter set it falls back
+	// to RoundRobin.
+	Nearest
+)
+
+type ResolverOptions struct {
+	// Tag, if set, restricts instances to those carrying it. Datacenter
+	// resolves in another datacenter than the agent's.
+	Tag        string
+	Datacenter string
+
+	Policy BalancePolicy
+
+	// Dialer is used by DialService. Nil uses a zero net.Dialer.
+	Dialer *net.Dialer
+
+	// IdleTimeout stops the query of a service not resolved for that long.
+	IdleTimeout time.Duration
+
+	// Watch configures the blocking queries that keep instances current.
+	// Its Datacenter is overridden by Datacenter.
+	Watch WatchOptions
+}
+
+// Resolver resolves service names to their healthy instances. It starts a
+// blocking query per service the first time the service is resolved and keeps
+// it running until the service goes unresolved for IdleTimeout or until
+// Close. While queries fail, the instances last seen are used.
+type Resolver struct {
+	health Health
+	opts   ResolverOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	services map[string]*resolvedService
+	mutex    sync.Mutex
+}
+
+type resolvedService struct {
+	ready  chan struct{}
+	cancel context.CancelFunc
+
+	// used is guarded by the mutex of the Resolver.
+	used time.Time
+
+	instances []*api.ServiceEntry
+	synced    bool
+	err       error
+
+	next     int
+	picks    uint64
+	lastUsed map[string]uint64
+	mutex    sync.Mutex
+}
+
+func NewResolver(health Health, opts ResolverOptions) *Resolver {
+	if opts.Dialer == nil {
+		opts.Dialer = &net.Dialer{}
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultResolverIdleTimeout
+	}
+	if opts.Datacenter == "" {
+		opts.Datacenter = opts.Watch.QueryOptions.Datacenter
+	}
+	if opts.Policy == Nearest && opts.Datacenter != "" {
+		opts.Policy = RoundRobin
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Resolver{
+		health:   health,
+		opts:     opts,
+		ctx:      ctx,
+		cancel:   cancel,
+		services: map[string]*resolvedService{},
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.evictIdle()
+	}()
+	return r
+}
+
+// Instances returns the healthy instances of service, waiting for the first
+// query if the service was not resolved before. It returns the error of that
+// query if no query has succeeded yet.
+func (r *Resolver) Instances(ctx context.Context, service string) ([]*api.ServiceEntry, error) {
+	s, err := r.resolve(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.synced {
+		return nil, s.err
+	}
+	return append([]*api.ServiceEntry{}, s.instances...), nil
+}
+
+// Pick returns one healthy instance of service chosen by the policy, or a
+// NoInstancesError if there is none.
+func (r *Resolver) Pick(ctx context.Context, service string) (*api.ServiceEntry, error) {
+	s, err := r.resolve(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.synced {
+		return nil, s.err
+	}
+	if len(s.instances) == 0 {
+		return nil, NewNoInstancesError(service)
+	}
+	return s.pick(r.opts.Policy), nil
+}
+
+// DialService connects over TCP to an instance of service chosen by the
+// policy. If that fails it tries the other instances in turn, and returns the
+// error of the last attempt if none can be reached.
+func (r *Resolver) DialService(ctx context.Context, service string) (net.Conn, error) {
+	picked, err := r.Pick(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := r.Instances(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []*api.ServiceEntry{picked}
+	for _, instance := range instances {
+		if instanceKey(instance) != instanceKey(picked) {
+			candidates = append(candidates, instance)
+		}
+	}
+
+	for _, instance := range candidates {
+		var conn net.Conn
+		conn, err = r.opts.Dialer.DialContext(ctx, "tcp", InstanceAddress(instance))
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+	}
+	return nil, err
+}
+
+func (r *Resolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// InstanceAddress returns the host:port an instance is reachable at, which is
+// the service address if it has one and the node address otherwise.
+func InstanceAddress(instance *api.ServiceEntry) string {
+	host := instance.Service.Address
+	if host == "" {
+		host = instance.Node.Address
+	}
+	return net.JoinHostPort(host, strconv.Itoa(instance.Service.Port))
+}
+
+// resolve returns the service, starting its query if needed, once its first
+// query has completed.
+func (r *Resolver) resolve(ctx context.Context, service string) (*resolvedService, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	s, ok := r.services[service]
+	if !ok {
+		ctx, cancel := context.WithCancel(r.ctx)
+		s = &resolvedService{ready: make(chan struct{}), cancel: cancel, lastUsed: map[string]uint64{}}
+		r.services[service] = s
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.watch(ctx, service, s)
+		}()
+	}
+	s.used = time.Now()
+	r.mutex.Unlock()
+
+	select {
+	case <-s.ready:
+		return s, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// evictIdle stops the queries of services unresolved for IdleTimeout.
+func (r *Resolver) evictIdle() {
+	ticker := time.NewTicker(r.opts.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+
+		r.mutex.Lock()
+		for service, s := range r.services {
+			if time.Since(s.used) >= r.opts.IdleTimeout {
+				s.cancel()
+				delete(r.services, service)
+			}
+		}
+		r.mutex.Unlock()
+	}
+}
+
+func (r *Resolver) watch(ctx context.Context, service string, s *resolvedService) {
+	o := r.opts.Watch.withDefaults()
+	health := r.health.WithContext(ctx)
+
+	signaled := false
+	signalReady := func() {
+		if !signaled {
+			close(s.ready)
+			signaled = true
+		}
+	}
+	defer func() {
+		// Callers still waiting for the first query of an evicted service.
+		s.mutex.Lock()
+		if !s.synced && s.err == nil {
+			s.err = ctx.Err()
+		}
+		s.mutex.Unlock()
+		signalReady()
+	}()
+
+	var index uint64
+	var failures uint
+	for ctx.Err() == nil {
+		q := o.QueryOptions
+		q.WaitIndex = index
+		if r.opts.Datacenter != "" {
+			q.Datacenter = r.opts.Datacenter
+		}
+		if r.opts.Policy == Nearest {
+			q.Near = "_agent"
+		}
+
+		instances, meta, err := health.Service(service, r.opts.Tag, true, &q)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.mutex.Lock()
+			s.err = err
+			s.mutex.Unlock()
+			signalReady()
+
+			if o.OnError != nil {
+				o.OnError(err)
+			}
+			if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(o.MinBackoff, o.MaxBackoff, failures))) {
+				return
+			}
+			failures++
+			continue
+		}
+		failures = 0
+
+		s.mutex.Lock()
+		s.update(instances)
+		s.mutex.Unlock()
+		signalReady()
+
+		if meta == nil || meta.LastIndex == 0 || meta.LastIndex < index {
+			index = 0
+			if !retry.Sleep(ctx, retry.Jitter(o.MinBackoff)) {
+				return
+			}
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
+
+// update must be called with the mutex held.
+func (s *resolvedService) update(instances []*api.ServiceEntry) {
+	s.instances = instances
+	s.synced = true
+	s.err = nil
+
+	current := map[string]bool{}
+	for _, instance := range instances {
+		current[instanceKey(instance)] = true
+	}
+	for key := range s.lastUsed {
+		if !current[key] {
+			delete(s.lastUsed, key)
+		}
+	}
+}
+
+// pick must be called with the mutex held and at least one instance.
+func (s *resolvedService) pick(policy BalancePolicy) *api.ServiceEntry {
+	var instance *api.ServiceEntry
+	switch policy {
+	case Random:
+		instance = s.instances[rand.Intn(len(s.instances))]
+	case LeastRecentlyUsed:
+		for _, candidate := range s.instances {
+			if instance == nil || s.lastUsed[instanceKey(candidate)] < s.lastUsed[instanceKey(instance)] {
+				instance = candidate
+			}
+		}
+	case Nearest:
+		instance = s.instances[0]
+	default:
+		instance = s.instances[s.next%len(s.instances)]
+		s.next++
+	}
+
+	s.picks++
+	s.lastUsed[instanceKey(instance)] = s.picks
+	return instance
+}
+
+func instanceKey(instance *api.ServiceEntry) string {
+	return instance.Node.Node + "/" + instance.Service.ID
+}
@@ -0,0 +1,316 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolver", func() {
+	var (
+		client   *memconsul.Client
+		opts     consuladapter.ResolverOptions
+		resolver *consuladapter.Resolver
+		ctx      context.Context
+	)
+
+	register := func(node, address string, port int, tags ...string) {
+		_, err := client.Catalog().Register(&api.CatalogRegistration{
+			Node:    node,
+			Address: address,
+			Service: &api.AgentService{ID: "api", Service: "api", Port: port, Tags: tags},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	addresses := func(instances []*api.ServiceEntry) []string {
+		var addresses []string
+		for _, instance := range instances {
+			addresses = append(addresses, consuladapter.InstanceAddress(instance))
+		}
+		return addresses
+	}
+
+	BeforeEach(func() {
+		client = memconsul.NewClient()
+		ctx = context.Background()
+		opts = consuladapter.ResolverOptions{
+			Watch: consuladapter.WatchOptions{
+				MinBackoff: 10 * time.Millisecond,
+				MaxBackoff: 20 * time.Millisecond,
+			},
+		}
+
+		register("cell-1", "10.0.0.1", 8080, "v1")
+		register("cell-2", "10.0.0.2", 8080, "v2")
+	})
+
+	JustBeforeEach(func() {
+		resolver = consuladapter.NewResolver(client.Health(), opts)
+	})
+
+	AfterEach(func() {
+		resolver.Close()
+	})
+
+	It("returns the healthy instances of a service", func() {
+		Expect(client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+			Name:  "api",
+			Port:  8080,
+			Check: &api.AgentServiceCheck{TTL: "1m"},
+		})).To(Succeed())
+
+		instances, err := resolver.Instances(ctx, "api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addresses(instances)).To(ConsistOf("10.0.0.1:8080", "10.0.0.2:8080"))
+	})
+
+	It("keeps the instances current", func() {
+		_, err := resolver.Instances(ctx, "api")
+		Expect(err).NotTo(HaveOccurred())
+
+		register("cell-3", "10.0.0.3", 8080)
+		Eventually(func() []string {
+			instances, err := resolver.Instances(ctx, "api")
+			Expect(err).NotTo(HaveOccurred())
+			return addresses(instances)
+		}).Should(ConsistOf("10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080"))
+	})
+
+	It("returns a NoInstancesError for a service without instances", func() {
+		_, err := resolver.Pick(ctx, "db")
+		Expect(err).To(Equal(consuladapter.NewNoInstancesError("db")))
+	})
+
+	Context("with a tag", func() {
+		BeforeEach(func() {
+			opts.Tag = "v2"
+		})
+
+		It("only returns instances carrying it", func() {
+			instances, err := resolver.Instances(ctx, "api")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(addresses(instances)).To(ConsistOf("10.0.0.2:8080"))
+		})
+	})
+
+	Describe("policies", func() {
+		picks := func(n int) []string {
+			var picked []string
+			for i := 0; i < n; i++ {
+				instance, err := resolver.Pick(ctx, "api")
+				Expect(err).NotTo(HaveOccurred())
+				picked = append(picked, instance.Node.Address)
+			}
+			return picked
+		}
+
+		It("rotates through the instances by default", func() {
+			Expect(picks(4)).To(Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.2"}))
+		})
+
+		Context("Random", func() {
+			BeforeEach(func() {
+				opts.Policy = consuladapter.Random
+			})
+
+			It("picks among the instances", func() {
+				Expect(picks(20)).To(ContainElements("10.0.0.1", "10.0.0.2"))
+			})
+		})
+
+		Context("LeastRecentlyUsed", func() {
+			BeforeEach(func() {
+				opts.Policy = consuladapter.LeastRecentlyUsed
+			})
+
+			It("prefers instances never picked", func() {
+				Expect(picks(3)).To(Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}))
+
+				register("cell-3", "10.0.0.3", 8080)
+				Eventually(func() []*api.ServiceEntry {
+					instances, _ := resolver.Instances(ctx, "api")
+					return instances
+				}).Should(HaveLen(3))
+
+				Expect(picks(2)).To(Equal([]string{"10.0.0.3", "10.0.0.2"}))
+			})
+		})
+
+		Context("Nearest", func() {
+			BeforeEach(func() {
+				opts.Policy = consuladapter.Nearest
+				client.SetRTT("cell-1", 30*time.Millisecond)
+				client.SetRTT("cell-2", 10*time.Millisecond)
+			})
+
+			It("picks the instance nearest to the agent", func() {
+				Expect(picks(2)).To(Equal([]string{"10.0.0.2", "10.0.0.2"}))
+			})
+
+			Context("in another datacenter", func() {
+				var fakeHealth *fakes.FakeHealth
+
+				BeforeEach(func() {
+					opts.Datacenter = "dc2"
+					opts.Watch.QueryOptions.WaitTime = 20 * time.Millisecond
+				})
+
+				JustBeforeEach(func() {
+					resolver.Close()
+
+					fakeHealth = &fakes.FakeHealth{}
+					fakeHealth.WithContextReturns(fakeHealth)
+					fakeHealth.ServiceStub = client.Health().Service
+					resolver = consuladapter.NewResolver(fakeHealth, opts)
+				})
+
+				It("rotates through the instances instead", func() {
+					Expect(picks(2)).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+
+					_, _, _, q := fakeHealth.ServiceArgsForCall(0)
+					Expect(q.Near).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	It("returns the error of the first query until one succeeds", func() {
+		resolver.Close()
+
+		fakeHealth := &fakes.FakeHealth{}
+		fakeHealth.WithContextReturns(fakeHealth)
+		fakeHealth.ServiceReturns(nil, nil, errors.New("Unexpected response code: 500 (no leader)"))
+
+		opts.Datacenter = "dc2"
+		resolver = consuladapter.NewResolver(fakeHealth, opts)
+
+		_, err := resolver.Instances(ctx, "api")
+		Expect(err).To(MatchError(ContainSubstring("no leader")))
+
+		_, _, _, q := fakeHealth.ServiceArgsForCall(0)
+		Expect(q.Datacenter).To(Equal("dc2"))
+	})
+
+	It("keeps resolving when a query returns no meta", func() {
+		resolver.Close()
+
+		fakeHealth := &fakes.FakeHealth{}
+		fakeHealth.WithContextReturns(fakeHealth)
+		fakeHealth.ServiceReturns([]*api.ServiceEntry{{
+			Node:    &api.Node{Node: "cell-1", Address: "10.0.0.1"},
+			Service: &api.AgentService{ID: "api", Port: 8080},
+		}}, nil, nil)
+		resolver = consuladapter.NewResolver(fakeHealth, opts)
+
+		instances, err := resolver.Instances(ctx, "api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addresses(instances)).To(ConsistOf("10.0.0.1:8080"))
+
+		Eventually(fakeHealth.ServiceCallCount).Should(BeNumerically(">", 1))
+		_, _, _, q := fakeHealth.ServiceArgsForCall(1)
+		Expect(q.WaitIndex).To(BeZero())
+	})
+
+	Describe("DialService", func() {
+		var listener net.Listener
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			go func(l net.Listener) {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					conn.Close()
+				}
+			}(listener)
+
+			closed, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			closedPort := closed.Addr().(*net.TCPAddr).Port
+			closed.Close()
+
+			port := listener.Addr().(*net.TCPAddr).Port
+			_, err = client.Catalog().Register(&api.CatalogRegistration{
+				Node:    "cell-1",
+				Address: "127.0.0.1",
+				Service: &api.AgentService{ID: "db", Service: "db", Port: closedPort},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.Catalog().Register(&api.CatalogRegistration{
+				Node:    "cell-2",
+				Address: "127.0.0.1",
+				Service: &api.AgentService{ID: "db", Service: "db", Port: port},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("falls back to another instance when one cannot be reached", func() {
+			conn, err := resolver.DialService(ctx, "db")
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			Expect(conn.RemoteAddr().String()).To(Equal(listener.Addr().String()))
+		})
+
+		It("returns a NoInstancesError for a service without instances", func() {
+			_, err := resolver.DialService(ctx, "cache")
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.NoInstancesError("")))
+		})
+	})
+
+	It("stops the queries of services not resolved for IdleTimeout", func() {
+		resolver.Close()
+
+		fakeHealth := &fakes.FakeHealth{}
+		fakeHealth.WithContextReturns(fakeHealth)
+		fakeHealth.ServiceReturns(nil, &api.QueryMeta{}, nil)
+		opts.IdleTimeout = 50 * time.Millisecond
+		resolver = consuladapter.NewResolver(fakeHealth, opts)
+
+		_, err := resolver.Instances(ctx, "api")
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() int {
+			calls := fakeHealth.ServiceCallCount()
+			time.Sleep(50 * time.Millisecond)
+			return fakeHealth.ServiceCallCount() - calls
+		}).Should(BeZero())
+
+		calls := fakeHealth.ServiceCallCount()
+		_, err = resolver.Instances(ctx, "api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeHealth.ServiceCallCount()).To(BeNumerically(">", calls))
+	})
+
+	It("stops resolving once closed", func() {
+		resolver.Close()
+		_, err := resolver.Instances(ctx, "api")
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("formats instance addresses preferring the service address", func() {
+		instance := &api.ServiceEntry{
+			Node:    &api.Node{Address: "10.0.0.1"},
+			Service: &api.AgentService{Address: "192.168.0.1", Port: 80},
+		}
+		Expect(consuladapter.InstanceAddress(instance)).To(Equal("192.168.0.1:80"))
+	})
+})
@@ -0,0 +1,81 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Semaphore", func() {
+	var opts *api.SemaphoreOptions
+
+	BeforeEach(func() {
+		Expect(consulRunner.Reset()).To(Succeed())
+		consulClient = consulRunner.NewClient()
+		opts = &api.SemaphoreOptions{Prefix: "downloads", Limit: 1}
+	})
+
+	newSemaphore := func() consuladapter.Semaphore {
+		sem, err := consulClient.SemaphoreOpts(opts)
+		Expect(err).NotTo(HaveOccurred())
+		return sem
+	}
+
+	It("admits up to Limit holders and hands the slot over on Release", func() {
+		holder := newSemaphore()
+		lostSlot, err := holder.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		opts.SemaphoreTryOnce = true
+		opts.SemaphoreWaitTime = 100 * time.Millisecond
+		waiter := newSemaphore()
+		waiterSlot, err := waiter.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waiterSlot).To(BeNil())
+
+		Expect(holder.Release()).To(Succeed())
+		Eventually(lostSlot).Should(BeClosed())
+
+		waiterSlot, err = waiter.Acquire(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waiterSlot).NotTo(BeNil())
+		Expect(waiter.Release()).To(Succeed())
+	})
+
+	It("returns a SemaphoreNotHeldError when releasing a slot that is not held", func() {
+		err := newSemaphore().Release()
+		Expect(err).To(Equal(consuladapter.NewSemaphoreNotHeldError("downloads")))
+		Expect(errors.Is(err, api.ErrSemaphoreNotHeld)).To(BeTrue())
+	})
+
+	Describe("Destroy", func() {
+		It("returns a SemaphoreInUseError while a slot is held", func() {
+			holder := newSemaphore()
+			_, err := holder.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer holder.Release()
+
+			err = newSemaphore().Destroy()
+			Expect(err).To(Equal(consuladapter.NewSemaphoreInUseError("downloads")))
+			Expect(errors.Is(err, api.ErrSemaphoreInUse)).To(BeTrue())
+		})
+
+		It("deletes the semaphore once every slot is released", func() {
+			sem := newSemaphore()
+			_, err := sem.Acquire(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sem.Release()).To(Succeed())
+
+			Expect(sem.Destroy()).To(Succeed())
+
+			pairs, _, err := consulClient.KV().List("downloads", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pairs).To(BeEmpty())
+		})
+	})
+})
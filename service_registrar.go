@@ -0,0 +1,194 @@
+package consuladapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultServiceTTL = "30s"
+
+// HealthFunc reports the status to heartbeat, one of api.HealthPassing,
+// api.HealthWarning and api.HealthCritical, with a note for the check output.
+type HealthFunc func() (status, note string)
+
+type ServiceRegistrarOptions struct {
+	// Service is registered with a TTL check added to any checks it has.
+	Service *api.AgentServiceRegistration
+
+	// TTL is the TTL of the check. Interval is how often it is updated, and
+	// defaults to a third of the TTL.
+	TTL      string
+	Interval time.Duration
+
+	// Health is called before every heartbeat. Nil always reports passing.
+	Health HealthFunc
+
+	// MinBackoff and MaxBackoff bound the retries of failed registrations.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every failed registration or heartbeat.
+	OnError func(err error)
+}
+
+// ServiceRegistrar is an ifrit.Runner that registers a service with the local
+// agent and heartbeats its TTL check. If the agent loses the registration,
+// for instance because it restarted, it registers the service again. It is
+// ready once the service is registered and deregisters it on shutdown.
+type ServiceRegistrar struct {
+	agent   Agent
+	opts    ServiceRegistrarOptions
+	service api.AgentServiceRegistration
+	checkID string
+}
+
+func NewServiceRegistrar(agent Agent, opts ServiceRegistrarOptions) (*ServiceRegistrar, error) {
+	if opts.Service == nil || opts.Service.Name == "" {
+		return nil, NewInvalidOptionError("Service", "must have a name")
+	}
+	if opts.TTL == "" {
+		opts.TTL = DefaultServiceTTL
+	}
+	ttl, err := time.ParseDuration(opts.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, NewInvalidOptionError("TTL", "must be a positive duration")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = ttl / 3
+	} else if opts.Interval >= ttl {
+		return nil, NewInvalidOptionError("Interval", "must be shorter than the TTL")
+	}
+	if opts.Health == nil {
+		opts.Health = func() (string, string) { return api.HealthPassing, "" }
+	}
+	opts.MinBackoff, opts.MaxBackoff = retry.Bounds(opts.MinBackoff, opts.MaxBackoff, DefaultMinBackoff, DefaultMaxBackoff)
+
+	// The TTL check goes first, so that the agent names it service:<id>.
+	service := *opts.Service
+	if service.ID == "" {
+		service.ID = service.Name
+	}
+	checks := api.AgentServiceChecks{{TTL: opts.TTL}}
+	if service.Check != nil {
+		checks = append(checks, service.Check)
+	}
+	service.Check = nil
+	service.Checks = append(checks, service.Checks...)
+
+	checkID := "service:" + service.ID
+	if len(service.Checks) > 1 {
+		checkID += ":1"
+	}
+
+	return &ServiceRegistrar{
+		agent:   agent,
+		opts:    opts,
+		service: service,
+		checkID: checkID,
+	}, nil
+}
+
+func (r *ServiceRegistrar) CheckID() string {
+	return r.checkID
+}
+
+func (r *ServiceRegistrar) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	agent := r.agent.WithContext(ctx)
+	if !r.register(ctx, agent) {
+		return nil
+	}
+	close(ready)
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.heartbeat(ctx, agent)
+		case <-ctx.Done():
+			return r.agent.ServiceDeregister(r.service.ID)
+		}
+	}
+}
+
+// register registers the service and updates its check until both succeed,
+// returning false if ctx is done first.
+func (r *ServiceRegistrar) register(ctx context.Context, agent Agent) bool {
+	var failures uint
+	for {
+		err := agent.ServiceRegister(&r.service)
+		if err == nil {
+			err = r.update(agent)
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if err == nil {
+			return true
+		}
+
+		r.reportError(err)
+		if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(r.opts.MinBackoff, r.opts.MaxBackoff, failures))) {
+			return false
+		}
+		failures++
+	}
+}
+
+func (r *ServiceRegistrar) heartbeat(ctx context.Context, agent Agent) {
+	err := r.update(agent)
+	if ctx.Err() != nil || err == nil {
+		return
+	}
+	if isCheckNotFound(err) {
+		r.register(ctx, agent)
+		return
+	}
+	r.reportError(err)
+}
+
+func (r *ServiceRegistrar) update(agent Agent) error {
+	status, note := r.opts.Health()
+	switch status {
+	case api.HealthPassing:
+		return agent.PassTTL(r.checkID, note)
+	case api.HealthWarning:
+		return agent.WarnTTL(r.checkID, note)
+	case api.HealthCritical:
+		return agent.FailTTL(r.checkID, note)
+	default:
+		return fmt.Errorf("invalid health status '%s'", status)
+	}
+}
+
+func (r *ServiceRegistrar) reportError(err error) {
+	if r.opts.OnError != nil {
+		r.opts.OnError(err)
+	}
+}
+
+// isCheckNotFound reports whether err is the agent rejecting a TTL update for
+// a check it does not know, which older agents word as the check having no
+// TTL.
+func isCheckNotFound(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "does not have associated TTL") || strings.Contains(msg, "Unknown check")
+}
@@ -0,0 +1,174 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServiceRegistrar", func() {
+	var (
+		agent     consuladapter.Agent
+		healthy   atomic.Bool
+		opts      consuladapter.ServiceRegistrarOptions
+		registrar *consuladapter.ServiceRegistrar
+		process   ifrit.Process
+	)
+
+	BeforeEach(func() {
+		agent = memconsul.NewClient().Agent()
+		healthy.Store(true)
+		opts = consuladapter.ServiceRegistrarOptions{
+			Service:  &api.AgentServiceRegistration{Name: "rep", Port: 1800},
+			TTL:      "10s",
+			Interval: 20 * time.Millisecond,
+			Health: func() (string, string) {
+				if healthy.Load() {
+					return api.HealthPassing, "ok"
+				}
+				return api.HealthCritical, "disk full"
+			},
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+		}
+		process = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		registrar, err = consuladapter.NewServiceRegistrar(agent, opts)
+		Expect(err).NotTo(HaveOccurred())
+		process = ginkgomon.Invoke(registrar)
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ginkgomon.Interrupt(process)
+		}
+	})
+
+	checkStatus := func() string {
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		if check, ok := checks[registrar.CheckID()]; ok {
+			return check.Status
+		}
+		return ""
+	}
+
+	It("registers the service with a passing TTL check", func() {
+		services, err := agent.Services()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(HaveKey("rep"))
+		Expect(services["rep"].Port).To(Equal(1800))
+
+		Expect(registrar.CheckID()).To(Equal("service:rep"))
+		Expect(checkStatus()).To(Equal(api.HealthPassing))
+	})
+
+	It("heartbeats the status reported by the health function", func() {
+		healthy.Store(false)
+		Eventually(checkStatus).Should(Equal(api.HealthCritical))
+
+		checks, err := agent.Checks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checks[registrar.CheckID()].Output).To(Equal("disk full"))
+
+		healthy.Store(true)
+		Eventually(checkStatus).Should(Equal(api.HealthPassing))
+	})
+
+	It("registers the service again when the agent loses it", func() {
+		Expect(agent.ServiceDeregister("rep")).To(Succeed())
+		Expect(checkStatus()).To(BeEmpty())
+
+		Eventually(checkStatus).Should(Equal(api.HealthPassing))
+		services, err := agent.Services()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).To(HaveKey("rep"))
+	})
+
+	It("deregisters the service on shutdown", func() {
+		ginkgomon.Interrupt(process)
+		process = nil
+
+		services, err := agent.Services()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(services).NotTo(HaveKey("rep"))
+	})
+
+	Context("when the service has checks of its own", func() {
+		BeforeEach(func() {
+			opts.Service.Check = &api.AgentServiceCheck{TTL: "1m"}
+		})
+
+		It("heartbeats the first check", func() {
+			Expect(registrar.CheckID()).To(Equal("service:rep:1"))
+			Expect(checkStatus()).To(Equal(api.HealthPassing))
+
+			checks, err := agent.Checks()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checks).To(HaveKey("service:rep:2"))
+			Expect(checks["service:rep:2"].Status).To(Equal(api.HealthCritical))
+		})
+	})
+
+	Context("when registration fails at first", func() {
+		var (
+			fakeAgent *fakes.FakeAgent
+			errs      chan error
+		)
+
+		BeforeEach(func() {
+			live := agent
+			fakeAgent = &fakes.FakeAgent{}
+			fakeAgent.WithContextReturns(fakeAgent)
+			fakeAgent.ServiceRegisterStub = func(service *api.AgentServiceRegistration) error {
+				if fakeAgent.ServiceRegisterCallCount() < 3 {
+					return errors.New("dial tcp 127.0.0.1:8500: connection refused")
+				}
+				return live.ServiceRegister(service)
+			}
+			fakeAgent.PassTTLStub = live.PassTTL
+			fakeAgent.ServiceDeregisterStub = live.ServiceDeregister
+			agent = fakeAgent
+
+			errs = make(chan error, 10)
+			opts.OnError = func(err error) { errs <- err }
+		})
+
+		It("retries until it succeeds before becoming ready", func() {
+			Expect(fakeAgent.ServiceRegisterCallCount()).To(Equal(3))
+			Expect(errs).To(HaveLen(2))
+			Expect(fakeAgent.PassTTLCallCount()).To(BeNumerically(">=", 1))
+		})
+	})
+
+	Describe("NewServiceRegistrar", func() {
+		JustBeforeEach(func() {
+			ginkgomon.Interrupt(process)
+			process = nil
+		})
+
+		It("requires a service name", func() {
+			_, err := consuladapter.NewServiceRegistrar(agent, consuladapter.ServiceRegistrarOptions{})
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+
+		It("requires an interval shorter than the TTL", func() {
+			opts.Interval = time.Minute
+			_, err := consuladapter.NewServiceRegistrar(agent, opts)
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+	})
+})
@@ -1,6 +1,10 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_session.go . Session
 
@@ -13,10 +17,13 @@ type Session interface {
 	Node(node string, q *api.QueryOptions) ([]*api.SessionEntry, *api.QueryMeta, error)
 	Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error)
 	RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error
+
+	WithContext(ctx context.Context) Session
 }
 
 type session struct {
 	session *api.Session
+	client  *client
 }
 
 func NewConsulSession(s *api.Session) Session {
@@ -54,3 +61,10 @@ func (s *session) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api
 func (s *session) RenewPeriodic(initialTTL string, id string, q *api.WriteOptions, doneCh chan struct{}) error {
 	return s.session.RenewPeriodic(initialTTL, id, q, doneCh)
 }
+
+func (s *session) WithContext(ctx context.Context) Session {
+	if s.client == nil {
+		return &contextSession{session: s, ctx: ctx}
+	}
+	return s.client.WithContext(ctx).Session()
+}
@@ -0,0 +1,257 @@
+package consuladapter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultSessionTTL = "15s"
+
+type SessionManagerOptions struct {
+	Name      string
+	TTL       string
+	Behavior  string
+	LockDelay time.Duration
+	// Checks the session is bound to. Nil binds it to the agent's serfHealth
+	// check, as Consul does.
+	Checks []string
+
+	// Recreate makes the manager create a new session after losing one,
+	// retrying with a jittered exponential backoff between MinBackoff and
+	// MaxBackoff.
+	Recreate   bool
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every failed renewal or creation.
+	OnError func(err error)
+}
+
+// SessionManager keeps a session alive and reports when it is lost, which
+// happens when it is destroyed or invalidated, or when renewals fail for a
+// whole TTL.
+type SessionManager struct {
+	session Session
+	opts    SessionManagerOptions
+	ttl     time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	exited chan struct{}
+
+	current *managedSession
+	mutex   sync.Mutex
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type managedSession struct {
+	id   string
+	lost chan struct{}
+}
+
+// NewSessionManager creates a session and starts renewing it. It fails if the
+// first session cannot be created.
+func NewSessionManager(session Session, opts SessionManagerOptions) (*SessionManager, error) {
+	if opts.TTL == "" {
+		opts.TTL = DefaultSessionTTL
+	}
+	ttl, err := time.ParseDuration(opts.TTL)
+	if err != nil || ttl <= 0 {
+		return nil, NewInvalidOptionError("TTL", "must be a positive duration")
+	}
+	opts.MinBackoff, opts.MaxBackoff = retry.Bounds(opts.MinBackoff, opts.MaxBackoff, DefaultMinBackoff, DefaultMaxBackoff)
+
+	m := &SessionManager{
+		session: session,
+		opts:    opts,
+		ttl:     ttl,
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+
+	current, err := m.create()
+	if err != nil {
+		return nil, err
+	}
+	m.current = current
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	go m.run(current)
+
+	return m, nil
+}
+
+// ID returns the ID of the current session. After a loss it keeps returning
+// the lost session until a new one has been created.
+func (m *SessionManager) ID() string {
+	id, _ := m.Session()
+	return id
+}
+
+func (m *SessionManager) Lost() <-chan struct{} {
+	_, lost := m.Session()
+	return lost
+}
+
+func (m *SessionManager) Session() (string, <-chan struct{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.current.id, m.current.lost
+}
+
+// Done returns a channel that is closed once the manager stops, after Close
+// or after losing its session without Recreate.
+func (m *SessionManager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Close stops renewing and destroys the current session unless it is already
+// lost. It is safe to call more than once.
+func (m *SessionManager) Close() error {
+	m.closeOnce.Do(func() {
+		m.cancel()
+		<-m.exited
+
+		m.mutex.Lock()
+		current := m.current
+		m.mutex.Unlock()
+
+		select {
+		case <-current.lost:
+			return
+		default:
+		}
+
+		_, m.closeErr = m.session.Destroy(current.id, nil)
+		close(current.lost)
+	})
+	return m.closeErr
+}
+
+func (m *SessionManager) run(current *managedSession) {
+	defer close(m.exited)
+	defer close(m.done)
+
+	for {
+		if !m.keepAlive(current) {
+			return
+		}
+		close(current.lost)
+
+		if !m.opts.Recreate {
+			return
+		}
+
+		var failures uint
+		for {
+			next, err := m.create()
+			if err == nil {
+				current = next
+				break
+			}
+			if !retry.Sleep(m.ctx, retry.Jitter(retry.Backoff(m.opts.MinBackoff, m.opts.MaxBackoff, failures))) {
+				return
+			}
+			failures++
+		}
+
+		m.mutex.Lock()
+		m.current = current
+		m.mutex.Unlock()
+	}
+}
+
+func (m *SessionManager) create() (*managedSession, error) {
+	id, _, err := m.session.Create(&api.SessionEntry{
+		Name:      m.opts.Name,
+		TTL:       m.opts.TTL,
+		Behavior:  m.opts.Behavior,
+		LockDelay: m.opts.LockDelay,
+		Checks:    m.opts.Checks,
+	}, nil)
+	if err != nil {
+		m.reportError(err)
+		return nil, err
+	}
+	return &managedSession{id: id, lost: make(chan struct{})}, nil
+}
+
+// keepAlive renews current every half TTL and watches it with blocking
+// queries in between. It returns true once the session is lost, and false
+// when the manager is closed.
+func (m *SessionManager) keepAlive(current *managedSession) bool {
+	session := m.session.WithContext(m.ctx)
+
+	ttl := m.ttl
+	lastRenew := time.Now()
+	renewAt := lastRenew.Add(ttl / 2)
+	var index uint64
+
+	for {
+		if wait := time.Until(renewAt); wait > 0 {
+			entry, meta, err := session.Info(current.id, &api.QueryOptions{WaitIndex: index, WaitTime: wait})
+			if m.ctx.Err() != nil {
+				return false
+			}
+			if err != nil {
+				if !retry.Sleep(m.ctx, minDuration(wait, time.Second)) {
+					return false
+				}
+				continue
+			}
+			if entry == nil {
+				return true
+			}
+			if meta == nil || meta.LastIndex < index {
+				index = 0
+				if !retry.Sleep(m.ctx, minDuration(wait, retry.Jitter(m.opts.MinBackoff))) {
+					return false
+				}
+				continue
+			}
+			index = meta.LastIndex
+			continue
+		}
+
+		entry, _, err := session.Renew(current.id, nil)
+		if m.ctx.Err() != nil {
+			return false
+		}
+		switch {
+		case err != nil:
+			m.reportError(err)
+			if time.Since(lastRenew) > ttl {
+				return true
+			}
+			renewAt = time.Now().Add(minDuration(time.Second, ttl/2))
+		case entry == nil:
+			return true
+		default:
+			if d, err := time.ParseDuration(entry.TTL); err == nil && d > 0 {
+				ttl = d
+			}
+			lastRenew = time.Now()
+			renewAt = lastRenew.Add(ttl / 2)
+		}
+	}
+}
+
+func (m *SessionManager) reportError(err error) {
+	if m.opts.OnError != nil {
+		m.opts.OnError(err)
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,182 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SessionManager", func() {
+	var (
+		session consuladapter.Session
+		opts    consuladapter.SessionManagerOptions
+		manager *consuladapter.SessionManager
+	)
+
+	BeforeEach(func() {
+		session = memconsul.NewClient().Session()
+		opts = consuladapter.SessionManagerOptions{
+			Name:       "cell",
+			TTL:        "400ms",
+			Behavior:   api.SessionBehaviorDelete,
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+		}
+		manager = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		manager, err = consuladapter.NewSessionManager(session, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if manager != nil {
+			manager.Close()
+		}
+	})
+
+	It("creates a session with the given options", func() {
+		entry, _, err := session.Info(manager.ID(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).NotTo(BeNil())
+		Expect(entry.Name).To(Equal("cell"))
+		Expect(entry.TTL).To(Equal("400ms"))
+		Expect(entry.Behavior).To(Equal(api.SessionBehaviorDelete))
+	})
+
+	It("keeps the session alive past its TTL", func() {
+		Consistently(manager.Lost(), time.Second).ShouldNot(BeClosed())
+
+		entry, _, err := session.Info(manager.ID(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry).NotTo(BeNil())
+	})
+
+	Context("when the session is destroyed", func() {
+		It("reports it lost and stops", func() {
+			id, lost := manager.Session()
+			_, err := session.Destroy(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(lost).Should(BeClosed())
+			Eventually(manager.Done()).Should(BeClosed())
+			Expect(manager.ID()).To(Equal(id))
+		})
+
+		Context("with Recreate", func() {
+			BeforeEach(func() {
+				opts.Recreate = true
+			})
+
+			It("creates a fresh session", func() {
+				id, lost := manager.Session()
+				_, err := session.Destroy(id, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(lost).Should(BeClosed())
+				Eventually(manager.ID).ShouldNot(Equal(id))
+				Expect(manager.Lost()).NotTo(BeClosed())
+				Expect(manager.Done()).NotTo(BeClosed())
+
+				entry, _, err := session.Info(manager.ID(), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entry).NotTo(BeNil())
+			})
+		})
+	})
+
+	Describe("Close", func() {
+		It("destroys the session", func() {
+			id, lost := manager.Session()
+			Expect(manager.Close()).To(Succeed())
+
+			Expect(lost).To(BeClosed())
+			Expect(manager.Done()).To(BeClosed())
+			entry, _, err := session.Info(id, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(BeNil())
+
+			Expect(manager.Close()).To(Succeed())
+		})
+	})
+
+	Context("when renewals fail", func() {
+		var (
+			fakeSession *fakes.FakeSession
+			errs        chan error
+		)
+
+		BeforeEach(func() {
+			fakeSession = &fakes.FakeSession{}
+			fakeSession.WithContextReturns(fakeSession)
+			fakeSession.CreateReturns("session-id", nil, nil)
+			fakeSession.InfoStub = func(id string, q *api.QueryOptions) (*api.SessionEntry, *api.QueryMeta, error) {
+				time.Sleep(q.WaitTime)
+				return &api.SessionEntry{ID: id}, &api.QueryMeta{LastIndex: 1}, nil
+			}
+			fakeSession.RenewReturns(nil, nil, errors.New("Unexpected response code: 500 (no leader)"))
+			session = fakeSession
+
+			errs = make(chan error, 100)
+			opts.TTL = "200ms"
+			opts.OnError = func(err error) {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		})
+
+		It("retries until the TTL has passed and then reports the session lost", func() {
+			Consistently(manager.Lost(), 150*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(manager.Lost()).Should(BeClosed())
+
+			Expect(fakeSession.RenewCallCount()).To(BeNumerically(">=", 2))
+			Expect(errs).To(Receive(MatchError(ContainSubstring("no leader"))))
+		})
+	})
+
+	Context("when queries return no meta", func() {
+		var fakeSession *fakes.FakeSession
+
+		BeforeEach(func() {
+			fakeSession = &fakes.FakeSession{}
+			fakeSession.WithContextReturns(fakeSession)
+			fakeSession.CreateReturns("session-id", nil, nil)
+			fakeSession.InfoReturns(&api.SessionEntry{ID: "session-id"}, nil, nil)
+			fakeSession.RenewReturns(&api.SessionEntry{ID: "session-id"}, nil, nil)
+			session = fakeSession
+		})
+
+		It("backs off instead of spinning and keeps the session", func() {
+			Consistently(manager.Lost(), 100*time.Millisecond).ShouldNot(BeClosed())
+			Expect(fakeSession.InfoCallCount()).To(BeNumerically("<", 50))
+		})
+	})
+
+	Context("when the TTL is invalid", func() {
+		It("returns an InvalidOptionError", func() {
+			opts.TTL = "soon"
+			_, err := consuladapter.NewSessionManager(session, opts)
+			Expect(err).To(BeAssignableToTypeOf(consuladapter.InvalidOptionError{}))
+		})
+	})
+
+	Context("when the session cannot be created", func() {
+		It("returns the error", func() {
+			fakeSession := &fakes.FakeSession{}
+			fakeSession.CreateReturns("", nil, errors.New("boom"))
+			_, err := consuladapter.NewSessionManager(fakeSession, opts)
+			Expect(err).To(MatchError("boom"))
+		})
+	})
+})
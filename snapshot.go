@@ -0,0 +1,168 @@
+package consuladapter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"gopkg.in/yaml.v3"
+)
+
+type SnapshotFormat int
+
+const (
+	SnapshotJSON SnapshotFormat = iota
+	SnapshotYAML
+)
+
+// Snapshot is a portable copy of the keys under a prefix. Values are base64
+// encoded so that binary values survive JSON and YAML.
+type Snapshot struct {
+	Prefix string         `json:"prefix" yaml:"prefix"`
+	Pairs  []SnapshotPair `json:"pairs" yaml:"pairs"`
+}
+
+type SnapshotPair struct {
+	Key   string `json:"key" yaml:"key"`
+	Flags uint64 `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Value string `json:"value" yaml:"value"`
+}
+
+func ExportSnapshot(kv KV, prefix string) (*Snapshot, error) {
+	pairs, _, err := kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Prefix: prefix, Pairs: []SnapshotPair{}}
+	for _, pair := range pairs {
+		snapshot.Pairs = append(snapshot.Pairs, SnapshotPair{
+			Key:   pair.Key,
+			Flags: pair.Flags,
+			Value: base64.StdEncoding.EncodeToString(pair.Value),
+		})
+	}
+	return snapshot, nil
+}
+
+func WriteSnapshot(w io.Writer, snapshot *Snapshot, format SnapshotFormat) error {
+	switch format {
+	case SnapshotJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	case SnapshotYAML:
+		encoder := yaml.NewEncoder(w)
+		if err := encoder.Encode(snapshot); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown snapshot format %d", format)
+	}
+}
+
+func ReadSnapshot(r io.Reader, format SnapshotFormat) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	var err error
+	switch format {
+	case SnapshotJSON:
+		err = json.NewDecoder(r).Decode(snapshot)
+	case SnapshotYAML:
+		err = yaml.NewDecoder(r).Decode(snapshot)
+	default:
+		err = fmt.Errorf("unknown snapshot format %d", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportUpdate    ImportAction = "update"
+	ImportUnchanged ImportAction = "unchanged"
+	// ImportSkip is a key that exists with a different value and was left
+	// alone because Overwrite was not set.
+	ImportSkip ImportAction = "skip"
+	// ImportConflict is a key that changed between being read and written.
+	ImportConflict ImportAction = "conflict"
+)
+
+type ImportChange struct {
+	Key    string
+	Action ImportAction
+	Old    *api.KVPair
+	New    *api.KVPair
+}
+
+type ImportOptions struct {
+	// Prefix replaces the prefix the snapshot was exported from.
+	Prefix string
+	// Overwrite updates keys that exist with a different value.
+	Overwrite bool
+	DryRun    bool
+}
+
+// ImportSnapshot writes the pairs of a snapshot with check-and-set, so that a
+// key changed concurrently is reported as a conflict rather than clobbered. It
+// returns the change for every pair, in order.
+func ImportSnapshot(kv KV, snapshot *Snapshot, opts ImportOptions) ([]ImportChange, error) {
+	pairs := make([]*api.KVPair, 0, len(snapshot.Pairs))
+	for _, p := range snapshot.Pairs {
+		key := p.Key
+		if opts.Prefix != "" {
+			if !strings.HasPrefix(key, snapshot.Prefix) {
+				return nil, fmt.Errorf("key '%s' is outside the snapshot prefix '%s'", key, snapshot.Prefix)
+			}
+			key = opts.Prefix + strings.TrimPrefix(key, snapshot.Prefix)
+		}
+
+		value, err := base64.StdEncoding.DecodeString(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for '%s': %s", p.Key, err)
+		}
+		pairs = append(pairs, &api.KVPair{Key: key, Flags: p.Flags, Value: value})
+	}
+
+	changes := make([]ImportChange, 0, len(pairs))
+	for _, pair := range pairs {
+		existing, _, err := kv.Get(pair.Key, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return changes, err
+		}
+
+		change := ImportChange{Key: pair.Key, Old: existing, New: pair}
+		switch {
+		case existing == nil:
+			change.Action = ImportCreate
+		case existing.Flags == pair.Flags && bytes.Equal(existing.Value, pair.Value):
+			change.Action = ImportUnchanged
+		case !opts.Overwrite:
+			change.Action = ImportSkip
+		default:
+			change.Action = ImportUpdate
+			pair.ModifyIndex = existing.ModifyIndex
+		}
+
+		if !opts.DryRun && (change.Action == ImportCreate || change.Action == ImportUpdate) {
+			ok, _, err := kv.CAS(pair, nil)
+			if err != nil {
+				return changes, err
+			}
+			if !ok {
+				change.Action = ImportConflict
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,137 @@
+package consuladapter_test
+
+import (
+	"bytes"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	var source, target consuladapter.KV
+
+	BeforeEach(func() {
+		source = memconsul.NewClient().KV()
+		target = memconsul.NewClient().KV()
+
+		for _, pair := range []*api.KVPair{
+			{Key: "cells/a", Value: []byte("a")},
+			{Key: "cells/b", Value: []byte{0, 1, 2, 255}, Flags: 42},
+			{Key: "other", Value: []byte("ignored")},
+		} {
+			_, err := source.Put(pair, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("exports a prefix and round-trips through JSON and YAML", func() {
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.Pairs).To(HaveLen(2))
+
+		for _, format := range []consuladapter.SnapshotFormat{consuladapter.SnapshotJSON, consuladapter.SnapshotYAML} {
+			var buf bytes.Buffer
+			Expect(consuladapter.WriteSnapshot(&buf, snapshot, format)).To(Succeed())
+
+			read, err := consuladapter.ReadSnapshot(&buf, format)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read).To(Equal(snapshot))
+		}
+	})
+
+	It("imports a snapshot preserving flags and binary values", func() {
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+
+		changes, err := consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportCreate))
+
+		pair, _, err := target.Get("cells/b", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte{0, 1, 2, 255}))
+		Expect(pair.Flags).To(Equal(uint64(42)))
+
+		changes, err = consuladapter.ImportSnapshot(consuladapter.NewStrictKV(target), snapshot, consuladapter.ImportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportUnchanged))
+	})
+
+	It("rewrites the prefix", func() {
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{Prefix: "restored/"})
+		Expect(err).NotTo(HaveOccurred())
+
+		keys, _, err := target.Keys("", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"restored/a", "restored/b"}))
+	})
+
+	It("reports the diff without writing on a dry run", func() {
+		_, err := target.Put(&api.KVPair{Key: "cells/a", Value: []byte("changed")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+
+		changes, err := consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{Overwrite: true, DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportUpdate))
+		Expect(changes[0].Old.Value).To(Equal([]byte("changed")))
+		Expect(changes[0].New.Value).To(Equal([]byte("a")))
+		Expect(changes[1].Action).To(Equal(consuladapter.ImportCreate))
+
+		keys, _, err := target.Keys("", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"cells/a"}))
+	})
+
+	It("only overwrites existing keys when asked", func() {
+		_, err := target.Put(&api.KVPair{Key: "cells/a", Value: []byte("changed")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+
+		changes, err := consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportSkip))
+
+		changes, err = consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{Overwrite: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportUpdate))
+
+		pair, _, err := target.Get("cells/a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("a")))
+	})
+
+	It("reports keys that change while importing as conflicts", func() {
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.CASReturns(false, nil, nil)
+
+		snapshot, err := consuladapter.ExportSnapshot(source, "cells/")
+		Expect(err).NotTo(HaveOccurred())
+
+		changes, err := consuladapter.ImportSnapshot(fakeKV, snapshot, consuladapter.ImportOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes[0].Action).To(Equal(consuladapter.ImportConflict))
+
+		pair, _ := fakeKV.CASArgsForCall(0)
+		Expect(pair.ModifyIndex).To(BeZero())
+	})
+
+	It("rejects values that are not base64", func() {
+		snapshot := &consuladapter.Snapshot{Pairs: []consuladapter.SnapshotPair{{Key: "a", Value: "!"}}}
+		_, err := consuladapter.ImportSnapshot(target, snapshot, consuladapter.ImportOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,16 +1,23 @@
 package consuladapter
 
-import "github.com/hashicorp/consul/api"
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
 
 //go:generate counterfeiter -o fakes/fake_status.go . Status
 
 type Status interface {
 	Leader() (string, error)
 	Peers() ([]string, error)
+
+	WithContext(ctx context.Context) Status
 }
 
 type status struct {
 	status *api.Status
+	client *client
 }
 
 func NewConsulStatus(s *api.Status) Status {
@@ -24,3 +31,10 @@ func (s *status) Leader() (string, error) {
 func (s *status) Peers() ([]string, error) {
 	return s.status.Peers()
 }
+
+func (s *status) WithContext(ctx context.Context) Status {
+	if s.client == nil {
+		return &contextStatus{status: s, ctx: ctx}
+	}
+	return s.client.WithContext(ctx).Status()
+}
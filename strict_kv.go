@@ -0,0 +1,151 @@
+package consuladapter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type strictKV struct {
+	KV
+}
+
+// NewStrictKV wraps kv so that Get returns a KeyNotFoundError for a missing
+// key and List and Keys a PrefixNotFoundError for an empty prefix, and so that
+// every other failure is classified by ClassifyError.
+func NewStrictKV(kv KV) KV {
+	return &strictKV{KV: kv}
+}
+
+func (kv *strictKV) Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	pair, meta, err := kv.KV.Get(key, q)
+	if err != nil {
+		return nil, nil, ClassifyError(err)
+	}
+	if pair == nil {
+		return nil, meta, NewKeyNotFoundError(key)
+	}
+	return pair, meta, nil
+}
+
+func (kv *strictKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	pairs, meta, err := kv.KV.List(prefix, q)
+	if err != nil {
+		return nil, nil, ClassifyError(err)
+	}
+	if len(pairs) == 0 {
+		return nil, meta, NewPrefixNotFoundError(prefix)
+	}
+	return pairs, meta, nil
+}
+
+func (kv *strictKV) Keys(prefix, separator string, q *api.QueryOptions) ([]string, *api.QueryMeta, error) {
+	keys, meta, err := kv.KV.Keys(prefix, separator, q)
+	if err != nil {
+		return nil, nil, ClassifyError(err)
+	}
+	if len(keys) == 0 {
+		return nil, meta, NewPrefixNotFoundError(prefix)
+	}
+	return keys, meta, nil
+}
+
+func (kv *strictKV) Put(p *api.KVPair, q *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := kv.KV.Put(p, q)
+	return meta, ClassifyError(err)
+}
+
+func (kv *strictKV) CAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := kv.KV.CAS(p, q)
+	return ok, meta, ClassifyError(err)
+}
+
+func (kv *strictKV) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	acquired, meta, err := kv.KV.Acquire(p, q)
+	return acquired, meta, ClassifyError(err)
+}
+
+func (kv *strictKV) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	released, meta, err := kv.KV.Release(p, q)
+	return released, meta, ClassifyError(err)
+}
+
+func (kv *strictKV) Delete(key string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := kv.KV.Delete(key, w)
+	return meta, ClassifyError(err)
+}
+
+func (kv *strictKV) DeleteCAS(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	ok, meta, err := kv.KV.DeleteCAS(p, q)
+	return ok, meta, ClassifyError(err)
+}
+
+func (kv *strictKV) DeleteTree(prefix string, w *api.WriteOptions) (*api.WriteMeta, error) {
+	meta, err := kv.KV.DeleteTree(prefix, w)
+	return meta, ClassifyError(err)
+}
+
+func (kv *strictKV) Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	ok, resp, meta, err := kv.KV.Txn(txn, q)
+	return ok, resp, meta, ClassifyError(err)
+}
+
+func (kv *strictKV) WithContext(ctx context.Context) KV {
+	return NewStrictKV(kv.KV.WithContext(ctx))
+}
+
+var responseCodePattern = regexp.MustCompile(`Unexpected response code: (\d{3})`)
+
+// ClassifyError wraps err in a TimeoutError, ACLDeniedError, ServerError or
+// ConnectionError when it is one of those failures, and returns it unchanged
+// otherwise. Cancellation is not classified.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err.(type) {
+	case TimeoutError, ACLDeniedError, ServerError, ConnectionError:
+		return err
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return NewTimeoutError(err)
+	}
+
+	if code := responseCode(err); code == 403 {
+		return NewACLDeniedError(err)
+	} else if code >= 500 || api.IsServerError(err) {
+		return NewServerError(err)
+	}
+
+	var urlErr *url.Error
+	var opErr *net.OpError
+	if errors.As(err, &urlErr) || errors.As(err, &opErr) {
+		return NewConnectionError(err)
+	}
+
+	return err
+}
+
+// responseCode extracts the HTTP status from the errors the api package
+// formats for unexpected responses, or returns 0.
+func responseCode(err error) int {
+	match := responseCodePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+
+	code, _ := strconv.Atoi(match[1])
+	return code
+}
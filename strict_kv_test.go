@@ -0,0 +1,145 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StrictKV", func() {
+	var kv consuladapter.KV
+
+	BeforeEach(func() {
+		kv = consuladapter.NewStrictKV(memconsul.NewClient().KV())
+	})
+
+	It("returns a KeyNotFoundError for a missing key", func() {
+		_, meta, err := kv.Get("missing", nil)
+		Expect(err).To(Equal(consuladapter.NewKeyNotFoundError("missing")))
+		Expect(errors.Is(err, consuladapter.ErrNotFound)).To(BeTrue())
+		Expect(meta).NotTo(BeNil())
+
+		var notFound consuladapter.KeyNotFoundError
+		Expect(errors.As(err, &notFound)).To(BeTrue())
+		Expect(string(notFound)).To(Equal("missing"))
+	})
+
+	It("returns a PrefixNotFoundError for an empty prefix", func() {
+		_, _, err := kv.List("missing/", nil)
+		Expect(err).To(Equal(consuladapter.NewPrefixNotFoundError("missing/")))
+		Expect(errors.Is(err, consuladapter.ErrNotFound)).To(BeTrue())
+	})
+
+	It("returns existing data unchanged", func() {
+		_, err := kv.Put(&api.KVPair{Key: "key", Value: []byte("value")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("value")))
+
+		pairs, _, err := kv.List("k", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pairs).To(HaveLen(1))
+	})
+
+	It("stays strict when bound to a context", func() {
+		_, _, err := kv.WithContext(context.Background()).Get("missing", nil)
+		Expect(errors.Is(err, consuladapter.ErrNotFound)).To(BeTrue())
+	})
+
+	It("classifies errors from every method", func() {
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.PutReturns(nil, errors.New("Unexpected response code: 500 (boom)"))
+		fakeKV.DeleteTreeReturns(nil, errors.New("Unexpected response code: 403 (Permission denied)"))
+
+		kv = consuladapter.NewStrictKV(fakeKV)
+
+		_, err := kv.Put(&api.KVPair{Key: "key"}, nil)
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.ServerError{}))
+
+		_, err = kv.DeleteTree("key", nil)
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.ACLDeniedError{}))
+	})
+
+	Describe("ClassifyError", func() {
+		var (
+			server *httptest.Server
+			status int
+			delay  time.Duration
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			delay = 0
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(delay)
+				w.WriteHeader(status)
+				w.Write([]byte("null"))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		get := func(opts ...consuladapter.Option) error {
+			client, err := consuladapter.New(server.URL, opts...)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = consuladapter.NewStrictKV(client.KV()).Get("key", nil)
+			return err
+		}
+
+		It("classifies ACL denials", func() {
+			status = http.StatusForbidden
+			err := get()
+			var denied consuladapter.ACLDeniedError
+			Expect(errors.As(err, &denied)).To(BeTrue())
+		})
+
+		It("classifies 5xx responses", func() {
+			for _, code := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable} {
+				status = code
+				Expect(get()).To(BeAssignableToTypeOf(consuladapter.ServerError{}))
+			}
+		})
+
+		It("classifies unreachable agents", func() {
+			server.Close()
+			Expect(get()).To(BeAssignableToTypeOf(consuladapter.ConnectionError{}))
+		})
+
+		It("classifies timeouts", func() {
+			delay = 200 * time.Millisecond
+			Expect(get(consuladapter.WithRequestTimeout(50 * time.Millisecond))).To(BeAssignableToTypeOf(consuladapter.TimeoutError{}))
+		})
+
+		It("classifies context deadlines as timeouts but not cancellation", func() {
+			Expect(consuladapter.ClassifyError(context.DeadlineExceeded)).To(BeAssignableToTypeOf(consuladapter.TimeoutError{}))
+			Expect(consuladapter.ClassifyError(context.Canceled)).To(Equal(context.Canceled))
+		})
+
+		It("leaves other errors and nil alone", func() {
+			err := errors.New("boom")
+			Expect(consuladapter.ClassifyError(err)).To(Equal(err))
+			Expect(consuladapter.ClassifyError(nil)).To(BeNil())
+		})
+
+		It("keeps the original error reachable", func() {
+			status = http.StatusInternalServerError
+			err := get()
+			Expect(errors.Unwrap(err)).To(MatchError(ContainSubstring("Unexpected response code: 500")))
+			Expect(api.IsServerError(err)).To(BeTrue())
+		})
+	})
+})
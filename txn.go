@@ -0,0 +1,130 @@
+package consuladapter
+
+import "github.com/hashicorp/consul/api"
+
+const (
+	// MaxTxnOps is the number of operations Consul accepts in one transaction.
+	MaxTxnOps = 64
+	// MaxValueSize is the largest value Consul accepts for a single key.
+	MaxValueSize = 512 * 1024
+)
+
+// Txn builds a list of KV operations that Commit applies atomically.
+type Txn struct {
+	ops api.KVTxnOps
+}
+
+func NewTxn() *Txn {
+	return &Txn{}
+}
+
+// Op appends an arbitrary operation, for verbs and fields the other builder
+// methods do not cover.
+func (t *Txn) Op(op *api.KVTxnOp) *Txn {
+	t.ops = append(t.ops, op)
+	return t
+}
+
+func (t *Txn) Set(key string, value []byte) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: string(api.KVSet), Key: key, Value: value})
+}
+
+// CAS sets key only if its ModifyIndex is index. An index of 0 only succeeds
+// if the key does not exist.
+func (t *Txn) CAS(key string, value []byte, index uint64) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: value, Index: index})
+}
+
+func (t *Txn) Lock(key string, value []byte, session string) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVLock, Key: key, Value: value, Session: session})
+}
+
+func (t *Txn) Unlock(key string, value []byte, session string) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVUnlock, Key: key, Value: value, Session: session})
+}
+
+// Get returns the pair in the results of Commit, and fails the transaction
+// if the key does not exist.
+func (t *Txn) Get(key string) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVGet, Key: key})
+}
+
+func (t *Txn) Delete(key string) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVDelete, Key: key})
+}
+
+func (t *Txn) DeleteTree(prefix string) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVDeleteTree, Key: prefix})
+}
+
+// CheckIndex fails the transaction unless key exists with ModifyIndex index.
+func (t *Txn) CheckIndex(key string, index uint64) *Txn {
+	return t.Op(&api.KVTxnOp{Verb: api.KVCheckIndex, Key: key, Index: index})
+}
+
+func (t *Txn) Ops() api.KVTxnOps {
+	return t.ops
+}
+
+func (t *Txn) Len() int {
+	return len(t.ops)
+}
+
+func (t *Txn) Validate() error {
+	if len(t.ops) > MaxTxnOps {
+		return NewTxnTooLargeError(len(t.ops))
+	}
+	for _, op := range t.ops {
+		if len(op.Value) > MaxValueSize {
+			return NewValueTooLargeError(op.Key, len(op.Value))
+		}
+	}
+	return nil
+}
+
+// Split breaks the transaction into transactions of at most MaxTxnOps
+// operations, in order. Each of them is atomic, but they are not atomic
+// together.
+func (t *Txn) Split() []*Txn {
+	var txns []*Txn
+	for start := 0; start < len(t.ops); start += MaxTxnOps {
+		end := start + MaxTxnOps
+		if end > len(t.ops) {
+			end = len(t.ops)
+		}
+		txns = append(txns, &Txn{ops: append(api.KVTxnOps{}, t.ops[start:end]...)})
+	}
+	return txns
+}
+
+// Commit applies the transaction. If Consul rolls it back, the error is a
+// TxnFailedError naming each failed operation. The results hold a pair for
+// every operation that returns one, in order.
+func (t *Txn) Commit(kv KV, q *api.QueryOptions) ([]*api.KVPair, *api.QueryMeta, error) {
+	if len(t.ops) == 0 {
+		return nil, &api.QueryMeta{}, nil
+	}
+	if err := t.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	ok, resp, meta, err := kv.Txn(t.ops, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		var failures []TxnFailure
+		if resp != nil {
+			for _, txnErr := range resp.Errors {
+				failure := TxnFailure{Index: txnErr.OpIndex, What: txnErr.What}
+				if txnErr.OpIndex >= 0 && txnErr.OpIndex < len(t.ops) {
+					failure.Op = t.ops[txnErr.OpIndex]
+				}
+				failures = append(failures, failure)
+			}
+		}
+		return nil, meta, NewTxnFailedError(failures)
+	}
+
+	return resp.Results, meta, nil
+}
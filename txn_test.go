@@ -0,0 +1,143 @@
+package consuladapter_test
+
+import (
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Txn", func() {
+	var kv consuladapter.KV
+
+	BeforeEach(func() {
+		kv = memconsul.NewClient().KV()
+	})
+
+	It("builds and commits operations atomically", func() {
+		results, _, err := consuladapter.NewTxn().
+			Set("presence/cell", []byte("up")).
+			CAS("meta/cell", []byte("v1"), 0).
+			Get("presence/cell").
+			Commit(kv, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(3))
+		Expect(results[2].Value).To(Equal([]byte("up")))
+
+		meta, _, err := kv.Get("meta/cell", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.Value).To(Equal([]byte("v1")))
+
+		_, _, err = consuladapter.NewTxn().
+			CheckIndex("meta/cell", meta.ModifyIndex).
+			Delete("presence/cell").
+			DeleteTree("meta/").
+			Commit(kv, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		keys, _, err := kv.Keys("", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(BeEmpty())
+	})
+
+	It("reports which operation failed and why", func() {
+		txn := consuladapter.NewTxn().
+			Set("a", []byte("1")).
+			CAS("b", []byte("2"), 42)
+		_, _, err := txn.Commit(kv, nil)
+
+		var failed consuladapter.TxnFailedError
+		Expect(errors.As(err, &failed)).To(BeTrue())
+		Expect(failed.Failures).To(HaveLen(1))
+		Expect(failed.Failures[0].Index).To(Equal(1))
+		Expect(failed.Failures[0].Op).To(Equal(txn.Ops()[1]))
+		Expect(failed.Failures[0].What).To(ContainSubstring("index is stale"))
+		Expect(err.Error()).To(ContainSubstring("op 1 (cas 'b')"))
+
+		pair, _, err := kv.Get("a", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).To(BeNil())
+	})
+
+	It("locks and unlocks keys", func() {
+		client := memconsul.NewClient()
+		kv = client.KV()
+		sessionID, _, err := client.Session().CreateNoChecks(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = consuladapter.NewTxn().Lock("lock", []byte("me"), sessionID).Commit(kv, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = consuladapter.NewTxn().Unlock("lock", []byte("me"), "other").Commit(kv, nil)
+		Expect(err).To(BeAssignableToTypeOf(consuladapter.TxnFailedError{}))
+		_, _, err = consuladapter.NewTxn().Unlock("lock", []byte("me"), sessionID).Commit(kv, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("accepts arbitrary operations", func() {
+		_, _, err := consuladapter.NewTxn().
+			Op(&api.KVTxnOp{Verb: string(api.KVSet), Key: "key", Flags: 3}).
+			Commit(kv, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Flags).To(Equal(uint64(3)))
+	})
+
+	It("passes the query options on and returns the meta", func() {
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.TxnReturns(true, &api.KVTxnResponse{}, &api.QueryMeta{LastIndex: 42}, nil)
+
+		_, meta, err := consuladapter.NewTxn().Set("key", nil).Commit(fakeKV, &api.QueryOptions{Datacenter: "dc2", Token: "secret"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.LastIndex).To(BeEquivalentTo(42))
+
+		_, q := fakeKV.TxnArgsForCall(0)
+		Expect(q.Datacenter).To(Equal("dc2"))
+		Expect(q.Token).To(Equal("secret"))
+	})
+
+	Describe("limits", func() {
+		var txn *consuladapter.Txn
+
+		BeforeEach(func() {
+			txn = consuladapter.NewTxn()
+			for i := 0; i < 2*consuladapter.MaxTxnOps+1; i++ {
+				txn.Set(fmt.Sprintf("key/%d", i), nil)
+			}
+		})
+
+		It("refuses to commit more operations than Consul accepts", func() {
+			_, _, err := txn.Commit(kv, nil)
+			Expect(err).To(Equal(consuladapter.NewTxnTooLargeError(2*consuladapter.MaxTxnOps + 1)))
+		})
+
+		It("splits into transactions Consul accepts", func() {
+			txns := txn.Split()
+			Expect(txns).To(HaveLen(3))
+			Expect(txns[0].Len()).To(Equal(consuladapter.MaxTxnOps))
+			Expect(txns[2].Len()).To(Equal(1))
+			Expect(txns[2].Ops()[0].Key).To(Equal(fmt.Sprintf("key/%d", 2*consuladapter.MaxTxnOps)))
+
+			for _, t := range txns {
+				_, _, err := t.Commit(kv, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			keys, _, err := kv.Keys("key/", "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(HaveLen(2*consuladapter.MaxTxnOps + 1))
+		})
+
+		It("refuses values larger than Consul accepts", func() {
+			_, _, err := consuladapter.NewTxn().Set("big", make([]byte, consuladapter.MaxValueSize+1)).Commit(kv, nil)
+			Expect(err).To(Equal(consuladapter.NewValueTooLargeError("big", consuladapter.MaxValueSize+1)))
+		})
+	})
+})
@@ -0,0 +1,155 @@
+package consuladapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// envelopeMagic starts every versioned value. No JSON, gob or protobuf
+// encoding begins with a zero byte, so bare values are told apart from it.
+var envelopeMagic = []byte("\x00cv")
+
+// Migration upgrades an encoded value by one schema version.
+type Migration func(data []byte) ([]byte, error)
+
+type Schema struct {
+	// Version is recorded in an envelope around every value written. Version 0
+	// writes bare values, as code predating TypedKV did.
+	Version uint64
+	// Migrations[v] upgrades a value from version v to v+1. Values without an
+	// envelope are version 0.
+	Migrations map[uint64]Migration
+}
+
+type Entry[T any] struct {
+	Key         string
+	Value       T
+	ModifyIndex uint64
+}
+
+// TypedKV stores values of type T in a KV with a Codec. Values written with an
+// older schema version are migrated when read.
+type TypedKV[T any] struct {
+	kv     KV
+	codec  Codec
+	schema Schema
+}
+
+func NewTypedKV[T any](kv KV, codec Codec, schema Schema) *TypedKV[T] {
+	return &TypedKV[T]{kv: kv, codec: codec, schema: schema}
+}
+
+// Get returns a KeyNotFoundError if the key does not exist, and a DecodeError
+// if its value cannot be read.
+func (t *TypedKV[T]) Get(key string, q *api.QueryOptions) (*Entry[T], *api.QueryMeta, error) {
+	pair, meta, err := t.kv.Get(key, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pair == nil {
+		return nil, meta, NewKeyNotFoundError(key)
+	}
+
+	entry, err := t.decode(pair)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, meta, nil
+}
+
+func (t *TypedKV[T]) List(prefix string, q *api.QueryOptions) ([]*Entry[T], *api.QueryMeta, error) {
+	pairs, meta, err := t.kv.List(prefix, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]*Entry[T], 0, len(pairs))
+	for _, pair := range pairs {
+		entry, err := t.decode(pair)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, meta, nil
+}
+
+func (t *TypedKV[T]) Put(key string, value T, w *api.WriteOptions) (*api.WriteMeta, error) {
+	data, err := t.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return t.kv.Put(&api.KVPair{Key: key, Value: data}, w)
+}
+
+// CAS writes value only if the key's ModifyIndex is index. An index of 0 only
+// succeeds if the key does not exist.
+func (t *TypedKV[T]) CAS(key string, value T, index uint64, w *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	data, err := t.encode(value)
+	if err != nil {
+		return false, nil, err
+	}
+	return t.kv.CAS(&api.KVPair{Key: key, Value: data, ModifyIndex: index}, w)
+}
+
+func (t *TypedKV[T]) WithContext(ctx context.Context) *TypedKV[T] {
+	return &TypedKV[T]{kv: t.kv.WithContext(ctx), codec: t.codec, schema: t.schema}
+}
+
+func (t *TypedKV[T]) encode(value T) ([]byte, error) {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if t.schema.Version == 0 {
+		return data, nil
+	}
+
+	header := make([]byte, len(envelopeMagic)+binary.MaxVarintLen64)
+	n := copy(header, envelopeMagic)
+	n += binary.PutUvarint(header[n:], t.schema.Version)
+	return append(header[:n], data...), nil
+}
+
+func (t *TypedKV[T]) decode(pair *api.KVPair) (*Entry[T], error) {
+	data, version, err := openEnvelope(pair.Value)
+	if err != nil {
+		return nil, NewDecodeError(pair.Key, err)
+	}
+	if version > t.schema.Version {
+		return nil, NewDecodeError(pair.Key, fmt.Errorf("schema version %d is newer than %d", version, t.schema.Version))
+	}
+
+	for ; version < t.schema.Version; version++ {
+		migrate, ok := t.schema.Migrations[version]
+		if !ok {
+			return nil, NewDecodeError(pair.Key, fmt.Errorf("no migration from schema version %d", version))
+		}
+		data, err = migrate(data)
+		if err != nil {
+			return nil, NewDecodeError(pair.Key, fmt.Errorf("migration from schema version %d failed: %s", version, err))
+		}
+	}
+
+	entry := &Entry[T]{Key: pair.Key, ModifyIndex: pair.ModifyIndex}
+	if err := t.codec.Unmarshal(data, &entry.Value); err != nil {
+		return nil, NewDecodeError(pair.Key, err)
+	}
+	return entry, nil
+}
+
+func openEnvelope(value []byte) ([]byte, uint64, error) {
+	if !bytes.HasPrefix(value, envelopeMagic) {
+		return value, 0, nil
+	}
+
+	version, n := binary.Uvarint(value[len(envelopeMagic):])
+	if n <= 0 {
+		return nil, 0, fmt.Errorf("malformed envelope")
+	}
+	return value[len(envelopeMagic)+n:], version, nil
+}
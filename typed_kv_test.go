@@ -0,0 +1,185 @@
+package consuladapter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type cell struct {
+	ID       string
+	Capacity int
+}
+
+type protoCell struct {
+	ID       string `protobuf:"bytes,1,opt,name=id,proto3"`
+	Capacity int64  `protobuf:"varint,2,opt,name=capacity,proto3"`
+}
+
+func (c *protoCell) Reset()         { *c = protoCell{} }
+func (c *protoCell) String() string { return proto.CompactTextString(c) }
+func (*protoCell) ProtoMessage()    {}
+
+var _ = Describe("TypedKV", func() {
+	var kv consuladapter.KV
+
+	BeforeEach(func() {
+		kv = memconsul.NewClient().KV()
+	})
+
+	It("round-trips values with every codec", func() {
+		jsonKV := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{})
+		_, err := jsonKV.Put("json", cell{ID: "a", Capacity: 1}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		entry, _, err := jsonKV.Get("json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Value).To(Equal(cell{ID: "a", Capacity: 1}))
+
+		gobKV := consuladapter.NewTypedKV[cell](kv, consuladapter.GobCodec, consuladapter.Schema{Version: 1})
+		_, err = gobKV.Put("gob", cell{ID: "b", Capacity: 2}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		entry, _, err = gobKV.Get("gob", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Value).To(Equal(cell{ID: "b", Capacity: 2}))
+
+		protoKV := consuladapter.NewTypedKV[*protoCell](kv, consuladapter.ProtoCodec, consuladapter.Schema{Version: 1})
+		_, err = protoKV.Put("proto", &protoCell{ID: "c", Capacity: 3}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		protoEntry, _, err := protoKV.Get("proto", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(protoEntry.Value).To(Equal(&protoCell{ID: "c", Capacity: 3}))
+	})
+
+	It("returns the ModifyIndex and supports check-and-set", func() {
+		typed := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{})
+
+		ok, _, err := typed.CAS("cell", cell{ID: "a"}, 0, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		entry, _, err := typed.Get("cell", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.ModifyIndex).NotTo(BeZero())
+
+		ok, _, err = typed.CAS("cell", cell{ID: "b"}, entry.ModifyIndex+1, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		ok, _, err = typed.CAS("cell", cell{ID: "b"}, entry.ModifyIndex, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("lists decoded values in key order", func() {
+		typed := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{Version: 2})
+		for _, id := range []string{"b", "a"} {
+			_, err := typed.Put("cells/"+id, cell{ID: id}, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		entries, _, err := typed.List("cells/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Key).To(Equal("cells/a"))
+		Expect(entries[0].Value.ID).To(Equal("a"))
+	})
+
+	It("returns a KeyNotFoundError for a missing key", func() {
+		typed := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{})
+		_, _, err := typed.Get("missing", nil)
+		Expect(err).To(Equal(consuladapter.NewKeyNotFoundError("missing")))
+	})
+
+	It("keeps unversioned values readable by hand-written code", func() {
+		typed := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{})
+		_, err := typed.Put("cell", cell{ID: "a"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := kv.Get("cell", nil)
+		Expect(err).NotTo(HaveOccurred())
+		var c cell
+		Expect(json.Unmarshal(pair.Value, &c)).To(Succeed())
+		Expect(c.ID).To(Equal("a"))
+	})
+
+	Describe("migrations", func() {
+		type cellV2 struct {
+			ID     string
+			Memory int
+		}
+
+		renameCapacity := func(data []byte) ([]byte, error) {
+			var old cell
+			if err := json.Unmarshal(data, &old); err != nil {
+				return nil, err
+			}
+			return json.Marshal(cellV2{ID: old.ID, Memory: old.Capacity})
+		}
+
+		It("upgrades values written with older schema versions", func() {
+			_, err := kv.Put(&api.KVPair{Key: "legacy", Value: []byte(`{"ID":"a","Capacity":4}`)}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			v1 := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{
+				Version:    1,
+				Migrations: map[uint64]consuladapter.Migration{0: func(data []byte) ([]byte, error) { return data, nil }},
+			})
+			_, err = v1.Put("v1", cell{ID: "b", Capacity: 8}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			v2 := consuladapter.NewTypedKV[cellV2](kv, consuladapter.JSONCodec, consuladapter.Schema{
+				Version: 2,
+				Migrations: map[uint64]consuladapter.Migration{
+					0: func(data []byte) ([]byte, error) { return data, nil },
+					1: renameCapacity,
+				},
+			})
+
+			entry, _, err := v2.Get("legacy", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Value).To(Equal(cellV2{ID: "a", Memory: 4}))
+
+			entry, _, err = v2.Get("v1", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Value).To(Equal(cellV2{ID: "b", Memory: 8}))
+		})
+
+		It("fails to read values from a newer schema", func() {
+			v2 := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{Version: 2})
+			_, err := v2.Put("cell", cell{ID: "a"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			v1 := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{Version: 1})
+			_, _, err = v1.Get("cell", nil)
+			var decodeErr consuladapter.DecodeError
+			Expect(errors.As(err, &decodeErr)).To(BeTrue())
+			Expect(decodeErr.Key).To(Equal("cell"))
+		})
+
+		It("fails when a migration is missing", func() {
+			_, err := kv.Put(&api.KVPair{Key: "legacy", Value: []byte(`{"ID":"a"}`)}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			v1 := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{Version: 1})
+			_, _, err = v1.Get("legacy", nil)
+			Expect(err).To(MatchError(ContainSubstring("no migration from schema version 0")))
+		})
+	})
+
+	It("binds to a context", func() {
+		typed := consuladapter.NewTypedKV[cell](kv, consuladapter.JSONCodec, consuladapter.Schema{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := typed.WithContext(ctx).Put("cell", cell{}, nil)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
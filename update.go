@@ -0,0 +1,50 @@
+package consuladapter
+
+import (
+	"errors"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const DefaultUpdateAttempts = 10
+
+// UpdateFunc computes the new value of a key from its current value, which is
+// nil when the key does not exist. Returning an error aborts the update.
+type UpdateFunc func(old []byte) ([]byte, error)
+
+// Update applies fn to the value of key with a check-and-set, re-reading and
+// retrying when another writer got there first. It gives up with an
+// UpdateConflictError after DefaultUpdateAttempts attempts. Flags of an
+// existing key are preserved, and a missing key is created, also through a
+// StrictKV.
+func Update(kv KV, key string, fn UpdateFunc) error {
+	for attempt := 0; attempt < DefaultUpdateAttempts; attempt++ {
+		pair, _, err := kv.Get(key, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		next := &api.KVPair{Key: key}
+		var old []byte
+		if pair != nil {
+			old = pair.Value
+			next.Flags = pair.Flags
+			next.ModifyIndex = pair.ModifyIndex
+		}
+
+		next.Value, err = fn(old)
+		if err != nil {
+			return err
+		}
+
+		ok, _, err := kv.CAS(next, nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return NewUpdateConflictError(key)
+}
@@ -0,0 +1,89 @@
+package consuladapter_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Update", func() {
+	var kv consuladapter.KV
+
+	BeforeEach(func() {
+		kv = memconsul.NewClient().KV()
+	})
+
+	appendByte := func(b byte) consuladapter.UpdateFunc {
+		return func(old []byte) ([]byte, error) {
+			return append(old, b), nil
+		}
+	}
+
+	It("creates a missing key", func() {
+		Expect(consuladapter.Update(kv, "key", appendByte('a'))).To(Succeed())
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("a")))
+	})
+
+	It("creates a missing key through a StrictKV", func() {
+		Expect(consuladapter.Update(consuladapter.NewStrictKV(kv), "key", appendByte('a'))).To(Succeed())
+	})
+
+	It("updates an existing key and keeps its flags", func() {
+		_, err := kv.Put(&api.KVPair{Key: "key", Value: []byte("a"), Flags: 42}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(consuladapter.Update(kv, "key", appendByte('b'))).To(Succeed())
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("ab")))
+		Expect(pair.Flags).To(Equal(uint64(42)))
+	})
+
+	It("retries when a concurrent writer wins", func() {
+		conflicts := 2
+		err := consuladapter.Update(kv, "key", func(old []byte) ([]byte, error) {
+			if conflicts > 0 {
+				conflicts--
+				_, err := kv.Put(&api.KVPair{Key: "key", Value: append(old, 'x')}, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			return append(old, 'a'), nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair.Value).To(Equal([]byte("xxa")))
+	})
+
+	It("gives up after a bounded number of attempts", func() {
+		fakeKV := &fakes.FakeKV{}
+		fakeKV.CASReturns(false, nil, nil)
+
+		err := consuladapter.Update(fakeKV, "key", appendByte('a'))
+		Expect(err).To(Equal(consuladapter.NewUpdateConflictError("key")))
+		Expect(fakeKV.CASCallCount()).To(Equal(consuladapter.DefaultUpdateAttempts))
+	})
+
+	It("aborts when the function fails", func() {
+		boom := errors.New("boom")
+		err := consuladapter.Update(kv, "key", func([]byte) ([]byte, error) {
+			return nil, boom
+		})
+		Expect(err).To(Equal(boom))
+
+		pair, _, err := kv.Get("key", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pair).To(BeNil())
+	})
+})
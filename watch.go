@@ -0,0 +1,193 @@
+package consuladapter
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/internal/retry"
+	"github.com/hashicorp/consul/api"
+)
+
+// DefaultMinBackoff and DefaultMaxBackoff bound the retries of watches and of
+// the runners built on sessions and checks.
+const (
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+type WatchEventType int
+
+const (
+	WatchAdded WatchEventType = iota
+	WatchModified
+	WatchDeleted
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchAdded:
+		return "added"
+	case WatchModified:
+		return "modified"
+	case WatchDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchEvent describes a change to a single key. For deleted keys Pair is the
+// last version that was seen. Index is the LastIndex of the query that
+// observed the change.
+type WatchEvent struct {
+	Type  WatchEventType
+	Pair  *api.KVPair
+	Index uint64
+}
+
+// The zero value of WatchOptions is usable.
+type WatchOptions struct {
+	// Datacenter, Token, AllowStale, RequireConsistent and WaitTime are
+	// passed on to every query. WaitIndex is managed by the watch.
+	QueryOptions api.QueryOptions
+
+	// MinBackoff and MaxBackoff bound the jittered exponential backoff used
+	// after failed queries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError, if set, is called with every failed query before backing off.
+	OnError func(err error)
+}
+
+// WatchKey streams changes to key until ctx is done, then closes the channel.
+// The first event reports the key if it already exists.
+func WatchKey(ctx context.Context, kv KV, key string, opts *WatchOptions) <-chan WatchEvent {
+	kv = kv.WithContext(ctx)
+	return watch(ctx, opts, func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+		pair, meta, err := kv.Get(key, q)
+		if err != nil || pair == nil {
+			return nil, meta, err
+		}
+		return api.KVPairs{pair}, meta, nil
+	})
+}
+
+// WatchPrefix streams changes to every key under prefix until ctx is done,
+// then closes the channel. The first events report the keys that already
+// exist.
+func WatchPrefix(ctx context.Context, kv KV, prefix string, opts *WatchOptions) <-chan WatchEvent {
+	kv = kv.WithContext(ctx)
+	return watch(ctx, opts, func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+		return kv.List(prefix, q)
+	})
+}
+
+func (opts *WatchOptions) withDefaults() WatchOptions {
+	o := WatchOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.MinBackoff, o.MaxBackoff = retry.Bounds(o.MinBackoff, o.MaxBackoff, DefaultMinBackoff, DefaultMaxBackoff)
+	return o
+}
+
+type watchQuery func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+
+func watch(ctx context.Context, opts *WatchOptions, query watchQuery) <-chan WatchEvent {
+	o := opts.withDefaults()
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		known := map[string]*api.KVPair{}
+		var index uint64
+		var failures uint
+
+		for ctx.Err() == nil {
+			q := o.QueryOptions
+			q.WaitIndex = index
+
+			pairs, meta, err := query(&q)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if o.OnError != nil {
+					o.OnError(err)
+				}
+				if !retry.Sleep(ctx, retry.Jitter(retry.Backoff(o.MinBackoff, o.MaxBackoff, failures))) {
+					return
+				}
+				failures++
+				continue
+			}
+			failures = 0
+
+			var lastIndex uint64
+			if meta != nil {
+				lastIndex = meta.LastIndex
+			}
+
+			for _, event := range diffPairs(known, pairs, lastIndex) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if lastIndex == 0 || lastIndex < index {
+				// The index is missing or went backwards, e.g. after a snapshot
+				// restore or a leader change. Start over with a non-blocking
+				// query.
+				index = 0
+				if !retry.Sleep(ctx, retry.Jitter(o.MinBackoff)) {
+					return
+				}
+				continue
+			}
+			index = lastIndex
+		}
+	}()
+
+	return events
+}
+
+// diffPairs updates known to pairs and returns the changes in key order.
+func diffPairs(known map[string]*api.KVPair, pairs api.KVPairs, index uint64) []WatchEvent {
+	var events []WatchEvent
+
+	current := make(map[string]*api.KVPair, len(pairs))
+	for _, pair := range pairs {
+		current[pair.Key] = pair
+
+		previous, ok := known[pair.Key]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Type: WatchAdded, Pair: pair, Index: index})
+		case previous.ModifyIndex != pair.ModifyIndex:
+			events = append(events, WatchEvent{Type: WatchModified, Pair: pair, Index: index})
+		}
+	}
+
+	for key, pair := range known {
+		if _, ok := current[key]; !ok {
+			events = append(events, WatchEvent{Type: WatchDeleted, Pair: pair, Index: index})
+		}
+	}
+
+	for key := range known {
+		delete(known, key)
+	}
+	for key, pair := range current {
+		known[key] = pair
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Pair.Key < events[j].Pair.Key
+	})
+	return events
+}
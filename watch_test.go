@@ -0,0 +1,190 @@
+package consuladapter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/consuladapter/memconsul"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watch", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		kv     consuladapter.KV
+		opts   *consuladapter.WatchOptions
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		kv = memconsul.NewClient().KV()
+		opts = &consuladapter.WatchOptions{
+			QueryOptions: api.QueryOptions{WaitTime: 100 * time.Millisecond},
+			MinBackoff:   10 * time.Millisecond,
+			MaxBackoff:   20 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	put := func(key, value string) {
+		_, err := kv.Put(&api.KVPair{Key: key, Value: []byte(value)}, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	receive := func(events <-chan consuladapter.WatchEvent) consuladapter.WatchEvent {
+		var event consuladapter.WatchEvent
+		Eventually(events).Should(Receive(&event))
+		return event
+	}
+
+	Describe("WatchPrefix", func() {
+		It("reports existing keys, then changes to them", func() {
+			put("prefix/a", "1")
+			put("other/b", "1")
+
+			events := consuladapter.WatchPrefix(ctx, kv, "prefix/", opts)
+
+			event := receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchAdded))
+			Expect(event.Pair.Key).To(Equal("prefix/a"))
+			Expect(event.Index).To(BeNumerically(">", 0))
+
+			put("prefix/a", "2")
+			event = receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchModified))
+			Expect(event.Pair.Value).To(Equal([]byte("2")))
+
+			put("prefix/c", "1")
+			event = receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchAdded))
+			Expect(event.Pair.Key).To(Equal("prefix/c"))
+
+			_, err := kv.DeleteTree("prefix/a", nil)
+			Expect(err).NotTo(HaveOccurred())
+			event = receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchDeleted))
+			Expect(event.Pair.Key).To(Equal("prefix/a"))
+			Expect(event.Pair.Value).To(Equal([]byte("2")))
+
+			put("other/b", "2")
+			Consistently(events).ShouldNot(Receive())
+		})
+
+		It("closes the channel when the context is done", func() {
+			events := consuladapter.WatchPrefix(ctx, kv, "prefix/", opts)
+			cancel()
+			Eventually(events).Should(BeClosed())
+		})
+
+		It("interrupts a blocking query that is in flight", func() {
+			opts.QueryOptions.WaitTime = time.Minute
+			events := consuladapter.WatchPrefix(ctx, kv, "prefix/", opts)
+			Consistently(events, 100*time.Millisecond).ShouldNot(Receive())
+
+			cancel()
+			Eventually(events, 500*time.Millisecond).Should(BeClosed())
+		})
+	})
+
+	Describe("WatchKey", func() {
+		It("reports the key being created, modified and deleted", func() {
+			events := consuladapter.WatchKey(ctx, kv, "key", opts)
+			Consistently(events).ShouldNot(Receive())
+
+			put("key", "1")
+			Expect(receive(events).Type).To(Equal(consuladapter.WatchAdded))
+
+			put("key", "2")
+			Expect(receive(events).Type).To(Equal(consuladapter.WatchModified))
+
+			_, err := kv.DeleteTree("key", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(receive(events).Type).To(Equal(consuladapter.WatchDeleted))
+		})
+	})
+
+	Context("when queries fail", func() {
+		It("reports the error and retries", func() {
+			fakeKV := &fakes.FakeKV{}
+			fakeKV.WithContextReturns(fakeKV)
+			var calls int32
+			fakeKV.ListStub = func(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+				if atomic.AddInt32(&calls, 1) <= 2 {
+					return nil, nil, errors.New("boom")
+				}
+				return api.KVPairs{{Key: "prefix/a", ModifyIndex: 5}}, &api.QueryMeta{LastIndex: 5}, nil
+			}
+
+			errs := make(chan error, 10)
+			opts.OnError = func(err error) { errs <- err }
+
+			events := consuladapter.WatchPrefix(ctx, fakeKV, "prefix/", opts)
+			Expect(receive(events).Pair.Key).To(Equal("prefix/a"))
+			Expect(errs).To(HaveLen(2))
+		})
+	})
+
+	Context("when the index goes backwards", func() {
+		It("resets the wait index and resynchronizes", func() {
+			fakeKV := &fakes.FakeKV{}
+			fakeKV.WithContextReturns(fakeKV)
+			responses := []struct {
+				pairs api.KVPairs
+				index uint64
+			}{
+				{api.KVPairs{{Key: "a", ModifyIndex: 10}, {Key: "b", ModifyIndex: 10}}, 10},
+				{api.KVPairs{{Key: "a", ModifyIndex: 3}}, 3},
+			}
+			var calls int32
+			fakeKV.ListStub = func(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+				n := int(atomic.AddInt32(&calls, 1)) - 1
+				if n >= len(responses) {
+					time.Sleep(10 * time.Millisecond)
+					n = len(responses) - 1
+				}
+				return responses[n].pairs, &api.QueryMeta{LastIndex: responses[n].index}, nil
+			}
+
+			events := consuladapter.WatchPrefix(ctx, fakeKV, "", opts)
+			Expect(receive(events).Pair.Key).To(Equal("a"))
+			Expect(receive(events).Pair.Key).To(Equal("b"))
+
+			event := receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchModified))
+			Expect(event.Pair.Key).To(Equal("a"))
+			event = receive(events)
+			Expect(event.Type).To(Equal(consuladapter.WatchDeleted))
+			Expect(event.Pair.Key).To(Equal("b"))
+
+			Eventually(fakeKV.ListCallCount).Should(BeNumerically(">", 2))
+			_, q := fakeKV.ListArgsForCall(2)
+			Expect(q.WaitIndex).To(BeZero())
+		})
+	})
+
+	Context("when a query returns no meta", func() {
+		It("treats it as an index reset", func() {
+			fakeKV := &fakes.FakeKV{}
+			fakeKV.WithContextReturns(fakeKV)
+			fakeKV.ListReturns(api.KVPairs{{Key: "a", ModifyIndex: 1}}, nil, nil)
+
+			events := consuladapter.WatchPrefix(ctx, fakeKV, "", opts)
+			Expect(receive(events).Pair.Key).To(Equal("a"))
+
+			Eventually(fakeKV.ListCallCount).Should(BeNumerically(">", 1))
+			_, q := fakeKV.ListArgsForCall(1)
+			Expect(q.WaitIndex).To(BeZero())
+		})
+	})
+})